@@ -110,6 +110,10 @@ var (
 		Name:  "nostack",
 		Usage: "disable stack output",
 	}
+	PrecompilesFlag = cli.StringFlag{
+		Name:  "precompiles",
+		Usage: "Comma separated native contracts to activate (name=address)",
+	}
 )
 
 func init() {
@@ -128,6 +132,7 @@ func init() {
 		MemProfileFlag,
 		CPUProfileFlag,
 		StatDumpFlag,
+		PrecompilesFlag,
 		GenesisFlag,
 		MachineFlag,
 		SenderFlag,
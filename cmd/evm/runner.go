@@ -21,8 +21,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"runtime/pprof"
+	"strings"
 	"time"
 
 	goruntime "runtime"
@@ -101,6 +103,34 @@ func runCmd(ctx *cli.Context) error {
 		db, _ := ethdb.NewMemDatabase()
 		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
 	}
+	if ctx.GlobalString(PrecompilesFlag.Name) != "" {
+		if chainConfig == nil {
+			// Mirror the default config of the runtime
+			chainConfig = &params.ChainConfig{
+				ChainId:        big.NewInt(1),
+				HomesteadBlock: new(big.Int),
+				DAOForkBlock:   new(big.Int),
+				EIP150Block:    new(big.Int),
+				EIP155Block:    new(big.Int),
+				EIP158Block:    new(big.Int),
+			}
+		}
+		for _, spec := range strings.Split(ctx.GlobalString(PrecompilesFlag.Name), ",") {
+			parts := strings.Split(spec, "=")
+			if len(parts) != 2 || !common.IsHexAddress(parts[1]) {
+				utils.Fatalf("Invalid native contract %q, want name=address", spec)
+			}
+			chainConfig.Precompiles = append(chainConfig.Precompiles, &params.PrecompileConfig{
+				Name:    parts[0],
+				Address: common.HexToAddress(parts[1]),
+			})
+		}
+	}
+	if chainConfig != nil {
+		if err := vm.ValidatePrecompiles(chainConfig); err != nil {
+			utils.Fatalf("Invalid native contracts: %v", err)
+		}
+	}
 	if ctx.GlobalString(SenderFlag.Name) != "" {
 		sender = common.HexToAddress(ctx.GlobalString(SenderFlag.Name))
 	}
@@ -0,0 +1,536 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	bftCommand = cli.Command{
+		Name:     "bft",
+		Usage:    "Manage BFT validators and commit certificates",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+
+Tools for operating a BFT network: generate validator keys, verify the commit
+certificate of every block offline and inspect or move the certificates kept
+in the bft database (<DATADIR>/geth/bftData).`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "init-validators",
+				Usage:     "Generate validator keys and a genesis validator list",
+				ArgsUsage: "<count> [<genesisPath>]",
+				Action:    utils.MigrateFlags(bftInitValidators),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+				},
+				Description: `
+    geth bft init-validators <count> [<genesisPath>]
+
+Creates <count> new accounts in the keystore and prints their addresses as a
+JSON list. If a genesis file is given, the list is written into its
+config.bft.validators field, enabling BFT consensus for the network.
+
+Passwords are taken from the --password file, one per line, or prompted for.`,
+			},
+			{
+				Name:      "verify-chain",
+				Usage:     "Verify the commit certificate of every block",
+				ArgsUsage: "[<blockNumFirst> <blockNumLast>]",
+				Action:    utils.MigrateFlags(bftVerifyChain),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.NumValidatorsFlag,
+				},
+				Description: `
+    geth bft verify-chain [<blockNumFirst> <blockNumLast>]
+
+Checks offline that every canonical block in the given range (the whole chain
+by default) has a stored commit certificate signed by more than two thirds of
+the validators. Validators are read from the genesis configuration, falling
+back to the --num-validators development keys.`,
+			},
+			{
+				Name:      "dump-round-state",
+				Usage:     "Dump stored consensus state",
+				ArgsUsage: "[<blockHash> | <blockNum>]...",
+				Action:    utils.MigrateFlags(bftDumpRoundState),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+    geth bft dump-round-state [<blockHash> | <blockNum>]...
+
+Without arguments, prints the lockset the node committed last. Otherwise the
+arguments are interpreted as block numbers or hashes and their commit
+certificates are printed.`,
+			},
+			{
+				Name:      "export-certificates",
+				Usage:     "Export commit certificates into file",
+				ArgsUsage: "<filename> [<blockNumFirst> <blockNumLast>]",
+				Action:    utils.MigrateFlags(bftExportCertificates),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+    geth bft export-certificates <filename> [<blockNumFirst> <blockNumLast>]
+
+Writes the commit certificates of the canonical blocks in the given range (the
+whole chain by default) as a stream of RLP-encoded precommit locksets.`,
+			},
+			{
+				Name:      "import-certificates",
+				Usage:     "Import commit certificates from file",
+				ArgsUsage: "<filename>",
+				Action:    utils.MigrateFlags(bftImportCertificates),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.NumValidatorsFlag,
+				},
+				Description: `
+    geth bft import-certificates <filename>
+
+Reads a file written by export-certificates and stores every certificate that
+carries a valid validator quorum into the bft database.`,
+			},
+			{
+				Name:      "signer",
+				Usage:     "Serve a validator key to a node as external signer",
+				ArgsUsage: "<keyfile> <ipcpath> [<genesisPath>]",
+				Action:    utils.MigrateFlags(bftSigner),
+				Flags: []cli.Flag{
+					utils.PasswordFileFlag,
+				},
+				Description: `
+    geth bft signer <keyfile> <ipcpath> [<genesisPath>]
+
+Decrypts the validator key of the given keystore file and signs the consensus
+messages of a node started with --validator-signer <ipcpath>. The signer keeps
+the last proposal, vote and precommit it signed in <keyfile>.watermark and
+refuses to sign for earlier rounds or to sign a conflicting message for the
+same round, protecting the validator from equivocating.
+
+Messages are signed under the signature domain of the network of the given
+genesis file, whatever network the node claims. Without a genesis file they
+are signed without domain.`,
+			},
+		},
+	}
+)
+
+// bftInitValidators creates a batch of validator accounts and optionally
+// injects them into a genesis file.
+func bftInitValidators(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires the number of validators as argument.")
+	}
+	count, err := strconv.Atoi(ctx.Args().First())
+	if err != nil || count <= 0 {
+		utils.Fatalf("Invalid validator count: %s", ctx.Args().First())
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	passwords := utils.MakePasswordList(ctx)
+
+	validators := make([]common.Address, 0, count)
+	for i := 0; i < count; i++ {
+		password := getPassPhrase(fmt.Sprintf("Validator #%d is locked with a password. Please give a password. Do not forget this password.", i), true, i, passwords)
+		account, err := ks.NewAccount(password)
+		if err != nil {
+			utils.Fatalf("Failed to create validator account: %v", err)
+		}
+		log.Info("Created validator key", "address", account.Address, "path", account.URL.Path)
+		validators = append(validators, account.Address)
+	}
+	genesisPath := ctx.Args().Get(1)
+	if genesisPath == "" {
+		out, _ := json.MarshalIndent(validators, "", "  ")
+		fmt.Println(string(out))
+		return nil
+	}
+	blob, err := ioutil.ReadFile(genesisPath)
+	if err != nil {
+		utils.Fatalf("Failed to read genesis file: %v", err)
+	}
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal(blob, genesis); err != nil {
+		utils.Fatalf("Invalid genesis file: %v", err)
+	}
+	if genesis.Config == nil {
+		genesis.Config = new(params.ChainConfig)
+	}
+	if genesis.Config.BFT == nil {
+		genesis.Config.BFT = &params.BFTConfig{DomainBlock: new(big.Int)}
+	}
+	genesis.Config.Ethash, genesis.Config.Clique = nil, nil
+	genesis.Config.BFT.Validators = validators
+
+	out, _ := json.MarshalIndent(genesis, "", "  ")
+	if err := ioutil.WriteFile(genesisPath, out, 0644); err != nil {
+		utils.Fatalf("Failed to write genesis file: %v", err)
+	}
+	log.Info("Wrote validators into genesis", "path", genesisPath, "count", count)
+	return nil
+}
+
+// bftVerifyChain checks the commit certificates of a range of canonical blocks.
+func bftVerifyChain(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb, bftDb := openBFTDatabases(ctx, stack)
+	defer chainDb.Close()
+	defer bftDb.Close()
+
+	validators, domain := bftValidators(ctx, chainDb)
+	first, last := bftBlockRange(ctx, chainDb, 0)
+	if first == 0 {
+		first = 1 // genesis carries no certificate
+	}
+	var (
+		start    = time.Now()
+		reported = time.Now()
+		failures int
+	)
+	for number := first; number <= last; number++ {
+		hash := core.GetCanonicalHash(chainDb, number)
+		if hash == (common.Hash{}) {
+			utils.Fatalf("Canonical block #%d not found", number)
+		}
+		pls := bft.GetPrecommitLockset(bftDb, hash)
+		if err := bft.VerifyCommit(pls, hash, number, validators, domain); err != nil {
+			log.Error("Invalid commit certificate", "number", number, "hash", hash, "err", err)
+			failures++
+		}
+		if time.Since(reported) > 8*time.Second {
+			log.Info("Verifying commit certificates", "number", number, "failures", failures)
+			reported = time.Now()
+		}
+	}
+	if failures > 0 {
+		utils.Fatalf("Chain verification failed: %d invalid certificates", failures)
+	}
+	fmt.Printf("Verified %d blocks in %v\n", last-first+1, time.Since(start))
+	return nil
+}
+
+// certificateVote is the JSON representation of a precommit vote.
+type certificateVote struct {
+	Signer    *common.Address `json:"signer"`
+	Height    uint64          `json:"height"`
+	Round     uint64          `json:"round"`
+	Blockhash common.Hash     `json:"blockHash"`
+	VoteType  uint64          `json:"voteType"`
+}
+
+// certificateDump is the JSON representation of a precommit lockset.
+type certificateDump struct {
+	Block            common.Hash        `json:"block"`
+	Quorum           bool               `json:"quorum"`
+	EligibleVotesNum uint64             `json:"eligibleVotesNum"`
+	Votes            []*certificateVote `json:"votes"`
+}
+
+func newCertificateDump(pls *btypes.PrecommitLockSet, domain *btypes.Domain) *certificateDump {
+	quorum, hash := pls.HasQuorum()
+	dump := &certificateDump{
+		Block:            hash,
+		Quorum:           quorum,
+		EligibleVotesNum: pls.EligibleVotesNum,
+		Votes:            []*certificateVote{},
+	}
+	for _, v := range pls.PrecommitVotes {
+		vote := &certificateVote{
+			Height:    v.Height,
+			Round:     v.Round,
+			Blockhash: v.Blockhash,
+			VoteType:  v.VoteType,
+		}
+		if signer, err := v.From(domain); err == nil {
+			vote.Signer = &signer
+		}
+		dump.Votes = append(dump.Votes, vote)
+	}
+	return dump
+}
+
+// bftDumpRoundState prints the stored consensus state as JSON.
+func bftDumpRoundState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb, bftDb := openBFTDatabases(ctx, stack)
+	defer chainDb.Close()
+	defer bftDb.Close()
+
+	var locksets []*btypes.PrecommitLockSet
+	if len(ctx.Args()) == 0 {
+		pls := bft.GetLastCommittingLockset(bftDb)
+		if pls == nil {
+			utils.Fatalf("No committing lockset stored")
+		}
+		locksets = append(locksets, pls)
+	}
+	for _, arg := range ctx.Args() {
+		var hash common.Hash
+		if hashish(arg) {
+			hash = common.HexToHash(arg)
+		} else {
+			num, _ := strconv.ParseUint(arg, 10, 64)
+			hash = core.GetCanonicalHash(chainDb, num)
+		}
+		pls := bft.GetPrecommitLockset(bftDb, hash)
+		if pls == nil {
+			utils.Fatalf("No commit certificate stored for block %s", arg)
+		}
+		locksets = append(locksets, pls)
+	}
+	_, domain := bftValidators(ctx, chainDb)
+	for _, pls := range locksets {
+		out, _ := json.MarshalIndent(newCertificateDump(pls, domain), "", "  ")
+		fmt.Println(string(out))
+	}
+	return nil
+}
+
+// bftExportCertificates writes the commit certificates of a range of canonical
+// blocks into an RLP stream.
+func bftExportCertificates(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	chainDb, bftDb := openBFTDatabases(ctx, stack)
+	defer chainDb.Close()
+	defer bftDb.Close()
+
+	first, last := bftBlockRange(ctx, chainDb, 1)
+	if first == 0 {
+		first = 1
+	}
+	out, err := os.OpenFile(ctx.Args().First(), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		utils.Fatalf("Failed to open export file: %v", err)
+	}
+	defer out.Close()
+
+	start, exported := time.Now(), 0
+	for number := first; number <= last; number++ {
+		hash := core.GetCanonicalHash(chainDb, number)
+		pls := bft.GetPrecommitLockset(bftDb, hash)
+		if pls == nil {
+			log.Warn("Missing commit certificate", "number", number, "hash", hash)
+			continue
+		}
+		if err := rlp.Encode(out, pls); err != nil {
+			utils.Fatalf("Export error: %v", err)
+		}
+		exported++
+	}
+	fmt.Printf("Exported %d certificates in %v\n", exported, time.Since(start))
+	return nil
+}
+
+// bftImportCertificates stores the valid commit certificates of an RLP stream
+// into the bft database.
+func bftImportCertificates(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	chainDb, bftDb := openBFTDatabases(ctx, stack)
+	defer chainDb.Close()
+	defer bftDb.Close()
+
+	in, err := os.Open(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to open import file: %v", err)
+	}
+	defer in.Close()
+
+	validators, domain := bftValidators(ctx, chainDb)
+	var (
+		stream   = rlp.NewStream(in, 0)
+		start    = time.Now()
+		imported int
+		skipped  int
+	)
+	for {
+		var pls *btypes.PrecommitLockSet
+		if err := stream.Decode(&pls); err == io.EOF {
+			break
+		} else if err != nil {
+			utils.Fatalf("Import error: %v", err)
+		}
+		ok, hash := pls.HasQuorum()
+		if !ok {
+			log.Warn("Skipping certificate without quorum")
+			skipped++
+			continue
+		}
+		number := pls.Height()
+		if err := bft.VerifyCommit(pls, hash, number, validators, domain); err != nil {
+			log.Warn("Skipping invalid certificate", "number", number, "hash", hash, "err", err)
+			skipped++
+			continue
+		}
+		if canon := core.GetCanonicalHash(chainDb, number); canon != (common.Hash{}) && canon != hash {
+			log.Warn("Certificate for non-canonical block", "number", number, "hash", hash, "canonical", canon)
+		}
+		if err := bft.WritePrecommitLockset(bftDb, hash, pls); err != nil {
+			utils.Fatalf("Failed to store certificate: %v", err)
+		}
+		imported++
+	}
+	fmt.Printf("Imported %d certificates (%d skipped) in %v\n", imported, skipped, time.Since(start))
+	return nil
+}
+
+// bftSigner serves a validator key over IPC until interrupted.
+func bftSigner(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires the key file and IPC path as arguments.")
+	}
+	keyfile, endpoint := ctx.Args().Get(0), ctx.Args().Get(1)
+
+	keyjson, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		utils.Fatalf("Failed to read validator key file %s: %v", keyfile, err)
+	}
+	password := getPassPhrase("Validator key is locked with a password.", false, 0, utils.MakePasswordList(ctx))
+	key, err := keystore.DecryptKey(keyjson, password)
+	if err != nil {
+		utils.Fatalf("Failed to decrypt validator key %s: %v", keyfile, err)
+	}
+	var domain *btypes.Domain
+	if len(ctx.Args()) > 2 {
+		domain = genesisDomain(ctx.Args().Get(2))
+	}
+	service, err := bft.NewSignerService(key.PrivateKey, keyfile+".watermark", domain)
+	if err != nil {
+		utils.Fatalf("Failed to load signing watermarks: %v", err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("bftsigner", service); err != nil {
+		utils.Fatalf("Failed to register signer service: %v", err)
+	}
+	listener, err := rpc.CreateIPCListener(endpoint)
+	if err != nil {
+		utils.Fatalf("Failed to listen on %s: %v", endpoint, err)
+	}
+	go server.ServeListener(listener)
+	log.Info("Serving validator signer", "address", service.Address(), "endpoint", endpoint)
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	<-sigc
+
+	listener.Close()
+	server.Stop()
+	return nil
+}
+
+// openBFTDatabases opens the chain and bft databases of the node.
+func openBFTDatabases(ctx *cli.Context, stack *node.Node) (ethdb.Database, ethdb.Database) {
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	bftDb, err := stack.OpenDatabase("bftData", ctx.GlobalInt(utils.CacheFlag.Name), 0)
+	if err != nil {
+		utils.Fatalf("Could not open bft database: %v", err)
+	}
+	return chainDb, bftDb
+}
+
+// bftValidators returns the validator set of the chain stored in chainDb and
+// the signature domain of its consensus messages.
+func bftValidators(ctx *cli.Context, chainDb ethdb.Database) ([]common.Address, *btypes.Domain) {
+	genesis := core.GetCanonicalHash(chainDb, 0)
+	config, err := core.GetChainConfig(chainDb, genesis)
+	if err != nil {
+		config = new(params.ChainConfig)
+	}
+	return utils.MakeBFTValidators(ctx, config), bft.NewDomain(config, genesis)
+}
+
+// genesisDomain returns the signature domain of the consensus messages of the
+// network of the given genesis file.
+func genesisDomain(path string) *btypes.Domain {
+	file, err := os.Open(path)
+	if err != nil {
+		utils.Fatalf("Failed to read genesis file: %v", err)
+	}
+	defer file.Close()
+
+	genesis := new(core.Genesis)
+	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+		utils.Fatalf("Invalid genesis file: %v", err)
+	}
+	if genesis.Config == nil {
+		return nil
+	}
+	block, _ := genesis.ToBlock()
+	return bft.NewDomain(genesis.Config, block.Hash())
+}
+
+// bftBlockRange parses the optional block range arguments starting at index
+// offset, defaulting to the whole chain.
+func bftBlockRange(ctx *cli.Context, chainDb ethdb.Database, offset int) (uint64, uint64) {
+	hash := core.GetHeadBlockHash(chainDb)
+	if hash == (common.Hash{}) {
+		utils.Fatalf("No chain found in database")
+	}
+	head := core.GetBlockNumber(chainDb, hash)
+	if len(ctx.Args()) < offset+2 {
+		return 0, head
+	}
+	first, ferr := strconv.ParseUint(ctx.Args().Get(offset), 10, 64)
+	last, lerr := strconv.ParseUint(ctx.Args().Get(offset+1), 10, 64)
+	if ferr != nil || lerr != nil {
+		utils.Fatalf("Error in parsing parameters: block number not an integer")
+	}
+	if last > head {
+		last = head
+	}
+	return first, last
+}
@@ -65,6 +65,8 @@ It expects the genesis file as argument.`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			utils.BFTFlag,
+			utils.NumValidatorsFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -83,6 +85,8 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			utils.BFTFlag,
+			utils.NumValidatorsFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -119,6 +123,27 @@ Remove blockchain and state databases`,
 The arguments are interpreted as block numbers or hashes.
 Use "ethereum dump 0" to dump the genesis block.`,
 	}
+	pruneStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(pruneState),
+		Name:      "prune-state",
+		Usage:     "Delete the states of old blocks from the database",
+		ArgsUsage: "[<checkpointNum>]...",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.PruneRecentFlag,
+			utils.PruneIntervalFlag,
+			utils.NoCompactionFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The prune-state command deletes the state tries of all blocks from the database,
+except for the genesis, the last --prune.recent blocks, every --prune.interval-th
+block and the checkpoint blocks given as arguments. The states of the other blocks
+are no longer available to queries afterwards, unless the node stores state diffs
+(--state.diffs), in which case they are rebuilt from the nearest retained state.
+The node must not be running while the state is pruned.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -323,6 +348,42 @@ func dump(ctx *cli.Context) error {
 	return nil
 }
 
+func pruneState(ctx *cli.Context) error {
+	var checkpoints []uint64
+	for _, arg := range ctx.Args() {
+		num, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			utils.Fatalf("Invalid checkpoint block number %q: %v", arg, err)
+		}
+		checkpoints = append(checkpoints, num)
+	}
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	db := chainDb.(*ethdb.LDBDatabase)
+
+	start := time.Now()
+	deleted, err := core.PruneState(db, ctx.Uint64(utils.PruneRecentFlag.Name), ctx.Uint64(utils.PruneIntervalFlag.Name), checkpoints)
+	if err != nil {
+		utils.Fatalf("Pruning failed: %v", err)
+	}
+	fmt.Printf("Pruned %d trie nodes in %v.\n", deleted, time.Since(start))
+
+	if ctx.GlobalIsSet(utils.NoCompactionFlag.Name) {
+		return nil
+	}
+	// Compact the database to actually release the disk space
+	start = time.Now()
+	fmt.Println("Compacting entire database...")
+	if err := db.LDB().CompactRange(util.Range{}); err != nil {
+		utils.Fatalf("Compaction failed: %v", err)
+	}
+	fmt.Printf("Compaction done in %v.\n", time.Since(start))
+
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
@@ -73,6 +73,15 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolJournalFlag,
+		utils.TxPoolRejournalFlag,
+		utils.StateGCFlag,
+		utils.StateRecentFlag,
+		utils.StateFlushFlag,
+		utils.StateCacheFlag,
+		utils.StateDiffsFlag,
+		utils.PrivateDirFlag,
+		utils.PrivateIdentityFlag,
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
@@ -93,6 +102,8 @@ var (
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.NodeAllowlistFlag,
+		utils.NodeRegistryFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DevModeFlag,
@@ -114,6 +125,12 @@ var (
 		utils.NodeNumFlag,
 		utils.BFTFlag,
 		utils.AllowEmptyFlag,
+		utils.ValidatorKeyFlag,
+		utils.ValidatorAccountFlag,
+		utils.ValidatorSignerFlag,
+		utils.CheckpointFlag,
+		utils.CheckpointCertFlag,
+		utils.CheckpointValidatorsFlag,
 		utils.ByzantineModeFlag,
 	}
 
@@ -150,6 +167,9 @@ func init() {
 		exportCommand,
 		removedbCommand,
 		dumpCommand,
+		pruneStateCommand,
+		// See bftcmd.go:
+		bftCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
@@ -102,6 +102,25 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolJournalFlag,
+			utils.TxPoolRejournalFlag,
+		},
+	},
+	{
+		Name: "PRIVATE TRANSACTIONS",
+		Flags: []cli.Flag{
+			utils.PrivateDirFlag,
+			utils.PrivateIdentityFlag,
+		},
+	},
+	{
+		Name: "STATE GARBAGE COLLECTION",
+		Flags: []cli.Flag{
+			utils.StateGCFlag,
+			utils.StateRecentFlag,
+			utils.StateFlushFlag,
+			utils.StateCacheFlag,
+			utils.StateDiffsFlag,
 		},
 	},
 	{
@@ -151,6 +170,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.NodeAllowlistFlag,
+			utils.NodeRegistryFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
@@ -207,6 +228,12 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NodeNumFlag,
 			utils.BFTFlag,
 			utils.AllowEmptyFlag,
+			utils.ValidatorKeyFlag,
+			utils.ValidatorAccountFlag,
+			utils.ValidatorSignerFlag,
+			utils.CheckpointFlag,
+			utils.CheckpointCertFlag,
+			utils.CheckpointValidatorsFlag,
 			utils.ByzantineModeFlag,
 		},
 	},
@@ -33,20 +33,20 @@ var nodeDockerfile = `
 FROM ethereum/client-go:alpine-develop
 
 ADD genesis.json /genesis.json
-{{if .Unlock}}
+{{if or .Unlock .Validator}}
 	ADD signer.json /signer.json
 	ADD signer.pass /signer.pass
 {{end}}
 RUN \
   echo '/geth init /genesis.json' > geth.sh && \{{if .Unlock}}
 	echo 'mkdir -p /root/.ethereum/keystore/ && cp /signer.json /root/.ethereum/keystore/' >> geth.sh && \{{end}}
-	echo $'/geth --networkid {{.NetworkID}} --cache 512 --port {{.Port}} --maxpeers {{.Peers}} {{.LightFlag}} --ethstats \'{{.Ethstats}}\' {{if .BootV4}}--bootnodesv4 {{.BootV4}}{{end}} {{if .BootV5}}--bootnodesv5 {{.BootV5}}{{end}} {{if .Etherbase}}--etherbase {{.Etherbase}} --mine{{end}}{{if .Unlock}}--unlock 0 --password /signer.pass --mine{{end}} --targetgaslimit {{.GasTarget}} --gasprice {{.GasPrice}}' >> geth.sh
+	echo $'/geth --networkid {{.NetworkID}} --cache 512 --port {{.Port}} --maxpeers {{.Peers}} {{.LightFlag}} --ethstats \'{{.Ethstats}}\' {{if .BootV4}}--bootnodesv4 {{.BootV4}}{{end}} {{if .BootV5}}--bootnodesv5 {{.BootV5}}{{end}} {{if .Etherbase}}--etherbase {{.Etherbase}} --mine{{end}}{{if .Unlock}}--unlock 0 --password /signer.pass --mine{{end}}{{if .Validator}}--validator-key /signer.json --password /signer.pass --mine{{end}} --targetgaslimit {{.GasTarget}} --gasprice {{.GasPrice}}' >> geth.sh
 
 ENTRYPOINT ["/bin/sh", "geth.sh"]
 `
 
 // nodeComposefile is the docker-compose.yml file required to deploy and maintain
-// an Ethereum node (bootnode or miner for now).
+// an Ethereum node (bootnode, miner or bft observer for now).
 var nodeComposefile = `
 version: '2'
 services:
@@ -74,10 +74,8 @@ services:
 // deployNode deploys a new Ethereum node container to a remote machine via SSH,
 // docker and docker-compose. If an instance with the specified network name
 // already exists there, it will be overwritten!
-func deployNode(client *sshClient, network string, bootv4, bootv5 []string, config *nodeInfos) ([]byte, error) {
-	kind := "sealnode"
-	if config.keyJSON == "" && config.etherbase == "" {
-		kind = "bootnode"
+func deployNode(client *sshClient, network string, kind string, bootv4, bootv5 []string, config *nodeInfos) ([]byte, error) {
+	if kind == "bootnode" {
 		bootv4 = make([]string, 0)
 		bootv5 = make([]string, 0)
 	}
@@ -101,7 +99,8 @@ func deployNode(client *sshClient, network string, bootv4, bootv5 []string, conf
 		"Etherbase": config.etherbase,
 		"GasTarget": uint64(1000000 * config.gasTarget),
 		"GasPrice":  uint64(1000000000 * config.gasPrice),
-		"Unlock":    config.keyJSON != "",
+		"Unlock":    config.keyJSON != "" && !config.validator,
+		"Validator": config.keyJSON != "" && config.validator,
 	})
 	files[filepath.Join(workdir, "Dockerfile")] = dockerfile.Bytes()
 
@@ -155,6 +154,7 @@ type nodeInfos struct {
 	etherbase  string
 	keyJSON    string
 	keyPass    string
+	validator  bool // Whether keyJSON is a bft validator key instead of a clique signer
 	gasTarget  float64
 	gasPrice   float64
 }
@@ -169,14 +169,10 @@ func (info *nodeInfos) String() string {
 		info.portFull, discv5, info.datadir, info.peersTotal, info.peersLight, info.ethstats, info.gasTarget, info.gasPrice)
 }
 
-// checkNode does a health-check against an boot or seal node server to verify
-// whether it's running, and if yes, whether it's responsive.
-func checkNode(client *sshClient, network string, boot bool) (*nodeInfos, error) {
-	kind := "bootnode"
-	if !boot {
-		kind = "sealnode"
-	}
-	// Inspect a possible bootnode container on the host
+// checkNode does a health-check against a boot, seal or observer node server to
+// verify whether it's running, and if yes, whether it's responsive.
+func checkNode(client *sshClient, network string, kind string) (*nodeInfos, error) {
+	// Inspect a possible node container of the given kind on the host
 	infos, err := inspectContainer(client, fmt.Sprintf("%s_%s_1", network, kind))
 	if err != nil {
 		return nil, err
@@ -49,6 +49,7 @@ func (w *wizard) makeGenesis() {
 	fmt.Println("Which consensus engine to use? (default = clique)")
 	fmt.Println(" 1. Ethash - proof-of-work")
 	fmt.Println(" 2. Clique - proof-of-authority")
+	fmt.Println(" 3. BFT    - byzantine fault tolerant consensus")
 
 	choice := w.read()
 	switch {
@@ -83,18 +84,92 @@ func (w *wizard) makeGenesis() {
 			}
 		}
 		// Sort the signers and embed into the extra-data section
-		for i := 0; i < len(signers); i++ {
-			for j := i + 1; j < len(signers); j++ {
-				if bytes.Compare(signers[i][:], signers[j][:]) > 0 {
-					signers[i], signers[j] = signers[j], signers[i]
-				}
-			}
-		}
+		sortAddresses(signers)
+
 		genesis.ExtraData = make([]byte, 32+len(signers)*common.AddressLength+65)
 		for i, signer := range signers {
 			copy(genesis.ExtraData[32+i*common.AddressLength:], signer[:])
 		}
 
+	case choice == "3":
+		// In the case of bft, configure the validator set and round timeouts
+		genesis.Difficulty = big.NewInt(1)
+		genesis.ExtraData = make([]byte, 32)
+		genesis.Config.BFT = &params.BFTConfig{
+			RoundTimeout:       3,
+			RoundTimeoutFactor: 1.5,
+			PrecommitTimeout:   2,
+			DomainBlock:        new(big.Int),
+			RewardBlock:        new(big.Int),
+		}
+		fmt.Println()
+		fmt.Println("Which accounts are allowed to validate? (mandatory at least one)")
+
+		for {
+			if address := w.readAddress(); address != nil {
+				genesis.Config.BFT.Validators = append(genesis.Config.BFT.Validators, *address)
+				continue
+			}
+			if len(genesis.Config.BFT.Validators) > 0 {
+				break
+			}
+		}
+		// Proposers are picked round robin, so the validator order must be canonical
+		sortAddresses(genesis.Config.BFT.Validators)
+
+		fmt.Println()
+		fmt.Println("How many seconds should validators wait for a proposal? (default = 3)")
+		genesis.Config.BFT.RoundTimeout = uint64(w.readDefaultInt(3))
+
+		fmt.Println()
+		fmt.Println("How many seconds should validators wait for precommits? (default = 2)")
+		genesis.Config.BFT.PrecommitTimeout = uint64(w.readDefaultInt(2))
+
+		fmt.Println()
+		fmt.Println("How many seconds should at least pass between blocks? (default = 0)")
+		genesis.Config.BFT.BlockPeriod = uint64(w.readDefaultInt(0))
+
+		fmt.Println()
+		fmt.Println("Should block times be the median of the validators' clocks? (y/n) (default = no)")
+		if w.readDefaultString("n") == "y" {
+			genesis.Config.BFT.MedianTimeBlock = new(big.Int)
+		}
+
+		fmt.Println()
+		fmt.Println("Should validators seal empty blocks? (y/n) (default = no)")
+		genesis.Config.BFT.AllowEmpty = w.readDefaultString("n") == "y"
+
+		if !genesis.Config.BFT.AllowEmpty {
+			fmt.Println()
+			fmt.Println("How many seconds without transactions until an empty block is sealed? (default = 0 = never)")
+			genesis.Config.BFT.EmptyBlockPeriod = uint64(w.readDefaultInt(0))
+
+			fmt.Println()
+			fmt.Println("How many transactions should a block wait for? (default = 1)")
+			genesis.Config.BFT.MinBlockTxs = uint64(w.readDefaultInt(1))
+
+			if genesis.Config.BFT.MinBlockTxs > 1 {
+				fmt.Println()
+				fmt.Println("How many seconds may transactions wait before a partial block is sealed? (default = 0 = forever)")
+				genesis.Config.BFT.MaxTxWait = uint64(w.readDefaultInt(0))
+			}
+		}
+
+		fmt.Println()
+		fmt.Println("How many ethers should the proposer of a block be rewarded? (default = 0)")
+		if reward := w.readDefaultInt(0); reward > 0 {
+			genesis.Config.BFT.BlockReward = new(big.Int).Mul(big.NewInt(int64(reward)), big.NewInt(params.Ether))
+		}
+		fmt.Println()
+		fmt.Println("Which account should receive a share of the rewards and fees? (advisable none)")
+		if address := w.readAddress(); address != nil {
+			genesis.Config.BFT.Treasury = address
+
+			fmt.Println()
+			fmt.Println("What percentage of the rewards and fees should go to it? (default = 10)")
+			genesis.Config.BFT.TreasuryShare = uint64(w.readDefaultInt(10))
+		}
+
 	default:
 		log.Crit("Invalid consensus engine choice", "choice", choice)
 	}
@@ -134,3 +209,14 @@ func (w *wizard) makeGenesis() {
 	// All done, store the genesis and flush to disk
 	w.conf.genesis = genesis
 }
+
+// sortAddresses sorts a list of accounts in place by their byte representation.
+func sortAddresses(addrs []common.Address) {
+	for i := 0; i < len(addrs); i++ {
+		for j := i + 1; j < len(addrs); j++ {
+			if bytes.Compare(addrs[i][:], addrs[j][:]) > 0 {
+				addrs[i], addrs[j] = addrs[j], addrs[i]
+			}
+		}
+	}
+}
@@ -76,7 +76,7 @@ func (w *wizard) networkStats(tips bool) {
 			protips.ethstats = infos.config
 		}
 		logger.Debug("Checking for bootnode availability")
-		if infos, err := checkNode(client, w.network, true); err != nil {
+		if infos, err := checkNode(client, w.network, "bootnode"); err != nil {
 			if err != ErrServiceUnknown {
 				services["bootnode"] = err.Error()
 			}
@@ -90,7 +90,7 @@ func (w *wizard) networkStats(tips bool) {
 			}
 		}
 		logger.Debug("Checking for sealnode availability")
-		if infos, err := checkNode(client, w.network, false); err != nil {
+		if infos, err := checkNode(client, w.network, "sealnode"); err != nil {
 			if err != ErrServiceUnknown {
 				services["sealnode"] = err.Error()
 			}
@@ -98,6 +98,15 @@ func (w *wizard) networkStats(tips bool) {
 			services["sealnode"] = infos.String()
 			protips.genesis = string(infos.genesis)
 		}
+		logger.Debug("Checking for observer availability")
+		if infos, err := checkNode(client, w.network, "observer"); err != nil {
+			if err != ErrServiceUnknown {
+				services["observer"] = err.Error()
+			}
+		} else {
+			services["observer"] = infos.String()
+			protips.genesis = string(infos.genesis)
+		}
 		logger.Debug("Checking for faucet availability")
 		if infos, err := checkFaucet(client, w.network); err != nil {
 			if err != ErrServiceUnknown {
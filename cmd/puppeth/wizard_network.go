@@ -179,19 +179,22 @@ func (w *wizard) deployComponent() {
 	fmt.Println(" 4. Wallet    - Browser wallet for quick sends (todo)")
 	fmt.Println(" 5. Faucet    - Crypto faucet to give away funds")
 	fmt.Println(" 6. Dashboard - Website listing above web-services")
+	fmt.Println(" 7. Observer  - Full node following a BFT network")
 
 	switch w.read() {
 	case "1":
 		w.deployEthstats()
 	case "2":
-		w.deployNode(true)
+		w.deployNode("bootnode")
 	case "3":
-		w.deployNode(false)
+		w.deployNode("sealnode")
 	case "4":
 	case "5":
 		w.deployFaucet()
 	case "6":
 		w.deployDashboard()
+	case "7":
+		w.deployNode("observer")
 	default:
 		log.Error("That's not something I can do")
 	}
@@ -26,13 +26,18 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 )
 
-// deployNode creates a new node configuration based on some user input.
-func (w *wizard) deployNode(boot bool) {
+// deployNode creates a new node configuration of the given kind (bootnode,
+// sealnode or observer) based on some user input.
+func (w *wizard) deployNode(kind string) {
 	// Do some sanity check before the user wastes time on input
 	if w.conf.genesis == nil {
 		log.Error("No genesis block configured")
 		return
 	}
+	if kind == "observer" && w.conf.genesis.Config.BFT == nil {
+		log.Error("Observers can only follow BFT networks")
+		return
+	}
 	if w.conf.ethstats == "" {
 		log.Error("No ethstats server configured")
 		return
@@ -45,9 +50,9 @@ func (w *wizard) deployNode(boot bool) {
 	client := w.servers[server]
 
 	// Retrieve any active ethstats configurations from the server
-	infos, err := checkNode(client, w.network, boot)
+	infos, err := checkNode(client, w.network, kind)
 	if err != nil {
-		if boot {
+		if kind == "bootnode" {
 			infos = &nodeInfos{portFull: 30303, peersTotal: 512, peersLight: 256}
 		} else {
 			infos = &nodeInfos{portFull: 30303, peersTotal: 50, peersLight: 0, gasTarget: 4.7, gasPrice: 18}
@@ -90,7 +95,7 @@ func (w *wizard) deployNode(boot bool) {
 		infos.ethstats = w.readDefaultString(infos.ethstats) + ":" + w.conf.ethstats
 	}
 	// If the node is a miner/signer, load up needed credentials
-	if !boot {
+	if kind == "sealnode" {
 		if w.conf.genesis.Config.Ethash != nil {
 			// Ethash based miners only need an etherbase to mine against
 			fmt.Println()
@@ -106,7 +111,9 @@ func (w *wizard) deployNode(boot bool) {
 				fmt.Printf("What address should the miner user? (default = %s)\n", infos.etherbase)
 				infos.etherbase = w.readDefaultAddress(common.HexToAddress(infos.etherbase)).Hex()
 			}
-		} else if w.conf.genesis.Config.Clique != nil {
+		} else if w.conf.genesis.Config.Clique != nil || w.conf.genesis.Config.BFT != nil {
+			infos.validator = w.conf.genesis.Config.BFT != nil
+
 			// If a previous signer was already set, offer to reuse it
 			if infos.keyJSON != "" {
 				if key, err := keystore.DecryptKey([]byte(infos.keyJSON), infos.keyPass); err != nil {
@@ -119,7 +126,7 @@ func (w *wizard) deployNode(boot bool) {
 					}
 				}
 			}
-			// Clique based signers need a keyfile and unlock password, ask if unavailable
+			// Clique signers and BFT validators need a keyfile and unlock password, ask if unavailable
 			if infos.keyJSON == "" {
 				fmt.Println()
 				fmt.Println("Please paste the signer's key JSON:")
@@ -134,6 +141,20 @@ func (w *wizard) deployNode(boot bool) {
 					return
 				}
 			}
+			// BFT keys outside of the genesis validator set can only observe
+			if infos.validator {
+				key, _ := keystore.DecryptKey([]byte(infos.keyJSON), infos.keyPass)
+
+				known := false
+				for _, validator := range w.conf.genesis.Config.BFT.Validators {
+					if validator == key.Address {
+						known = true
+					}
+				}
+				if !known {
+					log.Warn("Signing account is not a genesis validator", "address", key.Address)
+				}
+			}
 		}
 		// Establish the gas dynamics to be enforced by the signer
 		fmt.Println()
@@ -145,7 +166,7 @@ func (w *wizard) deployNode(boot bool) {
 		infos.gasPrice = w.readDefaultFloat(infos.gasPrice)
 	}
 	// Try to deploy the full node on the host
-	if out, err := deployNode(client, w.network, w.conf.bootFull, w.conf.bootLight, infos); err != nil {
+	if out, err := deployNode(client, w.network, kind, w.conf.bootFull, w.conf.bootLight, infos); err != nil {
 		log.Error("Failed to deploy Ethereum node container", "err", err)
 		if len(out) > 0 {
 			fmt.Printf("%s\n", out)
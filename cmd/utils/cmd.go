@@ -26,6 +26,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/debug"
@@ -118,6 +119,7 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 	}
 
 	stream := rlp.NewStream(reader, 0)
+	engine, _ := bft.FromEngine(chain.Engine())
 
 	// Run actual the import.
 	blocks := make(types.Blocks, importBatchSize)
@@ -129,8 +131,8 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 		}
 		i := 0
 		for ; i < importBatchSize; i++ {
-			var b types.Block
-			if err := stream.Decode(&b); err == io.EOF {
+			b, cert, err := bft.DecodeBlock(stream)
+			if err == io.EOF {
 				break
 			} else if err != nil {
 				return fmt.Errorf("at block %d: %v", n, err)
@@ -140,7 +142,13 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 				i--
 				continue
 			}
-			blocks[i] = &b
+			// Store the commit certificate ahead of the block for seal verification
+			if cert != nil && engine != nil {
+				if err := engine.ImportCertificate(b.Header(), cert); err != nil {
+					return fmt.Errorf("invalid certificate for block %d: %v", b.NumberU64(), err)
+				}
+			}
+			blocks[i] = b
 			n++
 		}
 		if i == 0 {
@@ -185,7 +193,7 @@ func ExportChain(blockchain *core.BlockChain, fn string) error {
 		defer writer.(*gzip.Writer).Close()
 	}
 
-	if err := blockchain.Export(writer); err != nil {
+	if err := exportChain(blockchain, writer, 0, blockchain.CurrentBlock().NumberU64()); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain", "file", fn)
@@ -208,9 +216,18 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 		defer writer.(*gzip.Writer).Close()
 	}
 
-	if err := blockchain.ExportN(writer, first, last); err != nil {
+	if err := exportChain(blockchain, writer, first, last); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain to", "file", fn)
 	return nil
 }
+
+// exportChain writes a range of the chain to w, interleaving the blocks with
+// their commit certificates on BFT chains.
+func exportChain(blockchain *core.BlockChain, w io.Writer, first uint64, last uint64) error {
+	if engine, ok := bft.FromEngine(blockchain.Engine()); ok {
+		return engine.ExportChain(blockchain, w, first, last)
+	}
+	return blockchain.ExportN(w, first, last)
+}
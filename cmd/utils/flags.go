@@ -19,7 +19,6 @@ package utils
 
 import (
 	"crypto/ecdsa"
-	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -32,6 +31,9 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
+	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -53,6 +55,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/private"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv5"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -243,6 +246,59 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolJournalFlag = cli.StringFlag{
+		Name:  "txpool.journal",
+		Usage: "Disk journal for local transaction to survive node restarts",
+		Value: eth.DefaultConfig.TxPool.Journal,
+	}
+	TxPoolRejournalFlag = cli.DurationFlag{
+		Name:  "txpool.rejournal",
+		Usage: "Time interval to regenerate the local transaction journal",
+		Value: eth.DefaultConfig.TxPool.Rejournal,
+	}
+	// State garbage collection settings
+	StateGCFlag = cli.BoolFlag{
+		Name:  "state.gc",
+		Usage: "Keep the states of recent blocks in memory, only flushing some of them to disk",
+	}
+	StateRecentFlag = cli.Uint64Flag{
+		Name:  "state.recent",
+		Usage: "Number of recent block states kept in memory",
+		Value: core.DefaultStateGCConfig.Recent,
+	}
+	StateFlushFlag = cli.Uint64Flag{
+		Name:  "state.flush",
+		Usage: "Number of blocks between states flushed to disk",
+		Value: core.DefaultStateGCConfig.FlushInterval,
+	}
+	StateCacheFlag = cli.IntFlag{
+		Name:  "state.cache",
+		Usage: "Megabytes of memory allowed for the recent states before flushing them",
+		Value: int(core.DefaultStateGCConfig.MemoryLimit / 1024 / 1024),
+	}
+	StateDiffsFlag = cli.BoolFlag{
+		Name:  "state.diffs",
+		Usage: "Store the state changes of every block, keeping all historical states available",
+	}
+	PruneRecentFlag = cli.Uint64Flag{
+		Name:  "prune.recent",
+		Usage: "Number of recent blocks whose states survive pruning",
+		Value: core.DefaultStateGCConfig.Recent,
+	}
+	PruneIntervalFlag = cli.Uint64Flag{
+		Name:  "prune.interval",
+		Usage: "Number of blocks between old states surviving pruning, as bases to rebuild historical states from (0 = none)",
+		Value: core.DefaultStateGCConfig.FlushInterval,
+	}
+	// Private transaction settings
+	PrivateDirFlag = DirectoryFlag{
+		Name:  "private.dir",
+		Usage: "Directory shared by the participants to exchange private transaction payloads",
+	}
+	PrivateIdentityFlag = cli.StringFlag{
+		Name:  "private.identity",
+		Usage: "Identity of the local node among the participants of private transactions",
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -438,6 +494,14 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	NodeAllowlistFlag = cli.StringFlag{
+		Name:  "node-allowlist",
+		Usage: "Only allow the nodes listed in the given JSON file of enode URLs to connect",
+	}
+	NodeRegistryFlag = cli.BoolFlag{
+		Name:  "node-registry",
+		Usage: "Only allow the nodes permitted by the permission registry of the chain to connect",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -491,6 +555,30 @@ var (
 		Name:  "allow-empty",
 		Usage: "allow empty block",
 	}
+	ValidatorKeyFlag = cli.StringFlag{
+		Name:  "validator-key",
+		Usage: "keystore file of the bft validator key (unlocked with --password)",
+	}
+	ValidatorAccountFlag = cli.StringFlag{
+		Name:  "validator-account",
+		Usage: "address of the keystore account signing bft messages (unlocked with --password)",
+	}
+	ValidatorSignerFlag = cli.StringFlag{
+		Name:  "validator-signer",
+		Usage: "IPC path or HTTP URL of an external bft signer holding the validator key",
+	}
+	CheckpointFlag = cli.StringFlag{
+		Name:  "checkpoint",
+		Usage: "hash of a trusted bft block to start an empty chain from (requires --checkpoint-cert)",
+	}
+	CheckpointCertFlag = cli.StringFlag{
+		Name:  "checkpoint-cert",
+		Usage: "file holding the commit certificate of the checkpoint, as written by 'geth bft export-certificates'",
+	}
+	CheckpointValidatorsFlag = cli.StringFlag{
+		Name:  "checkpoint-validators",
+		Usage: "comma separated validators certifying the checkpoint, required if the set changed since the genesis (default = validators of the genesis)",
+	}
 	ByzantineModeFlag = cli.IntFlag{
 		Name:  "byzantine-mode",
 		Usage: "changes the mode for node strategy, 0 is normal, 1 is DifferentProposal, 2 is AlwaysVote, 3 is AlwaysAgree, 4 is NoResponse, 5 is ByzantineMode with 1~3",
@@ -528,7 +616,7 @@ func setNodeKey(ctx *cli.Context, cfg *p2p.Config) {
 	switch {
 	case file != "" && hex != "":
 		Fatalf("Options %q and %q are mutually exclusive", NodeKeyFileFlag.Name, NodeKeyHexFlag.Name)
-	case ctx.GlobalBool(BFTFlag.Name):
+	case ctx.GlobalBool(BFTFlag.Name) && !ctx.GlobalIsSet(ValidatorKeyFlag.Name):
 		nodeNum := ctx.GlobalString(NodeNumFlag.Name)
 		key, _ = MakePrivatekey(nodeNum)
 		cfg.PrivateKey = key
@@ -773,9 +861,61 @@ func MakePasswordList(ctx *cli.Context) []string {
 	return lines
 }
 
-func MakeBFTPrivateKeyHex(ctx *cli.Context) string {
-	key, _ := MakePrivatekey(ctx.GlobalString(NodeNumFlag.Name))
-	return PrikeyToHex(key)
+// MakeValidatorSigner creates the signer of the bft validator from the
+// --validator-signer, --validator-account or --validator-key flags, returning
+// nil if none is set.
+func MakeValidatorSigner(ctx *cli.Context, stack *node.Node) bft.ConsensusSigner {
+	checkExclusive(ctx, ValidatorSignerFlag, ValidatorAccountFlag, ValidatorKeyFlag)
+
+	switch {
+	case ctx.GlobalIsSet(ValidatorSignerFlag.Name):
+		endpoint := ctx.GlobalString(ValidatorSignerFlag.Name)
+		signer, err := bft.NewExternalSigner(endpoint)
+		if err != nil {
+			Fatalf("Failed to connect to validator signer %s: %v", endpoint, err)
+		}
+		return signer
+	case ctx.GlobalIsSet(ValidatorAccountFlag.Name):
+		ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+		account, err := MakeAddress(ks, ctx.GlobalString(ValidatorAccountFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", ValidatorAccountFlag.Name, err)
+		}
+		password := ""
+		if passwords := MakePasswordList(ctx); len(passwords) > 0 {
+			password = passwords[0]
+		}
+		if err := ks.Unlock(account, password); err != nil {
+			Fatalf("Failed to unlock validator account %s: %v", account.Address.Hex(), err)
+		}
+		signer, err := bft.NewKeystoreSigner(stack.AccountManager(), account.Address)
+		if err != nil {
+			Fatalf("Option %q: %v", ValidatorAccountFlag.Name, err)
+		}
+		return signer
+	case ctx.GlobalIsSet(ValidatorKeyFlag.Name):
+		return bft.NewKeySigner(MakeValidatorKey(ctx))
+	}
+	return nil
+}
+
+// MakeValidatorKey decrypts the bft validator key from the keystore file given
+// with --validator-key, using the first password of --password.
+func MakeValidatorKey(ctx *cli.Context) *ecdsa.PrivateKey {
+	path := ctx.GlobalString(ValidatorKeyFlag.Name)
+	keyjson, err := ioutil.ReadFile(path)
+	if err != nil {
+		Fatalf("Failed to read validator key file %s: %v", path, err)
+	}
+	password := ""
+	if passwords := MakePasswordList(ctx); len(passwords) > 0 {
+		password = passwords[0]
+	}
+	key, err := keystore.DecryptKey(keyjson, password)
+	if err != nil {
+		Fatalf("Failed to decrypt validator key %s: %v", path, err)
+	}
+	return key.PrivateKey
 }
 
 func MakePrivatekey(seed string) (*ecdsa.PrivateKey, error) {
@@ -783,10 +923,6 @@ func MakePrivatekey(seed string) (*ecdsa.PrivateKey, error) {
 	return crypto.ToECDSA(crypto.Keccak256(s))
 }
 
-func PrikeyToHex(key *ecdsa.PrivateKey) string {
-	return hex.EncodeToString(crypto.FromECDSA(key))
-}
-
 // create validator addresses
 func MakeValidators(accman *accounts.Manager, ctx *cli.Context) []common.Address {
 	num_validators := ctx.GlobalInt(NumValidatorsFlag.Name)
@@ -803,6 +939,20 @@ func MakeValidators(accman *accounts.Manager, ctx *cli.Context) []common.Address
 	return validators
 }
 
+// MakeBFTValidators returns the validator set of a BFT chain, taken from the
+// genesis configuration or derived from the --num-validators development keys.
+func MakeBFTValidators(ctx *cli.Context, config *params.ChainConfig) []common.Address {
+	if config.BFT != nil && len(config.BFT.Validators) > 0 {
+		return config.BFT.Validators
+	}
+	validators := []common.Address{}
+	for i := 0; i < ctx.GlobalInt(NumValidatorsFlag.Name); i++ {
+		privatekey, _ := MakePrivatekey(strconv.Itoa(i))
+		validators = append(validators, crypto.PubkeyToAddress(privatekey.PublicKey))
+	}
+	return validators
+}
+
 func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	setNodeKey(ctx, cfg)
 	setNAT(ctx, cfg)
@@ -839,6 +989,14 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NetRestrict = list
 	}
 
+	if path := ctx.GlobalString(NodeAllowlistFlag.Name); path != "" {
+		filter, err := p2p.NewFileNodeFilter(path)
+		if err != nil {
+			Fatalf("Option %q: %v", NodeAllowlistFlag.Name, err)
+		}
+		cfg.NodeFilter = filter
+	}
+
 	if ctx.GlobalBool(DevModeFlag.Name) {
 		// --dev mode can't use p2p networking.
 		cfg.MaxPeers = 0
@@ -910,6 +1068,30 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolJournalFlag.Name) {
+		cfg.Journal = ctx.GlobalString(TxPoolJournalFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
+		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
+	}
+}
+
+func setStateGC(ctx *cli.Context, cfg *eth.Config) {
+	if !ctx.GlobalBool(StateGCFlag.Name) {
+		return
+	}
+	gc := core.DefaultStateGCConfig
+	if ctx.GlobalIsSet(StateRecentFlag.Name) {
+		gc.Recent = ctx.GlobalUint64(StateRecentFlag.Name)
+	}
+	if ctx.GlobalIsSet(StateFlushFlag.Name) {
+		gc.FlushInterval = ctx.GlobalUint64(StateFlushFlag.Name)
+	}
+	if ctx.GlobalIsSet(StateCacheFlag.Name) {
+		gc.MemoryLimit = common.StorageSize(ctx.GlobalInt(StateCacheFlag.Name)) * 1024 * 1024
+	}
+	gc.Diffs = ctx.GlobalBool(StateDiffsFlag.Name)
+	cfg.StateGC = &gc
 }
 
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
@@ -933,14 +1115,73 @@ func setEthash(ctx *cli.Context, cfg *eth.Config) {
 	}
 }
 
+func setPrivate(ctx *cli.Context, cfg *eth.Config) {
+	if !ctx.GlobalIsSet(PrivateDirFlag.Name) {
+		return
+	}
+	identity := ctx.GlobalString(PrivateIdentityFlag.Name)
+	if identity == "" {
+		Fatalf("Option %q requires %q", PrivateDirFlag.Name, PrivateIdentityFlag.Name)
+	}
+	manager, err := private.NewFileManager(ctx.GlobalString(PrivateDirFlag.Name), identity)
+	if err != nil {
+		Fatalf("Option %q: %v", PrivateDirFlag.Name, err)
+	}
+	cfg.PrivateManager = manager
+}
+
 func setBFT(ctx *cli.Context, cfg *eth.Config, stack *node.Node) {
 	cfg.BFT = ctx.GlobalBool(BFTFlag.Name)
-	if cfg.BFT {
+	if signer := MakeValidatorSigner(ctx, stack); signer != nil {
+		// Validator set comes from the genesis, only the local signer is needed
+		cfg.Signer = signer
+		cfg.Etherbase = signer.Address()
+	} else if cfg.BFT {
 		cfg.Validators = MakeValidators(stack.AccountManager(), ctx)
-		cfg.PrivateKeyHex = MakeBFTPrivateKeyHex(ctx)
-		cfg.AllowEmpty = ctx.GlobalBool(AllowEmptyFlag.Name)
-		cfg.ByzantineMode = ctx.GlobalInt(ByzantineModeFlag.Name)
+		key, _ := MakePrivatekey(ctx.GlobalString(NodeNumFlag.Name))
+		cfg.Signer = bft.NewKeySigner(key)
+	}
+	cfg.AllowEmpty = ctx.GlobalBool(AllowEmptyFlag.Name)
+	cfg.ByzantineMode = ctx.GlobalInt(ByzantineModeFlag.Name)
+	cfg.Checkpoint = MakeCheckpoint(ctx)
+}
+
+// MakeCheckpoint loads the trusted checkpoint given with --checkpoint, returning
+// nil if none is set.
+func MakeCheckpoint(ctx *cli.Context) *bft.Checkpoint {
+	if !ctx.GlobalIsSet(CheckpointFlag.Name) {
+		return nil
+	}
+	hex := ctx.GlobalString(CheckpointFlag.Name)
+	if len(common.FromHex(hex)) != common.HashLength {
+		Fatalf("Option %q: invalid block hash %s", CheckpointFlag.Name, hex)
+	}
+	hash := common.HexToHash(hex)
+
+	var validators []common.Address
+	if list := ctx.GlobalString(CheckpointValidatorsFlag.Name); list != "" {
+		for _, addr := range strings.Split(list, ",") {
+			if addr = strings.TrimSpace(addr); !common.IsHexAddress(addr) {
+				Fatalf("Option %q: invalid address %s", CheckpointValidatorsFlag.Name, addr)
+			}
+			validators = append(validators, common.HexToAddress(addr))
+		}
 	}
+	path := ctx.GlobalString(CheckpointCertFlag.Name)
+	if path == "" {
+		Fatalf("Option %q requires %q", CheckpointFlag.Name, CheckpointCertFlag.Name)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		Fatalf("Failed to open checkpoint certificate: %v", err)
+	}
+	defer file.Close()
+
+	checkpoint, err := bft.ReadCheckpoint(file, hash, validators)
+	if err != nil {
+		Fatalf("Failed to read checkpoint certificate %s: %v", path, err)
+	}
+	return checkpoint
 }
 
 func checkExclusive(ctx *cli.Context, flags ...cli.Flag) {
@@ -970,16 +1211,19 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	// Avoid conflicting network flags
 	checkExclusive(ctx, DevModeFlag, TestnetFlag, RinkebyFlag)
 	checkExclusive(ctx, FastSyncFlag, LightModeFlag, SyncModeFlag)
+	checkExclusive(ctx, NodeAllowlistFlag, NodeRegistryFlag)
 
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	setEtherbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
+	setStateGC(ctx, cfg)
+	setPrivate(ctx, cfg)
 	setEthash(ctx, cfg)
 	setBFT(ctx, cfg, stack)
 
 	switch {
-	case ctx.GlobalBool(BFTFlag.Name):
+	case ctx.GlobalBool(BFTFlag.Name) && !ctx.GlobalIsSet(ValidatorKeyFlag.Name):
 		key, _ := MakePrivatekey(ctx.GlobalString(NodeNumFlag.Name))
 		cfg.Etherbase = crypto.PubkeyToAddress(key.PublicKey)
 	case ctx.GlobalIsSet(SyncModeFlag.Name):
@@ -1024,6 +1268,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(NodeRegistryFlag.Name) {
+		cfg.NodeRegistry = ctx.GlobalBool(NodeRegistryFlag.Name)
+	}
 
 	// Override any default configs for hard coded networks.
 	switch {
@@ -1140,14 +1387,29 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	var err error
 	chainDb = MakeChainDatabase(ctx, stack)
 
-	engine := ethash.NewFaker()
-	if !ctx.GlobalBool(FakePoWFlag.Name) {
-		engine = ethash.New("", 1, 0, "", 1, 0)
-	}
-	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
+	config, genesis, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
 	if err != nil {
 		Fatalf("%v", err)
 	}
+	var engine consensus.Engine = ethash.NewFaker()
+	switch {
+	case config.Clique != nil:
+		engine = clique.New(config.Clique, chainDb)
+	case !ctx.GlobalBool(FakePoWFlag.Name):
+		engine = ethash.New("", 1, 0, "", 1, 0)
+	}
+	if config.BFT != nil || ctx.GlobalBool(BFTFlag.Name) {
+		bftDb, err := stack.OpenDatabase("bftData", ctx.GlobalInt(CacheFlag.Name), 0)
+		if err != nil {
+			Fatalf("Could not open bft database: %v", err)
+		}
+		verifier := bft.NewVerifier(config, genesis, bftDb, MakeBFTValidators(ctx, config))
+		if config.BftBlock != nil && config.BftBlock.Sign() > 0 {
+			engine = bft.NewMigration(config, engine, verifier)
+		} else {
+			engine = verifier
+		}
+	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 	chain, err = core.NewBlockChain(chainDb, config, engine, new(event.TypeMux), vmcfg)
 	if err != nil {
@@ -31,9 +31,40 @@ const (
 	tokenToken             = 0xff
 )
 
+// maxZeroRun is the longest run of zero bytes encoded by a single token. Longer
+// runs would collide with the special tokens above.
+const maxZeroRun = emptyShaToken - 3
+
 var empty = crypto.Keccak256([]byte(""))
 var emptyList = crypto.Keccak256([]byte{0x80})
 
+var errTruncated = errors.New("error reading bytes. token encountered without proceeding bytes")
+
+// DecodedLen returns the length of the decompressed data, allowing callers to
+// bound it before decompressing.
+func DecodedLen(dat []byte) (int, error) {
+	n := 0
+	for i := 0; i < len(dat); i++ {
+		if dat[i] != token {
+			n++
+			continue
+		}
+		if i+1 >= len(dat) {
+			return 0, errTruncated
+		}
+		switch dat[i+1] {
+		case emptyShaToken, emptyListShaToken:
+			n += 32
+		case tokenToken:
+			n++
+		default:
+			n += int(dat[i+1] - 2)
+		}
+		i++
+	}
+	return n, nil
+}
+
 func Decompress(dat []byte) ([]byte, error) {
 	buf := new(bytes.Buffer)
 
@@ -52,7 +83,7 @@ func Decompress(dat []byte) ([]byte, error) {
 				}
 				i++
 			} else {
-				return nil, errors.New("error reading bytes. token encountered without proceeding bytes")
+				return nil, errTruncated
 			}
 		} else {
 			buf.WriteByte(dat[i])
@@ -68,7 +99,7 @@ func compressChunk(dat []byte) (ret []byte, n int) {
 		return []byte{token, tokenToken}, 1
 	case len(dat) > 1 && dat[0] == 0x0 && dat[1] == 0x0:
 		j := 0
-		for j <= 254 && j < len(dat) {
+		for j < maxZeroRun && j < len(dat) {
 			if dat[j] != 0 {
 				break
 			}
@@ -48,3 +48,18 @@ func (s *CompressionRleSuite) TestDecompressSimple(c *checker.C) {
 	c.Assert(res, checker.DeepEquals, make([]byte, 10))
 
 }
+
+func (s *CompressionRleSuite) TestCompressZeroRuns(c *checker.C) {
+	for _, n := range []int{2, 10, 250, 251, 252, 253, 254, 255, 256, 1000} {
+		dat := append(append([]byte{0x01}, make([]byte, n)...), token, 0x02)
+		res := Compress(dat)
+
+		size, err := DecodedLen(res)
+		c.Assert(err, checker.IsNil)
+		c.Assert(size, checker.Equals, len(dat))
+
+		res, err = Decompress(res)
+		c.Assert(err, checker.IsNil)
+		c.Assert(res, checker.DeepEquals, dat)
+	}
+}
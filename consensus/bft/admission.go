@@ -0,0 +1,168 @@
+package bft
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	maxFutureHeights    = 10   // Heights ahead of the current one whose messages are buffered
+	maxFutureRounds     = 32   // Rounds ahead of the active one a message may be for
+	maxFutureMessages   = 1024 // Messages buffered for future heights
+	maxPeerMisbehaviour = 16   // Invalid messages after which a peer is disconnected
+)
+
+var (
+	errInvalidSignature = errors.New("invalid message signature")
+	errNotValidator     = errors.New("message not signed by a validator")
+	errStaleMessage     = errors.New("message for a past height")
+	errFarFutureMessage = errors.New("message too far in the future")
+	errFutureMessage    = errors.New("message for a future height buffered")
+)
+
+// consensusMsg is a signed consensus message subject to admission control.
+type consensusMsg interface {
+	From(d *btypes.Domain) (common.Address, error)
+	Hash() common.Hash
+}
+
+// futureMessages is a bounded buffer of validator-signed messages for heights
+// the local chain has not reached yet.
+type futureMessages struct {
+	msgs   map[uint64][]consensusMsg // Buffered messages by height
+	known  map[common.Hash]struct{}  // Hashes of the buffered messages
+	height uint64                    // Height up to which messages were released
+	mu     sync.Mutex
+}
+
+func newFutureMessages() *futureMessages {
+	return &futureMessages{
+		msgs:  make(map[uint64][]consensusMsg),
+		known: make(map[common.Hash]struct{}),
+	}
+}
+
+// add buffers a message of the given height. If the buffer is full, messages
+// of the farthest height are evicted first; the message itself is dropped if
+// it is the farthest.
+func (f *futureMessages) add(height uint64, msg consensusMsg) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	hash := msg.Hash()
+	if _, ok := f.known[hash]; ok {
+		return
+	}
+	if len(f.known) >= maxFutureMessages {
+		var farthest uint64
+		for h := range f.msgs {
+			if h > farthest {
+				farthest = h
+			}
+		}
+		if height >= farthest {
+			return
+		}
+		evict := f.msgs[farthest]
+		delete(f.known, evict[len(evict)-1].Hash())
+		if len(evict) == 1 {
+			delete(f.msgs, farthest)
+		} else {
+			f.msgs[farthest] = evict[:len(evict)-1]
+		}
+	}
+	f.msgs[height] = append(f.msgs[height], msg)
+	f.known[hash] = struct{}{}
+}
+
+// release removes and returns the messages of all heights up to the given one,
+// or nil if they were already released.
+func (f *futureMessages) release(height uint64) []consensusMsg {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if height <= f.height {
+		return nil
+	}
+	f.height = height
+
+	var released []consensusMsg
+	for h, msgs := range f.msgs {
+		if h > height {
+			continue
+		}
+		for _, msg := range msgs {
+			delete(f.known, msg.Hash())
+		}
+		released = append(released, msgs...)
+		delete(f.msgs, h)
+	}
+	return released
+}
+
+// admit checks a consensus message for the given height and round before any
+// state is allocated for it: the signature must be valid, the signer must be a
+// validator and the height must be close to the current one. Messages of near
+// future heights are buffered and replayed once the chain reaches them.
+func (cm *ConsensusManager) admit(msg consensusMsg, height, round uint64, peer *peer) (common.Address, error) {
+	cm.replayFuture()
+
+	addr, err := msg.From(cm.domain)
+	if err != nil {
+		cm.penalize(peer, errInvalidSignature)
+		return common.Address{}, errInvalidSignature
+	}
+	if !cm.contract.isValidators(addr) {
+		cm.penalize(peer, errNotValidator)
+		return common.Address{}, errNotValidator
+	}
+	current := cm.Height()
+	switch {
+	case height+1 < current:
+		return addr, errStaleMessage
+	case height > current+maxFutureHeights:
+		return addr, errFarFutureMessage
+	case height > current:
+		cm.future.add(height, msg)
+		return addr, errFutureMessage
+	}
+	cm.getHeightMu.Lock()
+	active := cm.getHeightManager(height).Round()
+	cm.getHeightMu.Unlock()
+	if round > active+maxFutureRounds {
+		return addr, errFarFutureMessage
+	}
+	return addr, nil
+}
+
+// replayFuture feeds the buffered messages that are no longer in the future
+// back into consensus.
+func (cm *ConsensusManager) replayFuture() {
+	for _, msg := range cm.future.release(cm.Height()) {
+		switch m := msg.(type) {
+		case *btypes.Vote:
+			cm.AddVote(m, nil)
+		case *btypes.PrecommitVote:
+			cm.AddPrecommitVote(m, nil)
+		case btypes.Proposal:
+			cm.AddProposal(m, nil)
+		}
+	}
+}
+
+// penalize records an invalid message received from a peer, disconnecting the
+// peer once it sent too many.
+func (cm *ConsensusManager) penalize(p *peer, reason error) {
+	if p == nil {
+		return
+	}
+	if n := atomic.AddInt32(&p.misbehaviour, 1); n >= maxPeerMisbehaviour {
+		log.Debug("Dropping misbehaving peer", "peer", p.id, "invalid", n, "reason", reason)
+		cm.pm.removePeer(p.id)
+	}
+}
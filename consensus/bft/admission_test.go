@@ -0,0 +1,42 @@
+package bft
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+)
+
+func TestFutureMessagesBounded(t *testing.T) {
+	future := newFutureMessages()
+
+	// Fill the buffer with messages for a far height
+	for i := 0; i < maxFutureMessages; i++ {
+		future.add(5, btypes.NewVote(5, uint64(i), common.Hash{}, 1))
+	}
+	// Messages for the same or farther heights are dropped when full
+	future.add(6, btypes.NewVote(6, 0, common.Hash{}, 1))
+	if len(future.known) != maxFutureMessages || len(future.msgs[6]) != 0 {
+		t.Fatalf("farther message buffered: have %d messages, %d at height 6", len(future.known), len(future.msgs[6]))
+	}
+	// Nearer messages evict the farthest ones
+	future.add(2, btypes.NewVote(2, 0, common.Hash{}, 1))
+	if len(future.known) != maxFutureMessages || len(future.msgs[2]) != 1 || len(future.msgs[5]) != maxFutureMessages-1 {
+		t.Fatalf("nearer message not buffered: have %d messages, %d at height 2", len(future.known), len(future.msgs[2]))
+	}
+	// Duplicates are ignored
+	future.add(2, btypes.NewVote(2, 0, common.Hash{}, 1))
+	if len(future.msgs[2]) != 1 {
+		t.Fatalf("duplicate buffered: have %d messages at height 2", len(future.msgs[2]))
+	}
+	// Releasing returns the reached heights only, and only once
+	if released := future.release(3); len(released) != 1 {
+		t.Fatalf("released messages mismatch: have %d, want 1", len(released))
+	}
+	if released := future.release(3); released != nil {
+		t.Fatalf("messages released twice: have %d", len(released))
+	}
+	if len(future.known) != maxFutureMessages-1 {
+		t.Fatalf("buffer size mismatch after release: have %d, want %d", len(future.known), maxFutureMessages-1)
+	}
+}
@@ -15,3 +15,8 @@ type API struct {
 func (api *API) GetEtherbase() common.Address {
 	return api.bft.signer
 }
+
+// Status retrieves the current height, round, proposer and validator liveness.
+func (api *API) Status() *Status {
+	return api.bft.Status()
+}
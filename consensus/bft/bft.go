@@ -8,6 +8,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -18,6 +19,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -37,6 +39,10 @@ type BFT struct {
 	pm *ProtocolManager
 
 	signer common.Address // Ethereum address of the signing key
+	domain *btypes.Domain // Signature domain of the consensus messages
+
+	bftDb      ethdb.Database   // Commit certificate store used without a protocol manager
+	validators []common.Address // Validator set used without a protocol manager
 }
 
 func New(config *params.ChainConfig, db ethdb.Database) *BFT {
@@ -49,16 +55,46 @@ func New(config *params.ChainConfig, db ethdb.Database) *BFT {
 	return bft
 }
 
-func (b *BFT) SetupProtocolManager(chainConfig *params.ChainConfig, networkId uint64, mux *event.TypeMux, txpool *core.TxPool, blockchain *core.BlockChain, chainDb ethdb.Database, bftDb ethdb.Database, vmConfig vm.Config, validators []common.Address, privateKeyHex string, etherbase common.Address, allowEmpty bool, byzantineMode int) error {
-	var err error
-	privkey, _ := crypto.HexToECDSA(privateKeyHex)
-	// addr := crypto.ToECDSAPub(crypto.FromECDSA(privkey))
-	b.signer = crypto.PubkeyToAddress(privkey.PublicKey)
+// NewVerifier creates a BFT engine that does not take part in consensus but
+// verifies blocks against the commit certificates stored in bftDb, as needed
+// to import and export chains offline.
+func NewVerifier(config *params.ChainConfig, genesis common.Hash, bftDb ethdb.Database, validators []common.Address) *BFT {
+	b := New(config, bftDb)
+	b.bftDb, b.validators = bftDb, validators
+	if checkpoint := GetCheckpointValidators(bftDb); len(checkpoint) > 0 {
+		b.validators = checkpoint
+	}
+	b.domain = NewDomain(config, genesis)
+	return b
+}
+
+func (b *BFT) SetupProtocolManager(chainConfig *params.ChainConfig, networkId uint64, mux *event.TypeMux, txpool *core.TxPool, blockchain *core.BlockChain, chainDb ethdb.Database, bftDb ethdb.Database, vmConfig vm.Config, validators []common.Address, signer ConsensusSigner, allowEmpty bool, byzantineMode int) error {
+	if signer == nil {
+		// Nodes without a validator key only follow the consensus of others
+		log.Info("No validator key configured, running as observer")
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return err
+		}
+		signer = NewKeySigner(key)
+	}
+	b.signer = signer.Address()
 	b.blockchain = blockchain
 	b.txpool = txpool
-	if b.pm, err = NewProtocolManager(chainConfig, networkId, mux, txpool, blockchain, chainDb, bftDb, vmConfig, validators, privateKeyHex, etherbase, allowEmpty, byzantineMode); err != nil {
+
+	var err error
+	if b.pm, err = NewProtocolManager(chainConfig, networkId, mux, txpool, blockchain, chainDb, bftDb, vmConfig, validators, signer, allowEmpty, byzantineMode); err != nil {
 		return err
 	}
+	b.domain = b.pm.domain
+	// A chain started from a checkpoint is sealed by the validators of the checkpoint
+	if b.validators == nil {
+		b.validators = GetCheckpointValidators(bftDb)
+	}
+	// A validator set handed over from another engine overrides the configured one
+	if b.validators != nil {
+		b.pm.setValidators(b.validators)
+	}
 	return nil
 }
 
@@ -66,6 +102,25 @@ func (b *BFT) Start() {
 	b.pm.Start()
 }
 
+// SetValidators replaces the validator set of the engine. It is meant to be
+// called once, when a chain switches to BFT or starts from a checkpoint, before
+// consensus on the following block starts.
+func (b *BFT) SetValidators(validators []common.Address) {
+	b.validators = validators
+	if b.pm != nil {
+		b.pm.setValidators(validators)
+	}
+}
+
+// Status returns a snapshot of the consensus state, or nil if the consensus
+// protocol has not been set up yet.
+func (b *BFT) Status() *Status {
+	if b.pm == nil {
+		return nil
+	}
+	return b.pm.consensusManager.Status()
+}
+
 func (b *BFT) Author(header *types.Header) (common.Address, error) {
 	return header.Coinbase, nil
 }
@@ -80,16 +135,25 @@ func (b *BFT) VerifyHeader(chain consensus.ChainReader, header *types.Header, se
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
-	return b.verifyHeader(chain, header)
+	return b.verifyHeader(chain, header, nil)
 }
 
 func (b *BFT) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	return b.verifyHeaders(chain, headers, nil)
+}
+
+// verifyHeaders verifies a batch of headers whose ancestors not yet in the chain,
+// if any, are given.
+func (b *BFT) verifyHeaders(chain consensus.ChainReader, headers []*types.Header, ancestors []*types.Header) (chan<- struct{}, <-chan error) {
 	abort := make(chan struct{})
 	results := make(chan error, len(headers))
 
 	go func() {
+		parents := append([]*types.Header{}, ancestors...)
 		for _, header := range headers {
-			err := b.verifyHeader(chain, header)
+			err := b.verifyHeader(chain, header, parents)
+			parents = append(parents, header)
+
 			select {
 			case <-abort:
 				return
@@ -100,15 +164,52 @@ func (b *BFT) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header
 	return abort, results
 }
 
-func (b *BFT) verifyHeader(chain consensus.ChainReader, header *types.Header) error {
-	if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
+// verifyHeader checks whether a header conforms to the consensus rules. The
+// parents are the ancestors of the header not yet in the chain, if any.
+func (b *BFT) verifyHeader(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+	config := chain.Config().BFT
+
+	now := time.Now().Unix()
+	if config != nil {
+		now += int64(config.MaxTimeSkew)
+	}
+	if header.Time.Cmp(big.NewInt(now)) > 0 {
 		return consensus.ErrFutureBlock
 	}
 	number := header.Number.Uint64()
-
-	if number > 0 {
-		if header.Difficulty == nil || header.Difficulty.Cmp(fixDifficulty) != 0 {
-			return errInvalidDifficulty
+	if number == 0 {
+		return b.verifySeal(chain, header)
+	}
+	if header.Difficulty == nil || header.Difficulty.Cmp(fixDifficulty) != 0 {
+		return errInvalidDifficulty
+	}
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+	if err := verifyTime(config, header, parent); err != nil {
+		return err
+	}
+	// Fees are distributed among the signers of the parent certificate, who
+	// also attest the time of the block
+	if carriesCertificate(chain.Config(), header) {
+		pls, err := parentCertificate(header)
+		if err != nil {
+			return errMissingCertificate
+		}
+		_, validators := b.certificates()
+		if err := VerifyCommit(pls, header.ParentHash, number-1, validators, b.domain); err != nil {
+			return err
+		}
+		if config.IsMedianTime(header.Number) {
+			if median, ok := medianTime(pls, header.ParentHash); ok && header.Time.Uint64() != median {
+				return errInvalidMedianTime
+			}
 		}
 	}
 	return b.verifySeal(chain, header)
@@ -126,6 +227,10 @@ func (b *BFT) VerifySeal(chain consensus.ChainReader, header *types.Header) erro
 }
 
 func (b *BFT) verifySeal(chain consensus.ChainReader, header *types.Header) error {
+	if b.pm == nil {
+		db, validators := b.certificates()
+		return VerifyCommit(GetPrecommitLockset(db, header.Hash()), header.Hash(), header.Number.Uint64(), validators, b.domain)
+	}
 	if err := b.pm.consensusManager.verifyVotes(header); err != nil {
 		log.Error("verifySeal failed", "err", err)
 		return err
@@ -134,14 +239,63 @@ func (b *BFT) verifySeal(chain consensus.ChainReader, header *types.Header) erro
 	return nil
 }
 
+// Ready implements consensus.SealPolicy, holding back blocks that the block
+// policy of the network does not deem worth proposing yet.
+func (b *BFT) Ready(chain consensus.ChainReader, block *types.Block) (bool, <-chan struct{}) {
+	if b.pm == nil {
+		return true, nil
+	}
+	return b.pm.consensusManager.readyToSeal(block)
+}
+
 func (b *BFT) Prepare(chain consensus.ChainReader, header *types.Header) error {
 	header.Difficulty = fixDifficulty
 	header.Coinbase = b.signer
+
+	// Respect the block period, sealing waits for the timestamp to pass
+	number := header.Number.Uint64()
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	config := chain.Config().BFT
+	if config != nil && config.BlockPeriod > 0 {
+		earliest := new(big.Int).Add(parent.Time, new(big.Int).SetUint64(config.BlockPeriod))
+		if header.Time.Cmp(earliest) < 0 {
+			header.Time = earliest
+		}
+	}
+	if carriesCertificate(chain.Config(), header) {
+		db, _ := b.certificates()
+		pls := GetPrecommitLockset(db, header.ParentHash)
+		if pls == nil {
+			return errMissingCertificate
+		}
+		extra, err := rlp.EncodeToBytes(pls)
+		if err != nil {
+			return err
+		}
+		header.Extra = extra
+
+		if config.IsMedianTime(header.Number) {
+			if median, ok := medianTime(pls, header.ParentHash); ok {
+				header.Time = new(big.Int).SetUint64(median)
+			}
+		}
+	}
 	return nil
 }
 
 func (b *BFT) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
-
+	if config := chain.Config().BFT; config != nil && config.IsRewarding(header.Number) {
+		var signers []common.Address
+		if carriesCertificate(chain.Config(), header) {
+			if pls, err := parentCertificate(header); err == nil {
+				signers = certificateSigners(pls, b.domain)
+			}
+		}
+		accumulateRewards(config, state, header, txs, receipts, signers)
+	}
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
 
@@ -149,6 +303,18 @@ func (b *BFT) Finalize(chain consensus.ChainReader, header *types.Header, state
 }
 
 func (b *BFT) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
+	// Wait until the block is no longer in the future for the other validators
+	delay := time.Unix(block.Time().Int64(), 0).Sub(time.Now())
+	if config := chain.Config().BFT; config != nil {
+		delay -= time.Duration(config.MaxTimeSkew) * time.Second
+	}
+	if delay > 0 {
+		select {
+		case <-stop:
+			return nil, nil
+		case <-time.After(delay):
+		}
+	}
 	// start voting mechanism
 	log.Info("Sealing", "block n", block.Number(), "txs", len(block.Transactions()))
 	abort := make(chan struct{})
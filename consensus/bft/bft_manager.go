@@ -1,11 +1,10 @@
 package bft
 
 import (
-	"bytes"
-	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"sync"
 	"time"
 
@@ -13,11 +12,10 @@ import (
 	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 var (
@@ -56,8 +54,8 @@ func (cc *ConsensusContract) isValidators(v common.Address) bool {
 	return containsAddress(cc.validators, v)
 }
 
-func (cc *ConsensusContract) isProposer(p btypes.Proposal) bool {
-	if addr, err := p.From(); err != nil {
+func (cc *ConsensusContract) isProposer(p btypes.Proposal, d *btypes.Domain) bool {
+	if addr, err := p.From(d); err != nil {
 		log.Error("invalid sender %v", err)
 		return false
 	} else {
@@ -93,14 +91,18 @@ type StrategyConfig struct {
 type ConsensusManager struct {
 	pm                      *ProtocolManager
 	isAllowEmptyBlocks      bool
+	policy                  *blockPolicy
+	future                  *futureMessages
 	numInitialBlocks        uint64
 	roundTimeout            uint64
 	roundTimeoutFactor      float64
+	precommitTimeout        uint64
 	transactionTimeout      float64
 	chain                   *core.BlockChain
 	coinbase                common.Address
 	readyValidators         map[common.Address]struct{}
-	privkey                 *ecdsa.PrivateKey
+	signer                  ConsensusSigner
+	domain                  *btypes.Domain // signature domain of the consensus messages
 	contract                *ConsensusContract
 	trackedProtocolFailures []string
 	heights                 map[uint64]*HeightManager
@@ -114,6 +116,13 @@ type ConsensusManager struct {
 	currentBlock *types.Block
 	found        chan *types.Block
 
+	// consensus statistics
+	lastSeen      map[common.Address]time.Time // last time a message was received from each validator
+	startHeight   uint64                       // height the commit latency is measured for
+	startTime     time.Time                    // time the consensus on startHeight started
+	commitLatency time.Duration                // time it took to commit the last block
+	participants  *participationTracker        // precommit signers of the recent blocks
+
 	mu          sync.Mutex
 	currentMu   sync.Mutex
 	uncleMu     sync.Mutex
@@ -121,28 +130,33 @@ type ConsensusManager struct {
 	getHeightMu sync.RWMutex
 
 	processMu sync.Mutex
+	statsMu   sync.RWMutex
 
 	Enable bool
 	Config StrategyConfig
 }
 
-func NewConsensusManager(manager *ProtocolManager, chain *core.BlockChain, db ethdb.Database, cc *ConsensusContract, privkeyhex string) *ConsensusManager {
-
-	privkey, _ := crypto.HexToECDSA(privkeyhex)
+func NewConsensusManager(manager *ProtocolManager, chain *core.BlockChain, db ethdb.Database, cc *ConsensusContract, signer ConsensusSigner, domain *btypes.Domain) *ConsensusManager {
 	cm := &ConsensusManager{
 		pm:                 manager,
 		isAllowEmptyBlocks: false,
+		policy:             newBlockPolicy(),
+		future:             newFutureMessages(),
 		numInitialBlocks:   10,
 		roundTimeout:       3,
 		roundTimeoutFactor: 1.5,
+		precommitTimeout:   2,
 		transactionTimeout: 0.5,
 		hdcDb:              db,
 		chain:              chain,
-		privkey:            privkey,
+		signer:             signer,
+		domain:             domain,
 		readyValidators:    make(map[common.Address]struct{}),
 		heights:            make(map[uint64]*HeightManager),
 		readyNonce:         0,
 		blockCandidates:    make(map[common.Hash]*btypes.BlockProposal),
+		lastSeen:           make(map[common.Address]time.Time),
+		participants:       newParticipationTracker(participationWindow),
 		contract:           cc,
 		coinbase:           cc.coinbase,
 		Enable:             true,
@@ -189,6 +203,29 @@ func (cm *ConsensusManager) activeRound() *RoundManager {
 	return hm.getRoundManager(hm.Round())
 }
 
+// setConfig overrides the default timeouts and empty block policy with the
+// ones configured in the genesis.
+func (cm *ConsensusManager) setConfig(config *params.BFTConfig) {
+	if config.RoundTimeout != 0 {
+		cm.roundTimeout = config.RoundTimeout
+	}
+	if config.RoundTimeoutFactor != 0 {
+		cm.roundTimeoutFactor = config.RoundTimeoutFactor
+	}
+	if config.PrecommitTimeout != 0 {
+		cm.precommitTimeout = config.PrecommitTimeout
+	}
+	if config.AllowEmpty {
+		cm.isAllowEmptyBlocks = true
+	}
+	cm.policy.emptyInterval = time.Duration(config.EmptyBlockPeriod) * time.Second
+	if config.MinBlockTxs != 0 {
+		cm.policy.minTxs = int(config.MinBlockTxs)
+	}
+	cm.policy.minGas = new(big.Int).SetUint64(config.MinBlockGas)
+	cm.policy.maxWait = time.Duration(config.MaxTxWait) * time.Second
+}
+
 func (cm *ConsensusManager) enable() {
 	cm.Enable = true
 }
@@ -222,8 +259,9 @@ func (cm *ConsensusManager) initializeLocksets() {
 	log.Debug("initialize locksets")
 	v := btypes.NewPrecommitVote(0, 0, cm.chain.Genesis().Hash(), 1) // voteBlock
 
-	cm.Sign(v)
-	cm.AddPrecommitVote(v, nil)
+	if err := cm.Sign(v); err == nil {
+		cm.AddPrecommitVote(v, nil)
+	}
 	// add initial lockset
 	log.Debug("inintial lockset")
 	lastCommittingLockset := cm.loadLastCommittingLockset()
@@ -241,11 +279,7 @@ func (cm *ConsensusManager) initializeLocksets() {
 
 // persist proposals and last committing lockset
 func (cm *ConsensusManager) storeLastCommittingLockset(ls *btypes.PrecommitLockSet) error {
-	bytes, err := rlp.EncodeToBytes(ls)
-	if err != nil {
-		return err
-	}
-	if err := cm.hdcDb.Put([]byte("last_committing_lockset"), bytes); err != nil {
+	if err := WriteLastCommittingLockset(cm.hdcDb, ls); err != nil {
 		log.Error("failed to store last committing lockset into database", "err", err)
 		return err
 	}
@@ -253,26 +287,11 @@ func (cm *ConsensusManager) storeLastCommittingLockset(ls *btypes.PrecommitLockS
 }
 
 func (cm *ConsensusManager) loadLastCommittingLockset() *btypes.PrecommitLockSet {
-	key := fmt.Sprintf("last_committing_lockset")
-	data, _ := cm.hdcDb.Get([]byte(key))
-	if len(data) == 0 {
-		return nil
-	}
-	var lockset *btypes.PrecommitLockSet
-	if err := rlp.Decode(bytes.NewReader(data), &lockset); err != nil {
-		log.Error("invalid last_committing_lockset ", "err:", err)
-		return nil
-	}
-	return lockset
+	return GetLastCommittingLockset(cm.hdcDb)
 }
 
 func (cm *ConsensusManager) storePrecommitLockset(blockhash common.Hash, pls *btypes.PrecommitLockSet) error {
-	bytes, err := rlp.EncodeToBytes(pls)
-	if err != nil {
-		panic(err)
-	}
-	key := fmt.Sprintf("precommitLockset:%s", blockhash)
-	if err := cm.hdcDb.Put([]byte(key), bytes); err != nil {
+	if err := WritePrecommitLockset(cm.hdcDb, blockhash, pls); err != nil {
 		log.Error("failed to store proposal into database", "err", err)
 		return err
 	}
@@ -280,17 +299,7 @@ func (cm *ConsensusManager) storePrecommitLockset(blockhash common.Hash, pls *bt
 }
 
 func (cm *ConsensusManager) loadPrecommitLockset(blockhash common.Hash) *btypes.PrecommitLockSet {
-	key := fmt.Sprintf("precommitLockset:%s", blockhash)
-	data, _ := cm.hdcDb.Get([]byte(key))
-	if len(data) == 0 {
-		return nil
-	}
-	var pls *btypes.PrecommitLockSet
-	if err := rlp.Decode(bytes.NewReader(data), &pls); err != nil {
-		log.Error("invalid precommitLockset RLP for hash", "blockhash", blockhash, "err", err)
-		return nil
-	}
-	return pls
+	return GetPrecommitLockset(cm.hdcDb, blockhash)
 }
 
 func (cm *ConsensusManager) getPrecommitLocksetByHeight(height uint64) *btypes.PrecommitLockSet {
@@ -318,16 +327,32 @@ func (cm *ConsensusManager) setupTimeout(h uint64) {
 }
 
 func (cm *ConsensusManager) isWaitingForProposal() bool {
-	return cm.isAllowEmptyBlocks || cm.hasPendingTransactions() || cm.Height() <= cm.numInitialBlocks
+	block := cm.currentBlock
+	if block == nil {
+		return cm.isAllowEmptyBlocks || cm.Height() <= cm.numInitialBlocks
+	}
+	ready, _ := cm.shouldSeal(block)
+	return ready
 }
 
-func (cm *ConsensusManager) hasPendingTransactions() bool {
-	if txs, err := cm.pm.txpool.Pending(); err != nil {
-		log.Debug("error occur")
-		panic(err)
-	} else {
-		return len(txs) > 0
+// shouldSeal reports whether the block is worth proposing under the block
+// policy. Otherwise it returns the time at which that changes without new
+// events, or the zero time if it doesn't.
+func (cm *ConsensusManager) shouldSeal(block *types.Block) (bool, time.Time) {
+	if cm.isAllowEmptyBlocks || block.NumberU64() <= cm.numInitialBlocks {
+		return true, time.Time{}
+	}
+	return cm.policy.ready(len(block.Transactions()), block.GasUsed(), time.Now())
+}
+
+// readyToSeal reports whether the block is worth proposing under the block
+// policy. Otherwise it returns a channel closed once that may have changed.
+func (cm *ConsensusManager) readyToSeal(block *types.Block) (bool, <-chan struct{}) {
+	ready, deadline := cm.shouldSeal(block)
+	if ready {
+		return true, nil
 	}
+	return false, cm.policy.wait(deadline)
 }
 
 func (cm *ConsensusManager) Process(block *types.Block, abort chan struct{}, found chan *types.Block) {
@@ -345,6 +370,12 @@ func (cm *ConsensusManager) Process(block *types.Block, abort chan struct{}, fou
 	cm.found = found
 	cm.enable()
 
+	cm.statsMu.Lock()
+	if cm.startHeight != block.NumberU64() {
+		cm.startHeight, cm.startTime = block.NumberU64(), time.Now()
+	}
+	cm.statsMu.Unlock()
+
 	if cm.Height() != block.Number().Uint64() || !cm.Enable {
 		return
 	}
@@ -393,11 +424,14 @@ func (cm *ConsensusManager) commitPrecommitLockset(hash common.Hash, pls *btypes
 			if proposal.Blockhash() == hash {
 				if cm.found != nil {
 					log.Debug("cm.found is not nil")
+					// Store the certificate before the block is handed out, the
+					// next block carries it once rewards are enabled
+					log.Debug("store precommit lockset")
+					cm.storePrecommitLockset(hash, pls)
 					select {
 					case cm.found <- proposal.Block:
-						log.Debug("store precommit lockset")
-						cm.storePrecommitLockset(hash, pls)
 						cm.disable()
+						cm.markCommitted(proposal.Height)
 					default:
 						log.Debug("no chan")
 					}
@@ -458,26 +492,78 @@ func (cm *ConsensusManager) cleanup() {
 	cm.getHeightMu.Unlock()
 }
 
-func (cm *ConsensusManager) Sign(s interface{}) {
-	log.Debug("CM Sign")
+// attestTime stamps a precommit for a block with the local time, but no earlier
+// than the block period after the block's own timestamp, once the next block
+// takes the median of the attested times.
+func (cm *ConsensusManager) attestTime(vote *btypes.PrecommitVote) {
+	config := cm.chain.Config().BFT
+	if config == nil || !config.IsMedianTime(new(big.Int).SetUint64(vote.Height+1)) {
+		return
+	}
+	now := uint64(time.Now().Unix())
+
+	cm.writeMapMu.RLock()
+	if proposal, ok := cm.blockCandidates[vote.Blockhash]; ok {
+		now = attestedTime(config, now, proposal.Block.Time().Uint64())
+	}
+	cm.writeMapMu.RUnlock()
+
+	vote.SetTime(now)
+}
+
+// Sign signs a consensus message with the validator's signer.
+func (cm *ConsensusManager) Sign(s interface{}) error {
+	sign := func(kind string, msg interface{}) ([]byte, error) {
+		req, err := NewSignRequest(kind, msg, cm.domain)
+		if err != nil {
+			return nil, err
+		}
+		return cm.signer.SignConsensus(req)
+	}
+	var (
+		sig []byte
+		err error
+	)
 	switch t := s.(type) {
 	case *btypes.BlockProposal:
-		t.Sign(cm.privkey)
+		if sig, err = sign(SignProposal, t); err == nil {
+			_, err = t.WithSignature(sig)
+		}
+	case *btypes.VotingInstruction:
+		if sig, err = sign(SignVotingInstruction, t); err == nil {
+			_, err = t.WithSignature(sig)
+		}
+	case *btypes.ProposalHeader:
+		if sig, err = sign(SignProposalHeader, t); err == nil {
+			_, err = t.WithSignature(sig)
+		}
 	case *btypes.Vote:
-		t.Sign(cm.privkey)
+		if sig, err = sign(SignVote, t); err == nil {
+			_, err = t.WithSignature(sig)
+		}
 	case *btypes.PrecommitVote:
-		t.Sign(cm.privkey)
+		if sig, err = sign(SignPrecommit, t); err == nil {
+			_, err = t.WithSignature(sig)
+		}
 	case *btypes.LockSet:
-		t.Sign(cm.privkey)
+		if sig, err = sign(SignLockset, t); err == nil {
+			_, err = t.WithSignature(sig)
+		}
 	case *btypes.PrecommitLockSet:
-		t.Sign(cm.privkey)
-	case *btypes.VotingInstruction:
-		t.Sign(cm.privkey)
+		if sig, err = sign(SignPrecommitLockset, t); err == nil {
+			_, err = t.WithSignature(sig)
+		}
 	case *btypes.Ready:
-		t.Sign(cm.privkey)
+		if sig, err = sign(SignReady, t); err == nil {
+			_, err = t.WithSignature(sig)
+		}
 	default:
-		log.Debug("consensus mangaer sign error")
+		err = fmt.Errorf("unknown consensus message %T", s)
 	}
+	if err != nil {
+		log.Error("Failed to sign consensus message", "err", err)
+	}
+	return err
 }
 
 func (cm *ConsensusManager) setProposalLock(block *types.Block) {
@@ -500,15 +586,17 @@ func (cm *ConsensusManager) SendReady(force bool) {
 	}
 	ls := cm.activeRound().lockset
 	r := btypes.NewReady(cm.readyNonce, ls)
-	cm.Sign(r)
-	r.From()
+	if err := cm.Sign(r); err != nil {
+		return
+	}
+	r.From(cm.domain)
 	cm.broadcast(r)
 	cm.readyNonce += 1
 }
 
 func (cm *ConsensusManager) AddReady(ready *btypes.Ready) {
 	cc := cm.contract
-	addr, err := ready.From()
+	addr, err := ready.From(cm.domain)
 	if err != nil {
 		log.Error("AddReady err ", "err", err)
 		return
@@ -518,6 +606,7 @@ func (cm *ConsensusManager) AddReady(ready *btypes.Ready) {
 		log.Debug("receive ready from invalid sender")
 		return
 	}
+	cm.markSeen(addr)
 	if _, ok := cm.readyValidators[addr]; !ok {
 		cm.writeMapMu.Lock()
 		cm.readyValidators[addr] = struct{}{}
@@ -530,12 +619,17 @@ func (cm *ConsensusManager) AddVote(v *btypes.Vote, peer *peer) bool {
 		log.Debug("cm addvote error")
 		return false
 	}
-	addr, _ := v.From()
+	addr, err := cm.admit(v, v.Height, v.Round, peer)
+	if err != nil {
+		log.Debug("vote not admitted", "height", v.Height, "round", v.Round, "err", err)
+		return false
+	}
 	if _, ok := cm.readyValidators[addr]; !ok {
 		cm.writeMapMu.Lock()
 		cm.readyValidators[addr] = struct{}{}
 		cm.writeMapMu.Unlock()
 	}
+	cm.markSeen(addr)
 	cm.getHeightMu.Lock()
 	h := cm.getHeightManager(v.Height)
 	success := h.addVote(v, true)
@@ -550,7 +644,12 @@ func (cm *ConsensusManager) AddPrecommitVote(v *btypes.PrecommitVote, peer *peer
 		log.Debug("cm AddPrecommitVote fail")
 		return false
 	}
-	// log.Debug("addVote", v.From())
+	addr, err := cm.admit(v, v.Height, v.Round, peer)
+	if err != nil {
+		log.Debug("precommit vote not admitted", "height", v.Height, "round", v.Round, "err", err)
+		return false
+	}
+	cm.markSeen(addr)
 	cm.getHeightMu.Lock()
 	h := cm.getHeightManager(v.Height)
 	success := h.addPrecommitVote(v, true)
@@ -567,15 +666,20 @@ func (cm *ConsensusManager) AddProposal(p btypes.Proposal, peer *peer) bool {
 		log.Debug("proposal from past")
 		return false
 	}
-	addr, err := p.From()
+	msg, ok := p.(consensusMsg)
+	if !ok {
+		return false
+	}
+	addr, err := cm.admit(msg, p.GetHeight(), p.GetRound(), peer)
 	if err != nil {
-		log.Debug("proposal sender error ", "err", err)
+		log.Debug("proposal not admitted", "height", p.GetHeight(), "round", p.GetRound(), "err", err)
 		return false
 	}
-	if !cm.contract.isValidators(addr) || !cm.contract.isProposer(p) {
-		log.Debug("proposal sender invalid", "validator?", cm.contract.isValidators(addr), "proposer?", cm.contract.isProposer(p))
+	if !cm.contract.isProposer(p, cm.domain) {
+		log.Debug("proposal sender invalid", "proposer?", false)
 		return false
 	}
+	cm.markSeen(addr)
 	if _, ok := cm.readyValidators[addr]; !ok {
 		cm.writeMapMu.Lock()
 		cm.readyValidators[addr] = struct{}{}
@@ -874,7 +978,7 @@ func (hm *HeightManager) HasQuorum() (bool, common.Hash) {
 }
 
 func (hm *HeightManager) addVote(v *btypes.Vote, process bool) bool {
-	addr, _ := v.From()
+	addr, _ := v.From(hm.cm.domain)
 	if !hm.cm.contract.isValidators(addr) {
 		log.Debug("non-validator vote")
 		return false
@@ -885,7 +989,7 @@ func (hm *HeightManager) addVote(v *btypes.Vote, process bool) bool {
 }
 
 func (hm *HeightManager) addPrecommitVote(v *btypes.PrecommitVote, process bool) bool {
-	addr, _ := v.From()
+	addr, _ := v.From(hm.cm.domain)
 	if !hm.cm.contract.isValidators(addr) {
 		log.Debug("non-validator vote")
 		return false
@@ -958,8 +1062,8 @@ func (rm *RoundManager) setTimeoutPrecommit() {
 		return
 	}
 	now := rm.cm.Now()
-	timeout := 2
-	timeoutFactor := 1.5
+	timeout := rm.cm.precommitTimeout
+	timeoutFactor := rm.cm.roundTimeoutFactor
 	delay := float64(timeout) * math.Pow(timeoutFactor, float64(rm.round))
 	rm.timeoutPrecommit = float64(now) + delay
 	log.Debug("RM get timeoutPrecommit", "height", rm.height, "round", rm.round)
@@ -981,7 +1085,7 @@ func (rm *RoundManager) addVote(vote *btypes.Vote, force_replace bool, process b
 
 func (rm *RoundManager) addPrecommitVote(vote *btypes.PrecommitVote, force_replace bool, process bool) bool {
 	if !rm.precommitLockset.Contain(vote) {
-		addr, _ := vote.From()
+		addr, _ := vote.From(rm.cm.domain)
 		log.Debug("addPrecommitVote to ", "h", vote.Height, "r", vote.Round, "from", addr)
 		err := rm.precommitLockset.Add(vote, force_replace)
 		if err != nil {
@@ -1044,15 +1148,15 @@ func (rm *RoundManager) process() {
 			blockhash := rm.proposal.Blockhash()
 			vote := btypes.NewVote(rm.height, rm.round, blockhash, 1)
 			precommitVote := btypes.NewPrecommitVote(rm.height, rm.round, blockhash, 1)
+			rm.cm.attestTime(precommitVote)
 
-			rm.cm.Sign(vote)
-			rm.cm.Sign(precommitVote)
-
-			rm.voteLock = vote
-			rm.precommitVoteLock = precommitVote
+			if rm.cm.Sign(vote) == nil && rm.cm.Sign(precommitVote) == nil {
+				rm.voteLock = vote
+				rm.precommitVoteLock = precommitVote
 
-			rm.addVote(vote, false, true)
-			rm.addPrecommitVote(precommitVote, false, true)
+				rm.addVote(vote, false, true)
+				rm.addPrecommitVote(precommitVote, false, true)
+			}
 		}
 	}
 	if rm.voteLock != nil {
@@ -1095,13 +1199,13 @@ func (rm *RoundManager) propose() btypes.Proposal {
 	}
 	log.Debug("I am a proposer in ", "height", rm.height, "round", rm.round)
 	if rm.proposal != nil {
-		addr, err := rm.proposal.From()
+		addr, err := rm.proposal.From(rm.cm.domain)
 		if err != nil {
 			log.Error("error occur %v", err)
 			return nil
 		}
 		if addr != rm.cm.coinbase {
-			addr, _ := rm.proposal.From()
+			addr, _ := rm.proposal.From(rm.cm.domain)
 			log.Error(addr.Hex(), rm.cm.coinbase.Hex())
 			return nil
 		}
@@ -1131,8 +1235,9 @@ func (rm *RoundManager) propose() btypes.Proposal {
 			if bp2, err := btypes.NewBlockProposal(bp.Height, bp.Round, block, bp.SigningLockset, roundLockset); err == nil && bp2 != nil {
 				log.Info("create bp1", "hash", bp.Hash())
 				log.Info("create bp2", "hash", bp2.Hash())
-				rm.cm.Sign(bp2)
-				rm.cm.pm.BroadcastTwoBlockProposal(bp, bp2)
+				if err := rm.cm.Sign(bp2); err == nil {
+					rm.cm.pm.BroadcastTwoBlockProposal(bp, bp2)
+				}
 			} else {
 				log.Error("bp2 is empty", "bp2", bp2)
 				log.Error("create bp2 occur error,", "err", err)
@@ -1168,8 +1273,10 @@ func (rm *RoundManager) propose() btypes.Proposal {
 				log.Error("error occur %v", err)
 				return nil
 			} else {
+				if err := rm.cm.Sign(p); err != nil {
+					return nil
+				}
 				proposal = p
-				rm.cm.Sign(proposal)
 			}
 		}
 	}
@@ -1220,7 +1327,9 @@ func (rm *RoundManager) mkProposal() *btypes.BlockProposal {
 		log.Error("error occur %v", err)
 		return nil
 	}
-	rm.cm.Sign(blockProposal)
+	if err := rm.cm.Sign(blockProposal); err != nil {
+		return nil
+	}
 	rm.cm.setProposalLock(block)
 	log.Debug("Create block blockhash : ", blockProposal.Blockhash())
 	return blockProposal
@@ -1268,7 +1377,9 @@ func (rm *RoundManager) vote() *btypes.Vote {
 	if vote == nil {
 		return nil
 	}
-	rm.cm.Sign(vote)
+	if err := rm.cm.Sign(vote); err != nil {
+		return nil
+	}
 	rm.voteLock = vote
 
 	log.Debug("vote success in", "height", rm.height, "round", rm.round)
@@ -1287,6 +1398,7 @@ func (rm *RoundManager) votePrecommit() *btypes.PrecommitVote {
 		if quorum, blockhash := rm.lockset.HasQuorum(); quorum {
 			log.Debug("prevote quorum. vote precommit on block")
 			vote = btypes.NewPrecommitVote(rm.height, rm.round, blockhash, 1)
+			rm.cm.attestTime(vote)
 		} else if rm.timeoutTime != 0 && float64(rm.cm.Now()) >= rm.timeoutTime {
 			log.Debug("prevote no quorum. vote precommit nil")
 			vote = btypes.NewPrecommitVote(rm.height, rm.round, common.StringToHash(""), 2)
@@ -1297,7 +1409,9 @@ func (rm *RoundManager) votePrecommit() *btypes.PrecommitVote {
 		log.Debug("prevote invalid")
 	}
 	if vote != nil {
-		rm.cm.Sign(vote)
+		if err := rm.cm.Sign(vote); err != nil {
+			return nil
+		}
 		if vote.VoteType == 1 {
 			rm.precommitVoteLock = vote
 		}
@@ -20,6 +20,35 @@ func (p *peer) SendNewBlockProposal(bp *types.BlockProposal) error {
 	p.broadcastFilter.Add(bp.Hash())
 	return p2p.Send(p.rw, NewBlockProposalMsg, []interface{}{bp})
 }
+func (p *peer) SendProposalHeader(h *types.ProposalHeader) error {
+	p.broadcastFilter.Add(h.Hash())
+	return p2p.Send(p.rw, ProposalHeaderMsg, &proposalHeaderData{ProposalHeader: h})
+}
+func (p *peer) SendBlockPart(part *types.BlockPart) error {
+	p.broadcastFilter.Add(part.Hash())
+	return p2p.Send(p.rw, BlockPartMsg, &blockPartData{BlockPart: part})
+}
+
+// sendProposalParts sends the header and the parts of a proposal the peer does
+// not know yet.
+func (p *peer) sendProposalParts(bp *types.BlockProposal, set *partSet) error {
+	p.broadcastFilter.Add(bp.Hash())
+	if !p.broadcastFilter.Has(set.header.Hash()) {
+		if err := p.SendProposalHeader(set.header); err != nil {
+			return err
+		}
+	}
+	for i := uint32(0); i < set.header.Parts.Total; i++ {
+		part := set.parts.GetPart(i)
+		if part == nil || p.broadcastFilter.Has(part.Hash()) {
+			continue
+		}
+		if err := p.SendBlockPart(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 func (p *peer) SendVotingInstruction(vi *types.VotingInstruction) error {
 	p.broadcastFilter.Add(vi.Hash())
 	return p2p.Send(p.rw, VotingInstructionMsg, &votingInstructionData{VotingInstruction: vi})
@@ -20,6 +20,8 @@ const (
 	PrecommitLocksetMsg     = 0x0a
 	NewBlockProposalMsg     = 0x0b
 	VotingInstructionMsg    = 0x0c
+	ProposalHeaderMsg       = 0x0d // bft64: part set of a block proposal
+	BlockPartMsg            = 0x0e // bft64: part of a block proposal
 	VoteMsg                 = 0x11
 	PrecommitVoteMsg        = 0x12
 )
@@ -51,6 +53,12 @@ type voteData struct {
 type precommitVoteData struct {
 	PrecommitVote *types.PrecommitVote
 }
+type proposalHeaderData struct {
+	ProposalHeader *types.ProposalHeader
+}
+type blockPartData struct {
+	BlockPart *types.BlockPart
+}
 type readyData struct {
 	Ready *types.Ready
 }
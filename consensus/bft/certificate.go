@@ -0,0 +1,209 @@
+package bft
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	errMissingCertificate  = errors.New("missing commit certificate")
+	errEligibleMismatch    = errors.New("certificate eligible votes mismatch validator set")
+	errCertificateMismatch = errors.New("certificate quorum is not for the block")
+	errUnknownSigner       = errors.New("certificate vote signed by a non-validator")
+	errDuplicateSigner     = errors.New("certificate contains duplicate signer")
+	errInsufficientVotes   = errors.New("certificate has no valid quorum")
+)
+
+// lastCommittingLocksetKey is the bft database key of the lockset committed last.
+var lastCommittingLocksetKey = []byte("last_committing_lockset")
+
+// precommitLocksetKey returns the bft database key of a block's commit certificate.
+func precommitLocksetKey(hash common.Hash) []byte {
+	return []byte(fmt.Sprintf("precommitLockset:%s", hash))
+}
+
+// GetPrecommitLockset retrieves the commit certificate of a block from the bft
+// database, returning nil if it is missing or undecodable.
+func GetPrecommitLockset(db ethdb.Database, hash common.Hash) *btypes.PrecommitLockSet {
+	return getLockset(db, precommitLocksetKey(hash))
+}
+
+// WritePrecommitLockset stores the commit certificate of a block into the bft
+// database.
+func WritePrecommitLockset(db ethdb.Database, hash common.Hash, pls *btypes.PrecommitLockSet) error {
+	return putLockset(db, precommitLocksetKey(hash), pls)
+}
+
+// GetLastCommittingLockset retrieves the lockset committed last by the local
+// node, returning nil if none was stored.
+func GetLastCommittingLockset(db ethdb.Database) *btypes.PrecommitLockSet {
+	return getLockset(db, lastCommittingLocksetKey)
+}
+
+// WriteLastCommittingLockset stores the lockset committed last by the local node.
+func WriteLastCommittingLockset(db ethdb.Database, pls *btypes.PrecommitLockSet) error {
+	return putLockset(db, lastCommittingLocksetKey, pls)
+}
+
+func getLockset(db ethdb.Database, key []byte) *btypes.PrecommitLockSet {
+	data, _ := db.Get(key)
+	if len(data) == 0 {
+		return nil
+	}
+	var pls *btypes.PrecommitLockSet
+	if err := rlp.Decode(bytes.NewReader(data), &pls); err != nil {
+		return nil
+	}
+	return pls
+}
+
+func putLockset(db ethdb.Database, key []byte, pls *btypes.PrecommitLockSet) error {
+	data, err := rlp.EncodeToBytes(pls)
+	if err != nil {
+		return err
+	}
+	return db.Put(key, data)
+}
+
+// NewDomain returns the domain separating the signatures of the consensus
+// messages of the chain from those of other networks, starting at the domain
+// fork block. It is nil if the chain never separates them.
+func NewDomain(config *params.ChainConfig, genesis common.Hash) *btypes.Domain {
+	if config.BFT == nil || config.BFT.DomainBlock == nil {
+		return nil
+	}
+	return &btypes.Domain{
+		ChainID: config.ChainId,
+		Genesis: genesis,
+		Block:   config.BFT.DomainBlock,
+	}
+}
+
+// VerifyCommit checks that pls is a valid commit certificate for the block with
+// the given hash and number: more than two thirds of the validators must have
+// signed a precommit vote for it, and every vote must come from a validator.
+// The votes are verified under the signature domain d of the chain.
+func VerifyCommit(pls *btypes.PrecommitLockSet, hash common.Hash, number uint64, validators []common.Address, d *btypes.Domain) error {
+	if pls == nil {
+		return errMissingCertificate
+	}
+	if int(pls.EligibleVotesNum) != len(validators) {
+		return errEligibleMismatch
+	}
+	if ok, quorum := pls.HasQuorum(); !ok || quorum != hash {
+		return errCertificateMismatch
+	}
+	var (
+		seen  = make(map[common.Address]bool)
+		votes int
+	)
+	for _, v := range pls.PrecommitVotes {
+		signer, err := v.From(d)
+		if err != nil {
+			return err
+		}
+		if !containsAddress(validators, signer) {
+			return errUnknownSigner
+		}
+		if seen[signer] {
+			return errDuplicateSigner
+		}
+		seen[signer] = true
+		if v.VoteType == 1 && v.Blockhash == hash && v.Height == number {
+			votes++
+		}
+	}
+	if 3*votes <= 2*len(validators) {
+		return errInsufficientVotes
+	}
+	return nil
+}
+
+// CertifiedBlock is the export format of a block sealed by BFT consensus: the
+// block followed by the commit certificate proving its finality.
+type CertifiedBlock struct {
+	Block       *types.Block
+	Certificate *btypes.PrecommitLockSet
+}
+
+// DecodeBlock reads the next block of an export stream. Streams may mix plain
+// RLP blocks and certified blocks; the certificate is nil for the former.
+func DecodeBlock(stream *rlp.Stream) (*types.Block, *btypes.PrecommitLockSet, error) {
+	raw, err := stream.Raw()
+	if err != nil {
+		return nil, nil, err
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(raw, block); err == nil {
+		return block, nil, nil
+	}
+	var certified CertifiedBlock
+	if err := rlp.DecodeBytes(raw, &certified); err != nil {
+		return nil, nil, err
+	}
+	return certified.Block, certified.Certificate, nil
+}
+
+// certificates returns the commit certificate store and the validator set the
+// engine verifies blocks against.
+func (b *BFT) certificates() (ethdb.Database, []common.Address) {
+	if b.pm != nil {
+		cm := b.pm.consensusManager
+		return cm.hdcDb, cm.contract.validators
+	}
+	return b.bftDb, b.validators
+}
+
+// Certificate retrieves the stored commit certificate of the block with the
+// given hash, or nil if there is none.
+func (b *BFT) Certificate(hash common.Hash) *btypes.PrecommitLockSet {
+	db, _ := b.certificates()
+	if db == nil {
+		return nil
+	}
+	return GetPrecommitLockset(db, hash)
+}
+
+// ExportChain writes the canonical blocks first..last of chain to w, each
+// carrying its commit certificate. Blocks without a stored certificate, such
+// as the genesis, are written as plain RLP blocks.
+func (b *BFT) ExportChain(chain *core.BlockChain, w io.Writer, first, last uint64) error {
+	if first > last {
+		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+	}
+	db, _ := b.certificates()
+	for nr := first; nr <= last; nr++ {
+		block := chain.GetBlockByNumber(nr)
+		if block == nil {
+			return fmt.Errorf("export failed on #%d: not found", nr)
+		}
+		var item interface{} = block
+		if pls := GetPrecommitLockset(db, block.Hash()); pls != nil {
+			item = &CertifiedBlock{Block: block, Certificate: pls}
+		}
+		if err := rlp.Encode(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportCertificate verifies the commit certificate of an imported block and
+// stores it, so that the block passes seal verification without peers.
+func (b *BFT) ImportCertificate(header *types.Header, pls *btypes.PrecommitLockSet) error {
+	db, validators := b.certificates()
+	if err := VerifyCommit(pls, header.Hash(), header.Number.Uint64(), validators, b.domain); err != nil {
+		return err
+	}
+	return WritePrecommitLockset(db, header.Hash(), pls)
+}
@@ -0,0 +1,136 @@
+package bft
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func testValidators(n int) ([]*ecdsa.PrivateKey, []common.Address) {
+	var (
+		keys  []*ecdsa.PrivateKey
+		addrs []common.Address
+	)
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		addrs = append(addrs, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	return keys, addrs
+}
+
+func testCertificate(keys []*ecdsa.PrivateKey, eligible int, height uint64, hash common.Hash) *btypes.PrecommitLockSet {
+	return testDomainCertificate(keys, eligible, height, hash, nil)
+}
+
+func testDomainCertificate(keys []*ecdsa.PrivateKey, eligible int, height uint64, hash common.Hash, d *btypes.Domain) *btypes.PrecommitLockSet {
+	pls := btypes.NewPrecommitLockSet(uint64(eligible), btypes.PrecommitVotes{})
+	for _, key := range keys {
+		v := btypes.NewPrecommitVote(height, 0, hash, 1)
+		v.Sign(key, d)
+		pls.Add(v, false)
+	}
+	return pls
+}
+
+func TestVerifyCommit(t *testing.T) {
+	keys, validators := testValidators(4)
+	hash := common.HexToHash("0x01")
+
+	if err := VerifyCommit(testCertificate(keys[:3], 4, 1, hash), hash, 1, validators, nil); err != nil {
+		t.Errorf("valid certificate rejected: %v", err)
+	}
+	if err := VerifyCommit(nil, hash, 1, validators, nil); err != errMissingCertificate {
+		t.Errorf("missing certificate: have %v, want %v", err, errMissingCertificate)
+	}
+	if err := VerifyCommit(testCertificate(keys[:2], 4, 1, hash), hash, 1, validators, nil); err != errCertificateMismatch {
+		t.Errorf("no quorum: have %v, want %v", err, errCertificateMismatch)
+	}
+	if err := VerifyCommit(testCertificate(keys[:3], 4, 1, hash), common.HexToHash("0x02"), 1, validators, nil); err != errCertificateMismatch {
+		t.Errorf("other block: have %v, want %v", err, errCertificateMismatch)
+	}
+	if err := VerifyCommit(testCertificate(keys[:3], 3, 1, hash), hash, 1, validators, nil); err != errEligibleMismatch {
+		t.Errorf("eligible mismatch: have %v, want %v", err, errEligibleMismatch)
+	}
+	if err := VerifyCommit(testCertificate(keys[:3], 4, 2, hash), hash, 1, validators, nil); err != errInsufficientVotes {
+		t.Errorf("wrong height: have %v, want %v", err, errInsufficientVotes)
+	}
+	outsiders, _ := testValidators(8)
+	if err := VerifyCommit(testCertificate(outsiders[4:7], 4, 1, hash), hash, 1, validators, nil); err != errUnknownSigner {
+		t.Errorf("outsider signers: have %v, want %v", err, errUnknownSigner)
+	}
+}
+
+func TestDecodeBlock(t *testing.T) {
+	keys, _ := testValidators(4)
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1)})
+	pls := testCertificate(keys[:3], 4, 1, block.Hash())
+
+	buf := new(bytes.Buffer)
+	rlp.Encode(buf, block)
+	rlp.Encode(buf, &CertifiedBlock{Block: block, Certificate: pls})
+	stream := rlp.NewStream(buf, 0)
+
+	b, cert, err := DecodeBlock(stream)
+	if err != nil || b.Hash() != block.Hash() || cert != nil {
+		t.Fatalf("plain block: have %v/%v, err %v", b, cert, err)
+	}
+	b, cert, err = DecodeBlock(stream)
+	if err != nil || b.Hash() != block.Hash() || cert == nil {
+		t.Fatalf("certified block: have %v/%v, err %v", b, cert, err)
+	}
+	if ok, hash := cert.HasQuorum(); !ok || hash != block.Hash() {
+		t.Errorf("certificate quorum mismatch: have %v/%x", ok, hash)
+	}
+}
+
+func TestVerifyCommitDomain(t *testing.T) {
+	keys, validators := testValidators(4)
+	hash := common.HexToHash("0x01")
+
+	staging := &btypes.Domain{ChainID: big.NewInt(1), Genesis: common.HexToHash("0xaa"), Block: big.NewInt(10)}
+	production := &btypes.Domain{ChainID: big.NewInt(2), Genesis: common.HexToHash("0xbb"), Block: big.NewInt(10)}
+
+	// Certificates past the fork are only valid on the network that signed them
+	forked := testDomainCertificate(keys[:3], 4, 10, hash, staging)
+	legacy := testDomainCertificate(keys[:3], 4, 9, hash, staging)
+
+	if err := VerifyCommit(decodeCertificate(t, forked), hash, 10, validators, production); err != errUnknownSigner {
+		t.Errorf("foreign certificate: have %v, want %v", err, errUnknownSigner)
+	}
+	// Certificates before the fork stay verifiable
+	if err := VerifyCommit(decodeCertificate(t, legacy), hash, 9, validators, production); err != nil {
+		t.Errorf("pre-fork certificate rejected: %v", err)
+	}
+	if err := VerifyCommit(decodeCertificate(t, legacy), hash, 9, validators, nil); err != nil {
+		t.Errorf("pre-fork certificate rejected without domain: %v", err)
+	}
+	if err := VerifyCommit(decodeCertificate(t, forked), hash, 10, validators, staging); err != nil {
+		t.Errorf("own certificate rejected: %v", err)
+	}
+	// Signers cached under one domain are not trusted under another
+	if err := VerifyCommit(forked, hash, 10, validators, production); err != errUnknownSigner {
+		t.Errorf("cached foreign signers: have %v, want %v", err, errUnknownSigner)
+	}
+}
+
+// decodeCertificate round trips a certificate through RLP, dropping the
+// cached signers as when loading it from the database.
+func decodeCertificate(t *testing.T, pls *btypes.PrecommitLockSet) *btypes.PrecommitLockSet {
+	blob, err := rlp.EncodeToBytes(pls)
+	if err != nil {
+		t.Fatalf("failed to encode certificate: %v", err)
+	}
+	var dec *btypes.PrecommitLockSet
+	if err := rlp.DecodeBytes(blob, &dec); err != nil {
+		t.Fatalf("failed to decode certificate: %v", err)
+	}
+	return dec
+}
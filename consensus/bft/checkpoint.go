@@ -0,0 +1,129 @@
+package bft
+
+import (
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	errCheckpointMismatch = errors.New("block does not match the checkpoint")
+	errCheckpointNotBFT   = errors.New("checkpoint not sealed by BFT")
+	errNoCheckpointCert   = errors.New("no certificate for the checkpoint")
+	errNoCheckpointSet    = errors.New("no validator set for the checkpoint")
+)
+
+// checkpointValidatorsKey is the bft database key of the validator set of the
+// checkpoint the chain was started from.
+var checkpointValidatorsKey = []byte("checkpoint_validators")
+
+// Checkpoint is a trusted block of a BFT chain, proven final by its commit
+// certificate, from which a node can start syncing instead of replaying the
+// chain from the genesis.
+type Checkpoint struct {
+	Hash        common.Hash              // Hash of the checkpoint block
+	Certificate *btypes.PrecommitLockSet // Commit certificate of the checkpoint
+	Validators  []common.Address         // Validators signing the certificate (nil = those of the engine)
+}
+
+// GetCheckpointValidators retrieves the validator set of the checkpoint the
+// chain was started from, returning nil if it was synced from the genesis.
+func GetCheckpointValidators(db ethdb.Database) []common.Address {
+	data, _ := db.Get(checkpointValidatorsKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var validators []common.Address
+	if err := rlp.DecodeBytes(data, &validators); err != nil {
+		return nil
+	}
+	return validators
+}
+
+// WriteCheckpointValidators stores the validator set of the checkpoint the
+// chain was started from.
+func WriteCheckpointValidators(db ethdb.Database, validators []common.Address) error {
+	data, err := rlp.EncodeToBytes(validators)
+	if err != nil {
+		return err
+	}
+	return db.Put(checkpointValidatorsKey, data)
+}
+
+// ReadCheckpoint reads the certificate of the block of the given hash from a
+// stream of RLP encoded precommit locksets, as written by the certificate
+// export.
+func ReadCheckpoint(r io.Reader, hash common.Hash, validators []common.Address) (*Checkpoint, error) {
+	stream := rlp.NewStream(r, 0)
+	for {
+		var pls *btypes.PrecommitLockSet
+		if err := stream.Decode(&pls); err == io.EOF {
+			return nil, errNoCheckpointCert
+		} else if err != nil {
+			return nil, err
+		}
+		if ok, quorum := pls.HasQuorum(); ok && quorum == hash {
+			return &Checkpoint{Hash: hash, Certificate: pls, Validators: validators}, nil
+		}
+	}
+}
+
+// VerifyCheckpoint checks that header is the block of a checkpoint and that the
+// certificate of the checkpoint carries a quorum of the validators.
+//
+// The validators are those carried by the checkpoint, falling back to the set
+// of the engine only if the checkpoint has none. A set that changed since the
+// genesis, such as the clique signers taking over at the switch to BFT, cannot
+// be derived without the history of the chain and must be carried.
+func (b *BFT) VerifyCheckpoint(config *params.ChainConfig, cp *Checkpoint, header *types.Header) error {
+	if header.Hash() != cp.Hash {
+		return errCheckpointMismatch
+	}
+	if !config.IsBFT(header.Number) {
+		return errCheckpointNotBFT
+	}
+	validators := cp.Validators
+	if len(validators) == 0 {
+		_, validators = b.certificates()
+	}
+	if len(validators) == 0 {
+		return errNoCheckpointSet
+	}
+	return VerifyCommit(cp.Certificate, cp.Hash, header.Number.Uint64(), validators, b.domain)
+}
+
+// ImportCheckpoint stores the certificate of a checkpoint the chain was started
+// from, which completes the certificates of the blocks from it on, along with
+// the validator set that certified it. The set replaces the one of the engine
+// and survives restarts, as the blocks following the checkpoint are verified
+// against it.
+func (b *BFT) ImportCheckpoint(cp *Checkpoint) error {
+	db, validators := b.certificates()
+	if len(cp.Validators) > 0 {
+		validators = cp.Validators
+	}
+	if err := WritePrecommitLockset(db, cp.Hash, cp.Certificate); err != nil {
+		return err
+	}
+	if err := WriteCheckpointValidators(db, validators); err != nil {
+		return err
+	}
+	b.SetValidators(validators)
+	return nil
+}
+
+// CheckpointTD returns the total difficulty of the checkpoint of the given
+// number. It is exact for chains sealed by BFT since the genesis; for chains
+// that switched to BFT later it is a lower bound, which merely makes peers
+// look ahead of the node.
+func CheckpointTD(genesis *types.Block, number uint64) *big.Int {
+	td := new(big.Int).Mul(fixDifficulty, new(big.Int).SetUint64(number))
+	return td.Add(td, genesis.Difficulty())
+}
@@ -0,0 +1,99 @@
+package bft
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that a checkpoint is read from an exported certificate stream and only
+// accepted for the certified block.
+func TestCheckpoint(t *testing.T) {
+	keys, validators := testValidators(4)
+	config := &params.ChainConfig{
+		ChainId:  big.NewInt(1),
+		BFT:      &params.BFTConfig{Validators: validators},
+		BftBlock: big.NewInt(3),
+	}
+	var headers []*types.Header
+	buf := new(bytes.Buffer)
+	for i := int64(1); i <= 5; i++ {
+		header := &types.Header{Number: big.NewInt(i), Difficulty: fixDifficulty}
+		headers = append(headers, header)
+		rlp.Encode(buf, testCertificate(keys[:3], 4, header.Number.Uint64(), header.Hash()))
+	}
+	export := buf.Bytes()
+
+	cp, err := ReadCheckpoint(bytes.NewReader(export), headers[3].Hash(), validators)
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	if _, err := ReadCheckpoint(bytes.NewReader(export), common.HexToHash("0x01"), validators); err != errNoCheckpointCert {
+		t.Errorf("unknown checkpoint: have %v, want %v", err, errNoCheckpointCert)
+	}
+	engine := new(BFT)
+	if err := engine.VerifyCheckpoint(config, cp, headers[3]); err != nil {
+		t.Errorf("valid checkpoint rejected: %v", err)
+	}
+	if err := engine.VerifyCheckpoint(config, cp, headers[4]); err != errCheckpointMismatch {
+		t.Errorf("other block: have %v, want %v", err, errCheckpointMismatch)
+	}
+	// Blocks before the switch to BFT cannot serve as checkpoints
+	early, err := ReadCheckpoint(bytes.NewReader(export), headers[1].Hash(), validators)
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	if err := engine.VerifyCheckpoint(config, early, headers[1]); err != errCheckpointNotBFT {
+		t.Errorf("pre-BFT block: have %v, want %v", err, errCheckpointNotBFT)
+	}
+	_, others := testValidators(4)
+	if err := engine.VerifyCheckpoint(config, &Checkpoint{Hash: cp.Hash, Certificate: cp.Certificate, Validators: others}, headers[3]); err == nil {
+		t.Errorf("certificate of other validators accepted")
+	}
+}
+
+// Tests that the validator set carried by a checkpoint is required if the engine
+// has none, replaces the one of the engine on import and survives restarts.
+func TestCheckpointValidators(t *testing.T) {
+	keys, validators := testValidators(4)
+	config := &params.ChainConfig{
+		ChainId:  big.NewInt(1),
+		BFT:      &params.BFTConfig{},
+		BftBlock: big.NewInt(1),
+	}
+	header := &types.Header{Number: big.NewInt(10), Difficulty: fixDifficulty}
+	cert := testCertificate(keys[:3], 4, 10, header.Hash())
+
+	db, _ := ethdb.NewMemDatabase()
+	engine := NewVerifier(config, common.Hash{}, db, nil)
+	if err := engine.VerifyCheckpoint(config, &Checkpoint{Hash: header.Hash(), Certificate: cert}, header); err != errNoCheckpointSet {
+		t.Errorf("checkpoint without validators: have %v, want %v", err, errNoCheckpointSet)
+	}
+	cp := &Checkpoint{Hash: header.Hash(), Certificate: cert, Validators: validators}
+	if err := engine.VerifyCheckpoint(config, cp, header); err != nil {
+		t.Fatalf("valid checkpoint rejected: %v", err)
+	}
+	if err := engine.ImportCheckpoint(cp); err != nil {
+		t.Fatalf("failed to import checkpoint: %v", err)
+	}
+	if _, have := engine.certificates(); len(have) != len(validators) {
+		t.Errorf("imported validator count mismatch: have %d, want %d", len(have), len(validators))
+	}
+	// The blocks following the checkpoint are verified against its validators
+	restarted := NewVerifier(config, common.Hash{}, db, nil)
+	child := &types.Header{Number: big.NewInt(11), ParentHash: header.Hash(), Difficulty: fixDifficulty}
+	WritePrecommitLockset(db, child.Hash(), testCertificate(keys[:3], 4, 11, child.Hash()))
+	if err := restarted.VerifySeal(nil, child); err != nil {
+		t.Errorf("block after the checkpoint rejected: %v", err)
+	}
+	legacy := NewMigration(config, new(testLegacy), NewVerifier(config, common.Hash{}, db, nil))
+	if have, err := legacy.validators(nil, nil); err != nil || len(have) != len(validators) {
+		t.Errorf("migration validators mismatch: have %d (%v), want %d", len(have), err, len(validators))
+	}
+}
@@ -0,0 +1,151 @@
+package bft
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/compression/rle"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/golang/snappy"
+)
+
+// Compression schemes of bft65 messages. Every message following the handshake
+// is prefixed with the scheme its payload is compressed with.
+const (
+	compressNone   = 0x00
+	compressSnappy = 0x01 // General purpose, for messages carrying blocks or locksets
+	compressRLE    = 0x02 // Zero runs and empty hashes, for small hash-heavy messages
+)
+
+// compressionSchemes are the schemes advertised in the handshake, that is the
+// ones this node decompresses.
+var compressionSchemes = []uint{compressSnappy, compressRLE}
+
+// messageSchemes are the schemes messages are compressed with, by message code.
+// A message is sent uncompressed if the peer does not support its scheme, or if
+// compression does not make it smaller.
+var messageSchemes = map[uint64]uint{
+	NewBlockProposalMsg:  compressSnappy,
+	BlockPartMsg:         compressSnappy,
+	PrecommitLocksetMsg:  compressSnappy,
+	VotingInstructionMsg: compressSnappy,
+	ReadyMsg:             compressRLE,
+	ProposalHeaderMsg:    compressRLE,
+	VoteMsg:              compressRLE,
+	PrecommitVoteMsg:     compressRLE,
+}
+
+var (
+	errNoCompressionScheme = errors.New("missing compression scheme")
+	errUnknownCompression  = errors.New("unknown compression scheme")
+	errDecompressedSize    = errors.New("decompressed message too large")
+)
+
+// compressedMsgReadWriter is a wrapper around a p2p.MsgReadWriter compressing
+// outgoing and decompressing incoming messages with the schemes negotiated in
+// the bft65 handshake.
+type compressedMsgReadWriter struct {
+	p2p.MsgReadWriter
+	remote map[uint]bool // Schemes the remote peer decompresses
+}
+
+// newCompressedMsgReadWriter wraps a p2p MsgReadWriter with compression support,
+// given the schemes advertised by the remote peer.
+func newCompressedMsgReadWriter(rw p2p.MsgReadWriter, schemes []uint) *compressedMsgReadWriter {
+	remote := make(map[uint]bool)
+	for _, scheme := range schemes {
+		remote[scheme] = true
+	}
+	return &compressedMsgReadWriter{MsgReadWriter: rw, remote: remote}
+}
+
+func (rw *compressedMsgReadWriter) ReadMsg() (p2p.Msg, error) {
+	msg, err := rw.MsgReadWriter.ReadMsg()
+	if err != nil {
+		return msg, err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		msg.Discard()
+		return msg, errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	data, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return msg, err
+	}
+	if len(data) == 0 {
+		return msg, errResp(ErrDecode, "msg %v: %v", msg, errNoCompressionScheme)
+	}
+	payload, err := decompress(uint(data[0]), data[1:])
+	if err != nil {
+		return msg, errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	compressionInWireMeter.Mark(int64(len(data)))
+	compressionInRawMeter.Mark(int64(len(payload)))
+
+	msg.Size, msg.Payload = uint32(len(payload)), bytes.NewReader(payload)
+	return msg, nil
+}
+
+func (rw *compressedMsgReadWriter) WriteMsg(msg p2p.Msg) error {
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return err
+	}
+	scheme, data := uint(compressNone), payload
+	if preferred, ok := messageSchemes[msg.Code]; ok && rw.remote[preferred] {
+		if compressed := compress(preferred, payload); len(compressed) < len(payload) {
+			scheme, data = preferred, compressed
+		}
+	}
+	wire := make([]byte, 1+len(data))
+	wire[0] = byte(scheme)
+	copy(wire[1:], data)
+
+	compressionOutRawMeter.Mark(int64(len(payload)))
+	compressionOutWireMeter.Mark(int64(len(wire)))
+
+	msg.Size, msg.Payload = uint32(len(wire)), bytes.NewReader(wire)
+	return rw.MsgReadWriter.WriteMsg(msg)
+}
+
+// compress compresses data with a supported scheme.
+func compress(scheme uint, data []byte) []byte {
+	switch scheme {
+	case compressSnappy:
+		return snappy.Encode(nil, data)
+	case compressRLE:
+		return rle.Compress(data)
+	default:
+		return data
+	}
+}
+
+// decompress decompresses data of the given scheme, refusing data that would
+// exceed the maximum message size.
+func decompress(scheme uint, data []byte) ([]byte, error) {
+	var (
+		size int
+		err  error
+	)
+	switch scheme {
+	case compressNone:
+		return data, nil
+	case compressSnappy:
+		size, err = snappy.DecodedLen(data)
+	case compressRLE:
+		size, err = rle.DecodedLen(data)
+	default:
+		return nil, errUnknownCompression
+	}
+	if err != nil {
+		return nil, err
+	}
+	if size > ProtocolMaxMsgSize {
+		return nil, errDecompressedSize
+	}
+	if scheme == compressSnappy {
+		return snappy.Decode(nil, data)
+	}
+	return rle.Decompress(data)
+}
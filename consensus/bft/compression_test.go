@@ -0,0 +1,73 @@
+package bft
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// Tests that messages are compressed with the scheme of their code if the peer
+// supports it, and restored on the remote side.
+func TestCompressedMsgReadWriter(t *testing.T) {
+	payload := append(bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04}, 1024), make([]byte, 1024)...)
+
+	tests := []struct {
+		code    uint64
+		schemes []uint
+		scheme  byte
+	}{
+		{NewBlockProposalMsg, compressionSchemes, compressSnappy},
+		{VoteMsg, compressionSchemes, compressRLE},
+		{VoteMsg, []uint{compressSnappy}, compressNone},
+		{NewBlockProposalMsg, nil, compressNone},
+		{StatusMsg, compressionSchemes, compressNone},
+	}
+	for i, tt := range tests {
+		in, out := p2p.MsgPipe()
+		sender := newCompressedMsgReadWriter(out, tt.schemes)
+		receiver := newCompressedMsgReadWriter(in, compressionSchemes)
+
+		go sender.WriteMsg(p2p.Msg{Code: tt.code, Size: uint32(len(payload)), Payload: bytes.NewReader(payload)})
+
+		// Peek at the wire to check the scheme used
+		msg, err := in.ReadMsg()
+		if err != nil {
+			t.Fatalf("test %d: failed to read wire message: %v", i, err)
+		}
+		wire, _ := ioutil.ReadAll(msg.Payload)
+		if wire[0] != tt.scheme {
+			t.Errorf("test %d: scheme mismatch: have %d, want %d", i, wire[0], tt.scheme)
+		}
+		if tt.scheme != compressNone && len(wire) >= len(payload) {
+			t.Errorf("test %d: message not compressed: %d bytes", i, len(wire))
+		}
+		go out.WriteMsg(p2p.Msg{Code: tt.code, Size: uint32(len(wire)), Payload: bytes.NewReader(wire)})
+
+		if msg, err = receiver.ReadMsg(); err != nil {
+			t.Fatalf("test %d: failed to read message: %v", i, err)
+		}
+		data, _ := ioutil.ReadAll(msg.Payload)
+		if msg.Code != tt.code || int(msg.Size) != len(payload) || !bytes.Equal(data, payload) {
+			t.Errorf("test %d: message mismatch: code %d, size %d", i, msg.Code, msg.Size)
+		}
+		in.Close()
+	}
+}
+
+// Tests that messages of unknown schemes or decompressing past the size limit
+// are rejected.
+func TestDecompressLimits(t *testing.T) {
+	if _, err := decompress(0x7f, []byte{0x01}); err != errUnknownCompression {
+		t.Errorf("unknown scheme: have %v, want %v", err, errUnknownCompression)
+	}
+	bomb := compress(compressRLE, make([]byte, ProtocolMaxMsgSize+1))
+	if _, err := decompress(compressRLE, bomb); err != errDecompressedSize {
+		t.Errorf("oversized rle: have %v, want %v", err, errDecompressedSize)
+	}
+	bomb = compress(compressSnappy, make([]byte, ProtocolMaxMsgSize+1))
+	if _, err := decompress(compressSnappy, bomb); err != errDecompressedSize {
+		t.Errorf("oversized snappy: have %v, want %v", err, errDecompressedSize)
+	}
+}
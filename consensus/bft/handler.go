@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"runtime"
 	"sync"
 	"time"
 
@@ -70,16 +71,20 @@ type ProtocolManager struct {
 	// bft parameters
 	bftdb              ethdb.Database // bft database
 	validators         []common.Address
+	domain             *btypes.Domain // signature domain of the consensus messages
 	consensusManager   *ConsensusManager
 	consensusContract  *ConsensusContract
-	privateKeyHex      string
+	verifier           *msgVerifier // recovers message signers off the consensus path
+	parts              *partStore   // proposals gossiped in parts
 	addTransactionLock sync.Mutex
 	eventMu            sync.Mutex
 }
 
 // NewProtocolManager returns a new ethereum sub protocol manager. The Ethereum sub protocol manages peers capable
 // with the ethereum network.
-func NewProtocolManager(config *params.ChainConfig, networkId uint64, mux *event.TypeMux, txpool *core.TxPool, blockchain *core.BlockChain, chaindb ethdb.Database, bftdb ethdb.Database, vmConfig vm.Config, validators []common.Address, privateKeyHex string, etherbase common.Address, allowEmpty bool, byzantineMode int) (*ProtocolManager, error) {
+func NewProtocolManager(config *params.ChainConfig, networkId uint64, mux *event.TypeMux, txpool *core.TxPool, blockchain *core.BlockChain, chaindb ethdb.Database, bftdb ethdb.Database, vmConfig vm.Config, validators []common.Address, signer ConsensusSigner, allowEmpty bool, byzantineMode int) (*ProtocolManager, error) {
+	domain := NewDomain(config, blockchain.Genesis().Hash())
+
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
 		networkId:   networkId,
@@ -90,6 +95,9 @@ func NewProtocolManager(config *params.ChainConfig, networkId uint64, mux *event
 		chainconfig: config,
 		peers:       newPeerSet(),
 		vmConfig:    vmConfig,
+		domain:      domain,
+		verifier:    newMsgVerifier(runtime.NumCPU(), domain),
+		parts:       newPartStore(domain),
 	}
 
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
@@ -115,17 +123,25 @@ func NewProtocolManager(config *params.ChainConfig, networkId uint64, mux *event
 		})
 	}
 
+	// The validator set of the genesis takes precedence over the local one
+	if config.BFT != nil && len(config.BFT.Validators) > 0 {
+		validators = config.BFT.Validators
+	}
 	manager.bftdb = bftdb
-	manager.privateKeyHex = privateKeyHex
 	manager.validators = validators
-	manager.consensusContract = NewConsensusContract(mux, etherbase, txpool, validators)
-	manager.consensusManager = NewConsensusManager(manager, blockchain, bftdb, manager.consensusContract, manager.privateKeyHex)
+	manager.consensusContract = NewConsensusContract(mux, signer.Address(), txpool, validators)
+	manager.consensusManager = NewConsensusManager(manager, blockchain, bftdb, manager.consensusContract, signer, domain)
 	manager.consensusManager.isAllowEmptyBlocks = allowEmpty
+	if config.BFT != nil {
+		manager.consensusManager.setConfig(config.BFT)
+	}
 	manager.consensusManager.setByzantineMode(byzantineMode)
 	return manager, nil
 }
 
 func (pm *ProtocolManager) Start() {
+	pm.verifier.start(pm.handleVerified)
+	pm.consensusManager.policy.start(pm.eventMux, pm.txpool)
 	if !pm.consensusContract.isValidators(pm.consensusContract.coinbase) {
 		log.Info("not Validator")
 		return
@@ -133,8 +149,23 @@ func (pm *ProtocolManager) Start() {
 	go pm.announce()
 }
 
+// setValidators replaces the validator set, joining consensus if the local node
+// became a validator.
+func (pm *ProtocolManager) setValidators(validators []common.Address) {
+	joined := !pm.consensusContract.isValidators(pm.consensusContract.coinbase)
+
+	pm.validators = validators
+	pm.consensusContract.validators = validators
+
+	if joined && pm.consensusContract.isValidators(pm.consensusContract.coinbase) {
+		go pm.announce()
+	}
+}
+
 func (pm *ProtocolManager) Stop() {
 	log.Info("Stopping Ethereum protocol")
+	pm.consensusManager.policy.stop()
+	pm.verifier.stop()
 }
 
 func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -169,6 +200,10 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
 		rw.Init(p.version)
 	}
+	// Messages following a bft65 handshake carry their compression scheme
+	if p.version >= bft65 {
+		p.rw = newCompressedMsgReadWriter(p.rw, p.compression)
+	}
 	// Register the peer locally
 	if err := pm.peers.Register(p); err != nil {
 		p.Log().Error("Ethereum peer registration failed", "err", err)
@@ -252,11 +287,29 @@ func (pm *ProtocolManager) handleBFTMsg(p *peer) error {
 		if p.broadcastFilter.Has(bp.Hash()) {
 			return nil
 		}
-		if isValid := pm.consensusManager.AddProposal(bp, p); isValid {
-			pm.BroadcastBFTMsg(bp)
-		} else {
+		pm.verifier.enqueue(bp, p)
+	case msg.Code == ProposalHeaderMsg && p.version >= bft64:
+		var hData proposalHeaderData
+		if err := msg.Decode(&hData); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		header := hData.ProposalHeader
+		if p.broadcastFilter.Has(header.Hash()) {
+			return nil
+		}
+		p.broadcastFilter.Add(header.Hash())
+		pm.handleProposalHeader(header, p)
+	case msg.Code == BlockPartMsg && p.version >= bft64:
+		var pData blockPartData
+		if err := msg.Decode(&pData); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		part := pData.BlockPart
+		if p.broadcastFilter.Has(part.Hash()) {
 			return nil
 		}
+		p.broadcastFilter.Add(part.Hash())
+		pm.handleBlockPart(part, p)
 	case msg.Code == VotingInstructionMsg:
 		var viData votingInstructionData
 		if err := msg.Decode(&viData); err != nil {
@@ -266,9 +319,7 @@ func (pm *ProtocolManager) handleBFTMsg(p *peer) error {
 		if p.broadcastFilter.Has(vi.Hash()) {
 			return nil
 		}
-		if isValid := pm.consensusManager.AddProposal(vi, p); isValid {
-			pm.BroadcastBFTMsg(vi)
-		}
+		pm.verifier.enqueue(vi, p)
 	case msg.Code == VoteMsg:
 		var vData voteData
 		if err := msg.Decode(&vData); err != nil {
@@ -279,10 +330,7 @@ func (pm *ProtocolManager) handleBFTMsg(p *peer) error {
 		if p.broadcastFilter.Has(vote.Hash()) {
 			return nil
 		}
-		// log.Debug("receive vote with HR ", vote.Height, vote.Round)
-		if isValid := pm.consensusManager.AddVote(vote, p); isValid {
-			pm.BroadcastBFTMsg(vote)
-		}
+		pm.verifier.enqueue(vote, p)
 	case msg.Code == PrecommitVoteMsg:
 		var vData precommitVoteData
 		if err := msg.Decode(&vData); err != nil {
@@ -293,25 +341,48 @@ func (pm *ProtocolManager) handleBFTMsg(p *peer) error {
 		if p.broadcastFilter.Has(vote.Hash()) {
 			return nil
 		}
-		// log.Debug("receive precommit vote with HR ", vote.Height, vote.Round)
-		if isValid := pm.consensusManager.AddPrecommitVote(vote, p); isValid {
-			pm.BroadcastBFTMsg(vote)
-		}
+		pm.verifier.enqueue(vote, p)
 	case msg.Code == ReadyMsg:
 		var r readyData
 		if err := msg.Decode(&r); err != nil {
 			log.Debug("err: ", err)
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
-		ready := r.Ready
-		pm.consensusManager.AddReady(ready)
-		pm.BroadcastBFTMsg(ready)
+		pm.verifier.enqueue(r.Ready, p)
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
 	return nil
 }
 
+// handleVerified feeds a consensus message whose signers were recovered by the
+// verifier into consensus, relaying it to the other peers if it was accepted.
+func (pm *ProtocolManager) handleVerified(msg interface{}, p *peer) {
+	switch m := msg.(type) {
+	case *btypes.BlockProposal:
+		if pm.consensusManager.AddProposal(m, p) {
+			pm.BroadcastBFTMsg(m)
+		}
+	case *btypes.VotingInstruction:
+		if pm.consensusManager.AddProposal(m, p) {
+			pm.BroadcastBFTMsg(m)
+		}
+	case *btypes.Vote:
+		// log.Debug("receive vote with HR ", m.Height, m.Round)
+		if pm.consensusManager.AddVote(m, p) {
+			pm.BroadcastBFTMsg(m)
+		}
+	case *btypes.PrecommitVote:
+		// log.Debug("receive precommit vote with HR ", m.Height, m.Round)
+		if pm.consensusManager.AddPrecommitVote(m, p) {
+			pm.BroadcastBFTMsg(m)
+		}
+	case *btypes.Ready:
+		pm.consensusManager.AddReady(m)
+		pm.BroadcastBFTMsg(m)
+	}
+}
+
 func (pm *ProtocolManager) BroadcastBFTMsg(msg interface{}) {
 	// TODO: expect origin
 	var err error
@@ -328,9 +399,14 @@ func (pm *ProtocolManager) BroadcastBFTMsg(msg interface{}) {
 		}
 	case *btypes.BlockProposal:
 		peers := pm.peers.PeersWithoutHash(m.Hash())
+		set := pm.proposalParts(m)
 		// log.Info("Send Bp: ", m)
 		for _, peer := range peers {
-			peer.SendNewBlockProposal(m)
+			if set != nil && peer.version >= bft64 {
+				peer.sendProposalParts(m, set)
+			} else {
+				peer.SendNewBlockProposal(m)
+			}
 		}
 	case *btypes.VotingInstruction:
 		peers := pm.peers.PeersWithoutHash(m.Hash())
@@ -10,6 +10,11 @@ var (
 	miscInTrafficMeter  = metrics.NewMeter("bft/misc/in/traffic")
 	miscOutPacketsMeter = metrics.NewMeter("bft/misc/out/packets")
 	miscOutTrafficMeter = metrics.NewMeter("bft/misc/out/traffic")
+
+	compressionInRawMeter   = metrics.NewMeter("bft/compression/in/raw")
+	compressionInWireMeter  = metrics.NewMeter("bft/compression/in/wire")
+	compressionOutRawMeter  = metrics.NewMeter("bft/compression/out/raw")
+	compressionOutWireMeter = metrics.NewMeter("bft/compression/out/wire")
 )
 
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
@@ -0,0 +1,283 @@
+package bft
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// legacyEmptyDelay is the time after which the miner rechecks the transactions
+// for a block without any under the legacy engines.
+const legacyEmptyDelay = 3 * time.Second
+
+// errNoValidators is returned if the validators taking over a chain can neither
+// be found in the chain config nor in the state of the legacy engine.
+var errNoValidators = errors.New("no validators for the switch to BFT")
+
+// Migration is a consensus engine switching a running chain from ethash or
+// clique to BFT at the configured BftBlock. Blocks below the switch are handled
+// by the legacy engine, the others by BFT.
+//
+// The validators of BFT are taken from the checkpoint the chain was started from
+// if any, then from the chain config if set, otherwise from the clique signers
+// authorized at the block preceding the switch.
+type Migration struct {
+	config *params.ChainConfig
+	legacy consensus.Engine
+	bft    *BFT
+
+	active bool       // Whether the validator set was handed over to BFT
+	lock   sync.Mutex // Protects the hand over
+}
+
+// NewMigration creates an engine delegating to legacy below the BftBlock of the
+// chain config and to bft from it on.
+func NewMigration(config *params.ChainConfig, legacy consensus.Engine, bft *BFT) *Migration {
+	return &Migration{
+		config: config,
+		legacy: legacy,
+		bft:    bft,
+	}
+}
+
+// FromEngine returns the BFT engine behind engine, if any.
+func FromEngine(engine consensus.Engine) (*BFT, bool) {
+	switch e := engine.(type) {
+	case *BFT:
+		return e, true
+	case *Migration:
+		return e.bft, true
+	}
+	return nil, false
+}
+
+// Legacy returns the engine sealing the blocks before the switch.
+func (m *Migration) Legacy() consensus.Engine {
+	return m.legacy
+}
+
+// Activate hands the validator set over to BFT if the chain already reached the
+// switch, so that the node takes part in consensus right away.
+func (m *Migration) Activate(chain consensus.ChainReader) error {
+	if !m.config.IsBFT(new(big.Int).Add(chain.CurrentHeader().Number, common.Big1)) {
+		return nil
+	}
+	return m.activate(chain, nil)
+}
+
+// activate hands the validator set over to BFT unless already done. Parents are
+// the headers preceding the one being verified that are not yet in the chain.
+func (m *Migration) activate(chain consensus.ChainReader, parents []*types.Header) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.active {
+		return nil
+	}
+	validators, err := m.validators(chain, parents)
+	if err != nil {
+		return err
+	}
+	m.bft.SetValidators(validators)
+	m.active = true
+	return nil
+}
+
+// validators returns the validator set of the first BFT block.
+func (m *Migration) validators(chain consensus.ChainReader, parents []*types.Header) ([]common.Address, error) {
+	// Chains started from a checkpoint lack the history preceding the switch
+	if db, _ := m.bft.certificates(); db != nil {
+		if validators := GetCheckpointValidators(db); len(validators) > 0 {
+			return validators, nil
+		}
+	}
+	if len(m.config.BFT.Validators) > 0 {
+		return m.config.BFT.Validators, nil
+	}
+	signers, ok := m.legacy.(*clique.Clique)
+	if !ok {
+		return nil, errNoValidators
+	}
+	number := m.config.BftBlock.Uint64() - 1
+
+	var header *types.Header
+	if len(parents) > 0 && parents[len(parents)-1].Number.Uint64() == number {
+		header, parents = parents[len(parents)-1], parents[:len(parents)-1]
+	} else {
+		header, parents = chain.GetHeaderByNumber(number), nil
+	}
+	if header == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	validators, err := signers.Signers(chain, header, parents)
+	if err != nil {
+		return nil, err
+	}
+	if len(validators) == 0 {
+		return nil, errNoValidators
+	}
+	return validators, nil
+}
+
+// engine returns the engine handling the block of the given number, handing the
+// validator set over first if that is BFT.
+func (m *Migration) engine(chain consensus.ChainReader, number *big.Int) (consensus.Engine, error) {
+	if !m.config.IsBFT(number) {
+		return m.legacy, nil
+	}
+	if err := m.activate(chain, nil); err != nil {
+		return nil, err
+	}
+	return m.bft, nil
+}
+
+// Author implements consensus.Engine, returning the author of the block from
+// the engine that sealed it.
+func (m *Migration) Author(header *types.Header) (common.Address, error) {
+	if m.config.IsBFT(header.Number) {
+		return m.bft.Author(header)
+	}
+	return m.legacy.Author(header)
+}
+
+// VerifyHeader implements consensus.Engine, delegating to the engine of the
+// header's block.
+func (m *Migration) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	engine, err := m.engine(chain, header.Number)
+	if err != nil {
+		return err
+	}
+	return engine.VerifyHeader(chain, header, seal)
+}
+
+// VerifyHeaders implements consensus.Engine, verifying the headers below the
+// switch with the legacy engine and the others with BFT. The headers must be
+// ordered by number.
+func (m *Migration) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	split := sort.Search(len(headers), func(i int) bool {
+		return m.config.IsBFT(headers[i].Number)
+	})
+	legacy := headers[:split]
+
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		if !m.forward(m.legacy.VerifyHeaders, chain, headers[:split], seals[:split], abort, results) {
+			return
+		}
+		if split < len(headers) {
+			// The signers of the legacy headers in this batch decide the validators
+			if err := m.activate(chain, legacy); err != nil {
+				for range headers[split:] {
+					results <- err
+				}
+				return
+			}
+		}
+		// The legacy headers of the batch are ancestors not yet in the chain
+		verify := func(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+			return m.bft.verifyHeaders(chain, headers, legacy)
+		}
+		m.forward(verify, chain, headers[split:], seals[split:], abort, results)
+	}()
+	return abort, results
+}
+
+// forward verifies headers with the given batch verifier, passing the results
+// on. It returns false if the verification was aborted.
+func (m *Migration) forward(verify func(consensus.ChainReader, []*types.Header, []bool) (chan<- struct{}, <-chan error), chain consensus.ChainReader, headers []*types.Header, seals []bool, abort <-chan struct{}, results chan<- error) bool {
+	if len(headers) == 0 {
+		return true
+	}
+	cancel, errs := verify(chain, headers, seals)
+	defer close(cancel)
+
+	for range headers {
+		select {
+		case <-abort:
+			return false
+		case err := <-errs:
+			results <- err
+		}
+	}
+	return true
+}
+
+// VerifyUncles implements consensus.Engine, delegating to the engine of the
+// block.
+func (m *Migration) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	engine, err := m.engine(chain, block.Number())
+	if err != nil {
+		return err
+	}
+	return engine.VerifyUncles(chain, block)
+}
+
+// VerifySeal implements consensus.Engine, delegating to the engine of the
+// header's block.
+func (m *Migration) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	engine, err := m.engine(chain, header.Number)
+	if err != nil {
+		return err
+	}
+	return engine.VerifySeal(chain, header)
+}
+
+// Prepare implements consensus.Engine, delegating to the engine of the header's
+// block.
+func (m *Migration) Prepare(chain consensus.ChainReader, header *types.Header) error {
+	engine, err := m.engine(chain, header.Number)
+	if err != nil {
+		return err
+	}
+	return engine.Prepare(chain, header)
+}
+
+// Finalize implements consensus.Engine, delegating to the engine of the
+// header's block.
+func (m *Migration) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	if m.config.IsBFT(header.Number) {
+		return m.bft.Finalize(chain, header, state, txs, uncles, receipts)
+	}
+	return m.legacy.Finalize(chain, header, state, txs, uncles, receipts)
+}
+
+// Seal implements consensus.Engine, delegating to the engine of the block.
+func (m *Migration) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
+	engine, err := m.engine(chain, block.Number())
+	if err != nil {
+		return nil, err
+	}
+	return engine.Seal(chain, block, stop)
+}
+
+// Ready implements consensus.SealPolicy. Blocks below the switch keep the
+// behaviour of the miner for engines without a policy: non-empty blocks are
+// sealed right away, empty ones are rechecked after a delay.
+func (m *Migration) Ready(chain consensus.ChainReader, block *types.Block) (bool, <-chan struct{}) {
+	if m.config.IsBFT(block.Number()) {
+		return m.bft.Ready(chain, block)
+	}
+	if len(block.Transactions()) > 0 {
+		return true, nil
+	}
+	changed := make(chan struct{})
+	time.AfterFunc(legacyEmptyDelay, func() { close(changed) })
+	return false, changed
+}
+
+// APIs implements consensus.Engine, returning the APIs of both engines.
+func (m *Migration) APIs(chain consensus.ChainReader) []rpc.API {
+	return append(m.legacy.APIs(chain), m.bft.APIs(chain)...)
+}
@@ -0,0 +1,72 @@
+package bft
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testChain is a chain reader only exposing the chain config.
+type testChain struct {
+	consensus.ChainReader
+	config *params.ChainConfig
+}
+
+func (c *testChain) Config() *params.ChainConfig { return c.config }
+
+// testLegacy is a legacy engine accepting all headers, recording their numbers.
+type testLegacy struct {
+	consensus.Engine
+	verified []uint64
+}
+
+func (e *testLegacy) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	results := make(chan error, len(headers))
+	for _, header := range headers {
+		e.verified = append(e.verified, header.Number.Uint64())
+		results <- nil
+	}
+	return make(chan struct{}), results
+}
+
+func TestMigrationVerifyHeaders(t *testing.T) {
+	keys, validators := testValidators(4)
+	config := &params.ChainConfig{
+		ChainId:  big.NewInt(1),
+		BFT:      &params.BFTConfig{Validators: validators},
+		BftBlock: big.NewInt(3),
+	}
+	db, _ := ethdb.NewMemDatabase()
+
+	var headers []*types.Header
+	for i := int64(1); i <= 5; i++ {
+		header := &types.Header{Number: big.NewInt(i), Time: big.NewInt(i), Difficulty: big.NewInt(2)}
+		if len(headers) > 0 {
+			header.ParentHash = headers[len(headers)-1].Hash()
+		}
+		if config.IsBFT(header.Number) {
+			header.Difficulty = fixDifficulty
+			WritePrecommitLockset(db, header.Hash(), testCertificate(keys[:3], 4, header.Number.Uint64(), header.Hash()))
+		}
+		headers = append(headers, header)
+	}
+	legacy := new(testLegacy)
+	migration := NewMigration(config, legacy, NewVerifier(config, headers[0].ParentHash, db, nil))
+
+	_, results := migration.VerifyHeaders(&testChain{config: config}, headers, make([]bool, len(headers)))
+	for i := range headers {
+		if err := <-results; err != nil {
+			t.Errorf("header %d: verification failed: %v", i+1, err)
+		}
+	}
+	if len(legacy.verified) != 2 || legacy.verified[0] != 1 || legacy.verified[1] != 2 {
+		t.Errorf("legacy engine verified %v, want [1 2]", legacy.verified)
+	}
+	if !migration.active || len(migration.bft.validators) != len(validators) {
+		t.Errorf("validators not handed over: active %v, %d validators", migration.active, len(migration.bft.validators))
+	}
+}
@@ -0,0 +1,229 @@
+package bft
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// maxPartSets is the number of proposals being reassembled at the same time.
+const maxPartSets = 16
+
+var (
+	errNotProposer      = errors.New("proposal header not signed by the proposer")
+	errUnknownPartSet   = errors.New("block part of unknown part set")
+	errPartSetMismatch  = errors.New("reassembled proposal does not match its header")
+	errPartSetsExceeded = errors.New("too many part sets")
+)
+
+// Proposals larger than a part are gossiped to bft64 peers as a signed header
+// followed by their parts. Every node verifies each part against the root the
+// proposer signed and relays it right away, so the transmission of a proposal
+// is pipelined across hops instead of being delayed by a full transmit time
+// per hop. Once all parts arrived the proposal is reassembled and enters
+// consensus like a proposal received whole.
+
+// partSet is a block proposal gossiped in parts.
+type partSet struct {
+	header   *btypes.ProposalHeader
+	parts    *btypes.PartSet
+	proposal common.Hash // Signature hash of the proposal carried, known once complete
+}
+
+// partStore tracks the part sets of the proposals being gossiped.
+type partStore struct {
+	sets      map[common.Hash]*partSet // Part sets by Merkle root
+	proposals map[common.Hash]*partSet // Complete part sets by proposal signature hash
+	domain    *btypes.Domain           // Signature domain the proposals are hashed under
+	lock      sync.Mutex
+}
+
+func newPartStore(domain *btypes.Domain) *partStore {
+	return &partStore{
+		domain:    domain,
+		sets:      make(map[common.Hash]*partSet),
+		proposals: make(map[common.Hash]*partSet),
+	}
+}
+
+// add stores a part set, dropping the sets of heights below the current one.
+// If the store is full, the sets of the farthest height are evicted first; the
+// set itself is rejected if it is the farthest.
+func (s *partStore) add(set *partSet, current uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for root, known := range s.sets {
+		if known.header.Height < current {
+			s.remove(root)
+		}
+	}
+	if len(s.sets) >= maxPartSets {
+		var farthest *partSet
+		for _, known := range s.sets {
+			if farthest == nil || known.header.Height > farthest.header.Height {
+				farthest = known
+			}
+		}
+		if set.header.Height >= farthest.header.Height {
+			return errPartSetsExceeded
+		}
+		s.remove(farthest.header.Parts.Root)
+	}
+	s.sets[set.header.Parts.Root] = set
+	if set.proposal != (common.Hash{}) {
+		s.proposals[set.proposal] = set
+	}
+	return nil
+}
+
+func (s *partStore) remove(root common.Hash) {
+	if set, ok := s.sets[root]; ok {
+		delete(s.proposals, set.proposal)
+		delete(s.sets, root)
+	}
+}
+
+// has returns whether the part set of the given root is known.
+func (s *partStore) has(root common.Hash) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, ok := s.sets[root]
+	return ok
+}
+
+// addPart verifies a part and adds it to its set. It returns the set and
+// whether the part was new; the proposal is non-nil if the part completed it.
+func (s *partStore) addPart(part *btypes.BlockPart) (*partSet, bool, *btypes.BlockProposal, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	set, ok := s.sets[part.Root]
+	if !ok {
+		return nil, false, nil, errUnknownPartSet
+	}
+	added, err := set.parts.AddPart(part)
+	if err != nil || !added || !set.parts.IsComplete() {
+		return set, added, nil, err
+	}
+	// A complete set that fails to decode was signed so by the proposer, it is
+	// not the fault of the peer delivering the last part
+	data, err := set.parts.Assemble()
+	if err != nil {
+		return set, true, nil, err
+	}
+	bp := new(btypes.BlockProposal)
+	if err := rlp.DecodeBytes(data, bp); err != nil {
+		log.Debug("Undecodable proposal parts", "root", part.Root, "err", err)
+		return set, true, nil, nil
+	}
+	if bp.Height != set.header.Height || bp.Round != set.header.Round {
+		log.Debug("Invalid proposal parts", "root", part.Root, "err", errPartSetMismatch)
+		return set, true, nil, nil
+	}
+	set.proposal = bp.SigHash(s.domain)
+	s.proposals[set.proposal] = set
+	return set, true, bp, nil
+}
+
+// proposal returns the complete part set carrying the proposal of the given
+// signature hash, if any.
+func (s *partStore) proposal(hash common.Hash) *partSet {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.proposals[hash]
+}
+
+// handleProposalHeader starts reassembling the proposal announced by a header,
+// relaying the header if it was new.
+func (pm *ProtocolManager) handleProposalHeader(header *btypes.ProposalHeader, p *peer) {
+	cm := pm.consensusManager
+
+	current := cm.Height()
+	if header.Height < current || header.Height > current+maxFutureHeights {
+		return
+	}
+	addr, err := header.From(pm.domain)
+	if err != nil {
+		cm.penalize(p, errInvalidSignature)
+		return
+	}
+	if addr != cm.contract.proposer(header.Height, header.Round) {
+		cm.penalize(p, errNotProposer)
+		return
+	}
+	if pm.parts.has(header.Parts.Root) {
+		return
+	}
+	parts, err := btypes.NewPartSetFromHeader(header.Parts)
+	if err != nil {
+		cm.penalize(p, err)
+		return
+	}
+	if err := pm.parts.add(&partSet{header: header, parts: parts}, current); err != nil {
+		log.Debug("Dropping proposal header", "height", header.Height, "round", header.Round, "err", err)
+		return
+	}
+	for _, peer := range pm.peers.PeersWithoutHash(header.Hash()) {
+		if peer.version >= bft64 {
+			peer.SendProposalHeader(header)
+		}
+	}
+}
+
+// handleBlockPart adds a part to its set, relaying it to the peers that know
+// the header. The proposal is handed to consensus once all its parts arrived.
+func (pm *ProtocolManager) handleBlockPart(part *btypes.BlockPart, p *peer) {
+	set, added, bp, err := pm.parts.addPart(part)
+	if err == errUnknownPartSet {
+		// The header may have been dropped as stale or for lack of space
+		return
+	}
+	if err != nil {
+		log.Debug("Invalid block part", "root", part.Root, "index", part.Index, "err", err)
+		pm.consensusManager.penalize(p, err)
+		return
+	}
+	if !added {
+		return
+	}
+	for _, peer := range pm.peers.PeersWithoutHash(part.Hash()) {
+		if peer.version >= bft64 && peer.broadcastFilter.Has(set.header.Hash()) {
+			peer.SendBlockPart(part)
+		}
+	}
+	if bp != nil {
+		pm.verifier.enqueue(bp, p)
+	}
+}
+
+// proposalParts returns the part set to gossip a proposal with, or nil if it
+// is to be sent whole. Part sets of local proposals are created on demand.
+func (pm *ProtocolManager) proposalParts(bp *btypes.BlockProposal) *partSet {
+	if set := pm.parts.proposal(bp.SigHash(pm.domain)); set != nil {
+		return set
+	}
+	if addr, err := bp.From(pm.domain); err != nil || addr != pm.consensusContract.coinbase {
+		return nil
+	}
+	data, err := rlp.EncodeToBytes(bp)
+	if err != nil || len(data) <= btypes.BlockPartSize {
+		return nil
+	}
+	parts := btypes.NewPartSetFromData(data, btypes.BlockPartSize)
+	header := btypes.NewProposalHeader(bp.Height, bp.Round, parts.Header())
+	if err := pm.consensusManager.Sign(header); err != nil {
+		return nil
+	}
+	set := &partSet{header: header, parts: parts, proposal: bp.SigHash(pm.domain)}
+	if err := pm.parts.add(set, pm.consensusManager.Height()); err != nil {
+		return nil
+	}
+	return set
+}
@@ -0,0 +1,89 @@
+package bft
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+)
+
+// Tests that part sets of any size are reassembled from their parts in any
+// order, and that tampered parts are rejected.
+func TestPartSetReassembly(t *testing.T) {
+	const partSize = 64
+
+	for _, size := range []int{1, partSize, partSize + 1, 5 * partSize, 7*partSize - 3, 13 * partSize} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		source := btypes.NewPartSetFromData(data, partSize)
+		header := source.Header()
+
+		target, err := btypes.NewPartSetFromHeader(header)
+		if err != nil {
+			t.Fatalf("size %d: failed to create part set: %v", size, err)
+		}
+		for _, i := range rand.Perm(int(header.Total)) {
+			part := source.GetPart(uint32(i))
+
+			// Corrupt copies of the part must be rejected
+			forged := *part
+			forged.Bytes = append(common.CopyBytes(part.Bytes[1:]), part.Bytes[0]^0xff)
+			if added, _ := target.AddPart(&forged); added {
+				t.Errorf("size %d: part %d with forged content accepted", size, i)
+			}
+			if header.Total > 1 {
+				forged = *part
+				forged.Index = (part.Index + 1) % header.Total
+				if added, _ := target.AddPart(&forged); added {
+					t.Errorf("size %d: part %d with forged index accepted", size, i)
+				}
+			}
+			if added, err := target.AddPart(part); !added || err != nil {
+				t.Fatalf("size %d: part %d rejected: added %v, err %v", size, i, added, err)
+			}
+			if added, _ := target.AddPart(part); added {
+				t.Errorf("size %d: part %d added twice", size, i)
+			}
+		}
+		assembled, err := target.Assemble()
+		if err != nil {
+			t.Fatalf("size %d: failed to assemble: %v", size, err)
+		}
+		if !bytes.Equal(assembled, data) {
+			t.Errorf("size %d: assembled data mismatch", size)
+		}
+	}
+}
+
+// Tests that the part store keeps the nearest heights when full.
+func TestPartStoreBounded(t *testing.T) {
+	store := newPartStore(nil)
+	newSet := func(height uint64, seed byte) *partSet {
+		parts := btypes.NewPartSetFromData([]byte{seed, byte(height)}, btypes.BlockPartSize)
+		return &partSet{header: btypes.NewProposalHeader(height, 0, parts.Header()), parts: parts}
+	}
+	for i := 0; i < maxPartSets; i++ {
+		if err := store.add(newSet(5, byte(i)), 1); err != nil {
+			t.Fatalf("set %d rejected: %v", i, err)
+		}
+	}
+	if err := store.add(newSet(6, 0), 1); err != errPartSetsExceeded {
+		t.Fatalf("farther set error mismatch: have %v, want %v", err, errPartSetsExceeded)
+	}
+	if err := store.add(newSet(2, 0), 1); err != nil {
+		t.Fatalf("nearer set rejected: %v", err)
+	}
+	if len(store.sets) != maxPartSets {
+		t.Fatalf("store size mismatch: have %d, want %d", len(store.sets), maxPartSets)
+	}
+	// Sets below the current height are dropped
+	if err := store.add(newSet(7, 0), 3); err != nil {
+		t.Fatalf("set rejected after pruning: %v", err)
+	}
+	if store.has(newSet(2, 0).header.Parts.Root) {
+		t.Errorf("stale set not pruned")
+	}
+}
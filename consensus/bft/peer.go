@@ -54,8 +54,9 @@ type peer struct {
 	*p2p.Peer
 	rw p2p.MsgReadWriter
 
-	version  int         // Protocol version negotiated
-	forkDrop *time.Timer // Timed connection dropper if forks aren't validated in time
+	version     int         // Protocol version negotiated
+	compression []uint      // Compression schemes supported by the peer (bft65)
+	forkDrop    *time.Timer // Timed connection dropper if forks aren't validated in time
 
 	head common.Hash
 	td   *big.Int
@@ -67,6 +68,7 @@ type peer struct {
 	// bft parameters
 	broadcastFilter *set.Set
 	precommitFilter *set.Set
+	misbehaviour    int32 // Number of invalid consensus messages received (atomic)
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -121,14 +123,18 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 	errc := make(chan error, 2)
 	var status statusData // safe to read after two values have been received from errc
 
+	local := &statusData{
+		ProtocolVersion: uint32(p.version),
+		NetworkId:       network,
+		TD:              td,
+		CurrentBlock:    head,
+		GenesisBlock:    genesis,
+	}
+	if p.version >= bft65 {
+		local.Compression = compressionSchemes
+	}
 	go func() {
-		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
-			ProtocolVersion: uint32(p.version),
-			NetworkId:       network,
-			TD:              td,
-			CurrentBlock:    head,
-			GenesisBlock:    genesis,
-		})
+		errc <- p2p.Send(p.rw, StatusMsg, local)
 	}()
 	go func() {
 		errc <- p.readStatus(network, &status, genesis)
@@ -146,6 +152,7 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 		}
 	}
 	p.td, p.head = status.TD, status.CurrentBlock
+	p.compression = status.Compression
 	return nil
 }
 
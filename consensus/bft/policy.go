@@ -0,0 +1,153 @@
+package bft
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// blockPolicy decides when a validator seals and proposes a block. It tracks
+// the transactions announced through the event mux instead of polling the
+// transaction pool, so that waiting sealers are woken up as soon as the
+// decision may change.
+type blockPolicy struct {
+	emptyInterval time.Duration // Time without a new head after which any block is sealed (0 = never)
+	minTxs        int           // Minimum number of transactions before proposing
+	minGas        *big.Int      // Minimum gas used before proposing
+	maxWait       time.Duration // Time a transaction waits for the minimums before a partial block is sealed (0 = forever)
+
+	pending  map[common.Hash]time.Time // Arrival time of the transactions not yet in a block
+	lastHead time.Time                 // Arrival time of the current head block
+	changed  chan struct{}             // Closed whenever the decision may have changed
+	timer    *time.Timer               // Timer notifying the waiters at the earliest deadline
+	deadline time.Time                 // Deadline the timer is armed for (zero = none)
+
+	sub *event.TypeMuxSubscription
+	mu  sync.Mutex
+}
+
+func newBlockPolicy() *blockPolicy {
+	return &blockPolicy{
+		minTxs:   1,
+		minGas:   new(big.Int),
+		pending:  make(map[common.Hash]time.Time),
+		lastHead: time.Now(),
+		changed:  make(chan struct{}),
+	}
+}
+
+// start begins tracking the transactions and head blocks posted on mux.
+func (p *blockPolicy) start(mux *event.TypeMux, txpool txPool) {
+	p.sub = mux.Subscribe(core.TxPreEvent{}, core.ChainHeadEvent{})
+	go p.loop(txpool)
+}
+
+func (p *blockPolicy) stop() {
+	if p.sub != nil {
+		p.sub.Unsubscribe()
+	}
+}
+
+func (p *blockPolicy) loop(txpool txPool) {
+	for obj := range p.sub.Chan() {
+		p.mu.Lock()
+		switch ev := obj.Data.(type) {
+		case core.TxPreEvent:
+			if _, ok := p.pending[ev.Tx.Hash()]; !ok {
+				p.pending[ev.Tx.Hash()] = time.Now()
+			}
+		case core.ChainHeadEvent:
+			for _, tx := range ev.Block.Transactions() {
+				delete(p.pending, tx.Hash())
+			}
+			// Forget dropped and replaced transactions once the pool drained
+			if pending, _ := txpool.Stats(); pending == 0 {
+				p.pending = make(map[common.Hash]time.Time)
+			}
+			p.lastHead = time.Now()
+		}
+		p.notify()
+		p.mu.Unlock()
+	}
+}
+
+// notify wakes up everyone waiting for the decision to change. The caller
+// must hold the lock.
+func (p *blockPolicy) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// ready reports whether a block with the given number of transactions and gas
+// usage should be proposed now. Otherwise it returns the time at which the
+// decision changes without new events, or the zero time if it doesn't.
+func (p *blockPolicy) ready(txs int, gas *big.Int, now time.Time) (bool, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var deadline time.Time
+	if p.emptyInterval > 0 {
+		heartbeat := p.lastHead.Add(p.emptyInterval)
+		if !now.Before(heartbeat) {
+			return true, time.Time{}
+		}
+		deadline = heartbeat
+	}
+	if txs == 0 {
+		return false, deadline
+	}
+	if txs >= p.minTxs && gas.Cmp(p.minGas) >= 0 {
+		return true, time.Time{}
+	}
+	if p.maxWait > 0 {
+		partial := p.oldest().Add(p.maxWait)
+		if !now.Before(partial) {
+			return true, time.Time{}
+		}
+		if deadline.IsZero() || partial.Before(deadline) {
+			deadline = partial
+		}
+	}
+	return false, deadline
+}
+
+// oldest returns the arrival time of the oldest pending transaction, or that
+// of the head block if none is tracked. The caller must hold the lock.
+func (p *blockPolicy) oldest() time.Time {
+	var oldest time.Time
+	for _, seen := range p.pending {
+		if oldest.IsZero() || seen.Before(oldest) {
+			oldest = seen
+		}
+	}
+	if oldest.IsZero() {
+		return p.lastHead
+	}
+	return oldest
+}
+
+// wait returns a channel that is closed on the next event, or at the given
+// deadline if it is not zero. A single timer is armed for the earliest deadline
+// waited for, later ones are armed again by the waiters it wakes up.
+func (p *blockPolicy) wait(deadline time.Time) <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !deadline.IsZero() && (p.deadline.IsZero() || deadline.Before(p.deadline)) {
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+		p.deadline = deadline
+		p.timer = time.AfterFunc(deadline.Sub(time.Now()), func() {
+			p.mu.Lock()
+			p.deadline = time.Time{}
+			p.notify()
+			p.mu.Unlock()
+		})
+	}
+	return p.changed
+}
@@ -0,0 +1,75 @@
+package bft
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBlockPolicy(t *testing.T) {
+	head := time.Unix(1000, 0)
+	policy := newBlockPolicy()
+	policy.lastHead = head
+	policy.emptyInterval = 30 * time.Second
+	policy.minTxs = 10
+	policy.minGas = big.NewInt(100000)
+	policy.maxWait = 5 * time.Second
+	policy.pending[common.HexToHash("0x01")] = head.Add(2 * time.Second)
+
+	tests := []struct {
+		txs      int
+		gas      int64
+		now      time.Time
+		ready    bool
+		deadline time.Time
+	}{
+		// Empty blocks only wait for the heartbeat
+		{0, 0, head.Add(time.Second), false, head.Add(30 * time.Second)},
+		{0, 0, head.Add(30 * time.Second), true, time.Time{}},
+		// Full blocks are proposed right away
+		{10, 100000, head.Add(time.Second), true, time.Time{}},
+		// Partial blocks wait for the oldest transaction to time out
+		{10, 50000, head.Add(3 * time.Second), false, head.Add(7 * time.Second)},
+		{1, 100000, head.Add(7 * time.Second), true, time.Time{}},
+	}
+	for i, tt := range tests {
+		ready, deadline := policy.ready(tt.txs, big.NewInt(tt.gas), tt.now)
+		if ready != tt.ready || !deadline.Equal(tt.deadline) {
+			t.Errorf("test %d: have %v/%v, want %v/%v", i, ready, deadline, tt.ready, tt.deadline)
+		}
+	}
+	// Without a heartbeat, empty blocks are never proposed
+	policy.emptyInterval = 0
+	if ready, deadline := policy.ready(0, new(big.Int), head.Add(time.Hour)); ready || !deadline.IsZero() {
+		t.Errorf("empty block without heartbeat: have %v/%v, want false/zero", ready, deadline)
+	}
+}
+
+// Tests that waiting arms a single timer for the earliest deadline, no matter
+// how often the decision is polled.
+func TestBlockPolicyWait(t *testing.T) {
+	policy := newBlockPolicy()
+
+	deadline := time.Now().Add(time.Hour)
+	policy.wait(deadline)
+	timer := policy.timer
+	for i := 0; i < 10; i++ {
+		policy.wait(deadline)
+		policy.wait(deadline.Add(time.Minute))
+	}
+	if policy.timer != timer {
+		t.Fatalf("timer rearmed for the same or a later deadline")
+	}
+	// Earlier deadlines replace the armed timer
+	changed := policy.wait(time.Now().Add(10 * time.Millisecond))
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatalf("earlier deadline not notified")
+	}
+	if !policy.deadline.IsZero() {
+		t.Errorf("deadline not cleared after notification: %v", policy.deadline)
+	}
+}
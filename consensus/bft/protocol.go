@@ -29,16 +29,18 @@ import (
 // Constants to match up protocol versions and messages
 const (
 	eth63 = 63
+	bft64 = 64 // Gossips large block proposals in parts
+	bft65 = 65 // Negotiates message compression in the handshake
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "bft"
 
 // Supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{eth63}
+var ProtocolVersions = []uint{bft65, bft64, eth63}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{20}
+var ProtocolLengths = []uint64{20, 20, 20}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -80,6 +82,9 @@ type txPool interface {
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
+
+	// Stats should return the number of pending and queued transactions.
+	Stats() (int, int)
 }
 
 // statusData is the network packet for the status message.
@@ -89,6 +94,7 @@ type statusData struct {
 	TD              *big.Int
 	CurrentBlock    common.Hash
 	GenesisBlock    common.Hash
+	Compression     []uint `rlp:"tail"` // bft65: compression schemes supported
 }
 
 // hashOrNumber is a combined field for specifying an origin block.
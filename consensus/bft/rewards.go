@@ -0,0 +1,107 @@
+package bft
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var big100 = big.NewInt(100)
+
+// Once rewards or median times are enabled, the extra-data of every block past
+// the first one sealed by BFT carries the commit certificate of its parent. The
+// validators who signed it share the fees of the block and attest its time,
+// which keeps both deterministic even though the certificate of a block is only
+// assembled after it was sealed.
+
+// carriesCertificate returns whether the extra-data of header must hold the
+// commit certificate of its parent.
+func carriesCertificate(config *params.ChainConfig, header *types.Header) bool {
+	if config.BFT == nil || header.Number.Uint64() <= 1 {
+		return false
+	}
+	if !config.BFT.IsRewarding(header.Number) && !config.BFT.IsMedianTime(header.Number) {
+		return false
+	}
+	return config.IsBFT(new(big.Int).Sub(header.Number, common.Big1))
+}
+
+// parentCertificate decodes the commit certificate of the parent block from the
+// extra-data of header.
+func parentCertificate(header *types.Header) (*btypes.PrecommitLockSet, error) {
+	pls := new(btypes.PrecommitLockSet)
+	if err := rlp.DecodeBytes(header.Extra, pls); err != nil {
+		return nil, err
+	}
+	return pls, nil
+}
+
+// certificateSigners returns the validators who signed a commit certificate,
+// sorted by address.
+func certificateSigners(pls *btypes.PrecommitLockSet, d *btypes.Domain) []common.Address {
+	var signers []common.Address
+	for _, vote := range pls.PrecommitVotes {
+		if addr, err := vote.From(d); err == nil && !containsAddress(signers, addr) {
+			signers = append(signers, addr)
+		}
+	}
+	sort.Sort(addressesAscending(signers))
+	return signers
+}
+
+// addressesAscending implements the sort interface to allow sorting a list of
+// addresses.
+type addressesAscending []common.Address
+
+func (s addressesAscending) Len() int           { return len(s) }
+func (s addressesAscending) Less(i, j int) bool { return bytes.Compare(s[i][:], s[j][:]) < 0 }
+func (s addressesAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// accumulateRewards credits the proposer of a block with the block reward and
+// splits the transaction fees, which the state transition paid to the proposer,
+// evenly among the signers. The treasury, if any, takes its share of both
+// first. Remainders of the divisions stay with the proposer, as do the fees of
+// a block without signers.
+func accumulateRewards(config *params.BFTConfig, state *state.StateDB, header *types.Header, txs []*types.Transaction, receipts []*types.Receipt, signers []common.Address) {
+	reward := new(big.Int)
+	if config.BlockReward != nil {
+		reward.Set(config.BlockReward)
+	}
+	fees := new(big.Int)
+	for i, receipt := range receipts {
+		fees.Add(fees, new(big.Int).Mul(receipt.GasUsed, txs[i].GasPrice()))
+	}
+	// The proposer may have spent fees collected earlier in the same block
+	if balance := state.GetBalance(header.Coinbase); balance.Cmp(fees) < 0 {
+		fees.Set(balance)
+	}
+	state.SubBalance(header.Coinbase, fees)
+
+	if config.Treasury != nil && config.TreasuryShare > 0 {
+		share := new(big.Int).SetUint64(config.TreasuryShare)
+		if share.Cmp(big100) > 0 {
+			share.Set(big100)
+		}
+		rewardCut := new(big.Int).Div(new(big.Int).Mul(reward, share), big100)
+		feeCut := new(big.Int).Div(new(big.Int).Mul(fees, share), big100)
+
+		state.AddBalance(*config.Treasury, new(big.Int).Add(rewardCut, feeCut))
+		reward.Sub(reward, rewardCut)
+		fees.Sub(fees, feeCut)
+	}
+	if len(signers) > 0 {
+		share := new(big.Int).Div(fees, big.NewInt(int64(len(signers))))
+		for _, signer := range signers {
+			state.AddBalance(signer, share)
+			fees.Sub(fees, share)
+		}
+	}
+	state.AddBalance(header.Coinbase, new(big.Int).Add(reward, fees))
+}
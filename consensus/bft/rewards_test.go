@@ -0,0 +1,79 @@
+package bft
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestAccumulateRewards(t *testing.T) {
+	var (
+		proposer = common.HexToAddress("0x01")
+		treasury = common.HexToAddress("0x02")
+		signers  = []common.Address{common.HexToAddress("0x11"), common.HexToAddress("0x12"), common.HexToAddress("0x13"), common.HexToAddress("0x14")}
+
+		header   = &types.Header{Number: big.NewInt(10), Coinbase: proposer}
+		txs      = []*types.Transaction{types.NewTransaction(0, proposer, new(big.Int), big.NewInt(30000), big.NewInt(10), nil)}
+		receipts = []*types.Receipt{{GasUsed: big.NewInt(21001)}}
+	)
+	tests := []struct {
+		config   *params.BFTConfig
+		balance  int64 // Balance of the proposer after the transactions
+		signers  []common.Address
+		proposer int64
+		treasury int64
+		signer   int64
+	}{
+		// Reward and fees all go to the proposer without signers or treasury
+		{&params.BFTConfig{BlockReward: big.NewInt(1000)}, 210010, nil, 211010, 0, 0},
+		// Fees are split among the signers, remainders stay with the proposer
+		{&params.BFTConfig{BlockReward: big.NewInt(1000)}, 210010, signers, 1002, 0, 52502},
+		// The treasury takes its share of both the reward and the fees
+		{&params.BFTConfig{BlockReward: big.NewInt(1000), Treasury: &treasury, TreasuryShare: 10}, 210010, signers, 901, 21101, 47252},
+		// Fees spent by the proposer within the block are not distributed
+		{&params.BFTConfig{}, 4002, signers, 2, 0, 1000},
+	}
+	for i, tt := range tests {
+		db, _ := ethdb.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		statedb.AddBalance(proposer, big.NewInt(tt.balance))
+
+		accumulateRewards(tt.config, statedb, header, txs, receipts, tt.signers)
+
+		if have := statedb.GetBalance(proposer); have.Int64() != tt.proposer {
+			t.Errorf("test %d: proposer balance mismatch: have %v, want %d", i, have, tt.proposer)
+		}
+		if have := statedb.GetBalance(treasury); have.Int64() != tt.treasury {
+			t.Errorf("test %d: treasury balance mismatch: have %v, want %d", i, have, tt.treasury)
+		}
+		for _, signer := range signers {
+			if have := statedb.GetBalance(signer); have.Int64() != tt.signer {
+				t.Errorf("test %d: signer %x balance mismatch: have %v, want %d", i, signer, have, tt.signer)
+			}
+		}
+	}
+}
+
+func TestCertificateSigners(t *testing.T) {
+	keys, validators := testValidators(4)
+	hash := common.HexToHash("0x01")
+
+	pls := decodeCertificate(t, testCertificate(keys[:3], 4, 1, hash))
+	signers := certificateSigners(pls, nil)
+	if len(signers) != 3 {
+		t.Fatalf("signer count mismatch: have %d, want 3", len(signers))
+	}
+	for i, signer := range signers {
+		if !containsAddress(validators[:3], signer) {
+			t.Errorf("signer %d: %x not in certificate", i, signer)
+		}
+		if i > 0 && !addressesAscending(signers).Less(i-1, i) {
+			t.Errorf("signer %d: not sorted", i)
+		}
+	}
+}
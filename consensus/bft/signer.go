@@ -0,0 +1,320 @@
+package bft
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Kinds of consensus messages told to signers. Signing two different proposals,
+// proposal headers, votes or precommits for the same height and round is
+// equivocation, which external signers refuse.
+const (
+	SignProposal          = "proposal"          // Block proposals
+	SignVotingInstruction = "votingInstruction" // Voting instructions, proposals of locked blocks
+	SignProposalHeader    = "proposalHeader"    // Part sets of block proposals
+	SignVote              = "vote"              // Prevotes
+	SignPrecommit         = "precommit"         // Precommit votes
+	SignLockset           = "lockset"           // Locksets
+	SignPrecommitLockset  = "precommitLockset"  // Precommit locksets
+	SignReady             = "ready"             // Ready announcements
+)
+
+var (
+	errBelowWatermark = errors.New("consensus message below signing watermark")
+	errDoubleSign     = errors.New("conflicting consensus message already signed")
+	errUnknownKind    = errors.New("unknown consensus message kind")
+)
+
+// SignRequest describes a consensus message to sign. Signers decode the message
+// and derive the signature hash, height and round themselves, so that a node
+// cannot get a hash signed for other fields than it claims.
+type SignRequest struct {
+	Kind    string         `json:"kind"`             // Kind of the message
+	Message hexutil.Bytes  `json:"message"`          // RLP encoding of the message
+	Domain  *btypes.Domain `json:"domain,omitempty"` // Signature domain of the network
+}
+
+// NewSignRequest creates the request to sign a consensus message of the given
+// kind under the signature domain d.
+func NewSignRequest(kind string, msg interface{}, d *btypes.Domain) (*SignRequest, error) {
+	blob, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return nil, err
+	}
+	return &SignRequest{Kind: kind, Message: blob, Domain: d}, nil
+}
+
+// decode decodes the message of the request, returning its signature hash and
+// the height and round it is watermarked at.
+func (req *SignRequest) decode() (hash common.Hash, height, round uint64, err error) {
+	var msg interface {
+		SigHash(d *btypes.Domain) common.Hash
+	}
+	switch req.Kind {
+	case SignProposal:
+		bp := new(btypes.BlockProposal)
+		if err = rlp.DecodeBytes(req.Message, bp); err == nil {
+			msg, height, round = bp, bp.Height, bp.Round
+		}
+	case SignVotingInstruction:
+		vi := new(btypes.VotingInstruction)
+		if err = rlp.DecodeBytes(req.Message, vi); err == nil {
+			msg, height, round = vi, vi.Height, vi.Round
+		}
+	case SignProposalHeader:
+		header := new(btypes.ProposalHeader)
+		if err = rlp.DecodeBytes(req.Message, header); err == nil {
+			msg, height, round = header, header.Height, header.Round
+		}
+	case SignVote:
+		vote := new(btypes.Vote)
+		if err = rlp.DecodeBytes(req.Message, vote); err == nil {
+			msg, height, round = vote, vote.Height, vote.Round
+		}
+	case SignPrecommit:
+		vote := new(btypes.PrecommitVote)
+		if err = rlp.DecodeBytes(req.Message, vote); err == nil {
+			msg, height, round = vote, vote.Height, vote.Round
+		}
+	case SignLockset:
+		ls := new(btypes.LockSet)
+		if err = rlp.DecodeBytes(req.Message, ls); err == nil {
+			msg = ls
+			if len(ls.Votes) > 0 {
+				height, round = ls.Votes[0].Height, ls.Votes[0].Round
+			}
+		}
+	case SignPrecommitLockset:
+		ls := new(btypes.PrecommitLockSet)
+		if err = rlp.DecodeBytes(req.Message, ls); err == nil {
+			msg = ls
+			if len(ls.PrecommitVotes) > 0 {
+				height, round = ls.PrecommitVotes[0].Height, ls.PrecommitVotes[0].Round
+			}
+		}
+	case SignReady:
+		ready := new(btypes.Ready)
+		if err = rlp.DecodeBytes(req.Message, ready); err == nil {
+			msg = ready
+			if ls := ready.CurrentLockSet; ls != nil && len(ls.Votes) > 0 {
+				height, round = ls.Votes[0].Height, ls.Votes[0].Round
+			}
+		}
+	default:
+		err = errUnknownKind
+	}
+	if err != nil {
+		return common.Hash{}, 0, 0, err
+	}
+	return msg.SigHash(req.Domain), height, round, nil
+}
+
+// ConsensusSigner signs consensus messages on behalf of a validator.
+type ConsensusSigner interface {
+	// Address returns the address of the validator.
+	Address() common.Address
+
+	// SignConsensus returns the 65 byte [R || S || V] signature of the message.
+	SignConsensus(req *SignRequest) ([]byte, error)
+}
+
+// keySigner signs with a private key held in memory, as used by development
+// networks with deterministic validator keys.
+type keySigner struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+}
+
+// NewKeySigner creates a consensus signer from a private key.
+func NewKeySigner(key *ecdsa.PrivateKey) ConsensusSigner {
+	return &keySigner{key: key, addr: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+func (s *keySigner) Address() common.Address { return s.addr }
+
+func (s *keySigner) SignConsensus(req *SignRequest) ([]byte, error) {
+	hash, _, _, err := req.decode()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Sign(hash[:], s.key)
+}
+
+// KeystoreSigner signs with an account of the node's account manager, which
+// must be unlocked.
+type KeystoreSigner struct {
+	wallet  accounts.Wallet
+	account accounts.Account
+}
+
+// NewKeystoreSigner creates a consensus signer for the account of the given
+// address.
+func NewKeystoreSigner(am *accounts.Manager, address common.Address) (*KeystoreSigner, error) {
+	account := accounts.Account{Address: address}
+	wallet, err := am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	return &KeystoreSigner{wallet: wallet, account: account}, nil
+}
+
+func (s *KeystoreSigner) Address() common.Address { return s.account.Address }
+
+func (s *KeystoreSigner) SignConsensus(req *SignRequest) ([]byte, error) {
+	hash, _, _, err := req.decode()
+	if err != nil {
+		return nil, err
+	}
+	return s.wallet.SignHash(s.account, hash[:])
+}
+
+// ExternalSigner signs through the JSON-RPC API of a signer running outside of
+// the node, such as the one served by SignerService.
+type ExternalSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// NewExternalSigner connects to the signer listening at the given IPC path or
+// HTTP endpoint.
+func NewExternalSigner(endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return newExternalSigner(client)
+}
+
+func newExternalSigner(client *rpc.Client) (*ExternalSigner, error) {
+	s := &ExternalSigner{client: client}
+	if err := client.Call(&s.address, "bftsigner_address"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *ExternalSigner) Address() common.Address { return s.address }
+
+func (s *ExternalSigner) SignConsensus(req *SignRequest) ([]byte, error) {
+	var sig hexutil.Bytes
+	if err := s.client.Call(&sig, "bftsigner_signConsensus", req); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+// watermark is the last message of a watermarked kind signed by a SignerService.
+type watermark struct {
+	Height uint64      `json:"height"`
+	Round  uint64      `json:"round"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// SignerService is the RPC service of an external consensus signer. It keeps a
+// high watermark per message kind and refuses to sign messages for earlier
+// rounds, or different proposals and votes for the same round, so that a
+// compromised or misbehaving node cannot make the validator equivocate.
+type SignerService struct {
+	key    *ecdsa.PrivateKey
+	domain *btypes.Domain       // Signature domain of the network signed for
+	path   string               // File persisting the watermarks ("" = memory only)
+	marks  map[string]watermark // Watermarks by message kind
+	lock   sync.Mutex
+}
+
+// NewSignerService creates a signer service for the given key and signature
+// domain, resuming from the watermarks stored at path if any.
+func NewSignerService(key *ecdsa.PrivateKey, path string, domain *btypes.Domain) (*SignerService, error) {
+	s := &SignerService{
+		key:    key,
+		domain: domain,
+		path:   path,
+		marks:  make(map[string]watermark),
+	}
+	if path != "" {
+		blob, err := ioutil.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(blob, &s.marks); err != nil {
+				return nil, err
+			}
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Address returns the address of the validator.
+func (s *SignerService) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+// SignConsensus signs a consensus message unless it conflicts with one signed
+// before. The watermark is persisted before the signature is released.
+func (s *SignerService) SignConsensus(req SignRequest) (hexutil.Bytes, error) {
+	// Sign for the network the service was started for, whatever the node claims
+	req.Domain = s.domain
+
+	hash, height, round, err := req.decode()
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Messages of the genesis are signed afresh on every start
+	if height > 0 {
+		if mark, ok := s.marks[req.Kind]; ok {
+			switch {
+			case height < mark.Height || (height == mark.Height && round < mark.Round):
+				return nil, errBelowWatermark
+			case height == mark.Height && round == mark.Round && hash != mark.Hash && s.exclusive(req.Kind):
+				return nil, errDoubleSign
+			}
+		}
+		s.marks[req.Kind] = watermark{Height: height, Round: round, Hash: hash}
+		if err := s.store(); err != nil {
+			return nil, err
+		}
+	}
+	return crypto.Sign(hash[:], s.key)
+}
+
+// exclusive returns whether a single message of the given kind may be signed per
+// round. Locksets and ready announcements are re-signed as they change.
+func (s *SignerService) exclusive(kind string) bool {
+	switch kind {
+	case SignProposal, SignVotingInstruction, SignProposalHeader, SignVote, SignPrecommit:
+		return true
+	}
+	return false
+}
+
+// store persists the watermarks, if a file was given.
+func (s *SignerService) store() error {
+	if s.path == "" {
+		return nil
+	}
+	blob, err := json.Marshal(s.marks)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,118 @@
+package bft
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that the external signer refuses to sign conflicting or outdated
+// messages, also after a restart, and signs under its own signature domain.
+func TestExternalSignerWatermarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bftsigner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := crypto.GenerateKey()
+	path := filepath.Join(dir, "watermark")
+	domain := &btypes.Domain{ChainID: big.NewInt(1), Genesis: common.HexToHash("0xaa"), Block: big.NewInt(0)}
+
+	start := func() *ExternalSigner {
+		service, err := NewSignerService(key, path, domain)
+		if err != nil {
+			t.Fatalf("failed to create signer service: %v", err)
+		}
+		server := rpc.NewServer()
+		if err := server.RegisterName("bftsigner", service); err != nil {
+			t.Fatalf("failed to register signer service: %v", err)
+		}
+		signer, err := newExternalSigner(rpc.DialInProc(server))
+		if err != nil {
+			t.Fatalf("failed to connect to signer: %v", err)
+		}
+		return signer
+	}
+	signer := start()
+	if signer.Address() != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("signer address mismatch: have %x, want %x", signer.Address(), crypto.PubkeyToAddress(key.PublicKey))
+	}
+	sign := func(kind string, height, round uint64, hash common.Hash) error {
+		var msg interface {
+			SigHash(d *btypes.Domain) common.Hash
+		}
+		vote := btypes.NewVote(height, round, hash, 1)
+		vote.Sign(key, domain)
+		lockset := btypes.NewLockSet(1, btypes.Votes{vote})
+		switch kind {
+		case SignVote:
+			msg = btypes.NewVote(height, round, hash, 1)
+		case SignPrecommit:
+			msg = btypes.NewPrecommitVote(height, round, hash, 1)
+		case SignLockset:
+			msg = lockset
+		case SignReady:
+			msg = btypes.NewReady(0, lockset)
+		}
+		// The node asks for no domain, the service signs under its own
+		req, err := NewSignRequest(kind, msg, nil)
+		if err != nil {
+			t.Fatalf("failed to create sign request: %v", err)
+		}
+		sig, err := signer.SignConsensus(req)
+		if err != nil {
+			return err
+		}
+		sighash := msg.SigHash(domain)
+		pub, err := crypto.SigToPub(sighash[:], sig)
+		if err != nil || crypto.PubkeyToAddress(*pub) != signer.Address() {
+			t.Fatalf("invalid signature for %s at %d/%d", kind, height, round)
+		}
+		return nil
+	}
+	a, b := common.HexToHash("0x01"), common.HexToHash("0x02")
+
+	tests := []struct {
+		kind          string
+		height, round uint64
+		hash          common.Hash
+		fail          bool
+	}{
+		{SignVote, 5, 0, a, false},
+		{SignVote, 5, 0, a, false}, // re-signing the same message is harmless
+		{SignVote, 5, 0, b, true},  // equivocation
+		{SignPrecommit, 5, 0, b, false},
+		{SignVote, 5, 1, b, false},
+		{SignVote, 4, 7, b, true}, // below the watermark
+		{SignLockset, 5, 0, a, false},
+		{SignLockset, 5, 0, b, false}, // locksets change as votes come in
+		{SignLockset, 4, 0, b, true},
+		{SignReady, 5, 1, a, false},
+		{SignReady, 5, 0, a, true},
+	}
+	for i, tt := range tests {
+		err := sign(tt.kind, tt.height, tt.round, tt.hash)
+		if tt.fail && err == nil {
+			t.Errorf("test %d: %s at %d/%d signed", i, tt.kind, tt.height, tt.round)
+		}
+		if !tt.fail && err != nil {
+			t.Errorf("test %d: %s at %d/%d refused: %v", i, tt.kind, tt.height, tt.round, err)
+		}
+	}
+	// The watermarks survive a restart of the signer
+	signer = start()
+	if err := sign(SignVote, 5, 1, a); err == nil {
+		t.Errorf("conflicting vote signed after restart")
+	}
+	if err := sign(SignVote, 6, 0, a); err != nil {
+		t.Errorf("next vote refused after restart: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package bft
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+)
+
+const (
+	// livenessTimeout is the time after which a validator that sent no
+	// consensus message is reported as offline.
+	livenessTimeout = 30 * time.Second
+
+	// participationWindow is the number of recent blocks whose commit
+	// certificates are inspected to compute the validator participation rate.
+	participationWindow = 100
+)
+
+// ValidatorStatus is the liveness and participation summary of a validator.
+type ValidatorStatus struct {
+	Address       common.Address `json:"address"`
+	Alive         bool           `json:"alive"`         // Whether a message was seen within the liveness timeout
+	LastSeen      int64          `json:"lastSeen"`      // Unix time of the last consensus message (0 = never)
+	Participation float64        `json:"participation"` // Fraction of recent commit certificates signed
+}
+
+// Status is a snapshot of the local view of the consensus state.
+type Status struct {
+	Height        uint64             `json:"height"`
+	Round         uint64             `json:"round"`
+	Proposer      common.Address     `json:"proposer"`
+	Validator     bool               `json:"validator"`     // Whether the local node is a validator
+	Ready         bool               `json:"ready"`         // Whether enough validators are ready to start consensus
+	CommitLatency int64              `json:"commitLatency"` // Time to commit the last locally sealed block in milliseconds
+	Validators    []*ValidatorStatus `json:"validators"`
+}
+
+// markSeen records that a consensus message was received from addr.
+func (cm *ConsensusManager) markSeen(addr common.Address) {
+	cm.statsMu.Lock()
+	cm.lastSeen[addr] = time.Now()
+	cm.statsMu.Unlock()
+}
+
+// markCommitted records the commit latency of the given height.
+func (cm *ConsensusManager) markCommitted(height uint64) {
+	cm.statsMu.Lock()
+	defer cm.statsMu.Unlock()
+
+	if cm.startHeight == height && !cm.startTime.IsZero() {
+		cm.commitLatency = time.Since(cm.startTime)
+	}
+}
+
+// participationTracker computes the validator participation rates over a window
+// of recent blocks. The precommit signers of every certified block are recovered
+// once and cached while the block stays in the window.
+type participationTracker struct {
+	window  uint64
+	signers map[common.Hash][]common.Address // Precommit signers of the certified blocks in the window
+	lock    sync.Mutex
+}
+
+// newParticipationTracker creates a tracker over the given number of blocks.
+func newParticipationTracker(window uint64) *participationTracker {
+	return &participationTracker{
+		window:  window,
+		signers: make(map[common.Hash][]common.Address),
+	}
+}
+
+// rates counts, for every validator, the share of the window blocks up to head
+// whose commit certificate carries its precommit vote. The hashes of canonical
+// blocks are retrieved by hashOf, which returns false past the chain, and the
+// certificates of the blocks not cached yet by certOf.
+func (t *participationTracker) rates(head uint64, hashOf func(uint64) (common.Hash, bool), certOf func(common.Hash) *btypes.PrecommitLockSet, d *btypes.Domain) map[common.Address]float64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	from := uint64(1)
+	if head >= t.window {
+		from = head - t.window + 1
+	}
+	var (
+		window = make(map[common.Hash][]common.Address)
+		signed = make(map[common.Address]int)
+	)
+	for n := from; n <= head; n++ {
+		hash, ok := hashOf(n)
+		if !ok {
+			break
+		}
+		signers, ok := t.signers[hash]
+		if !ok {
+			// Blocks without a certificate yet are retried on the next call
+			pls := certOf(hash)
+			if pls == nil {
+				continue
+			}
+			signers = precommitSigners(pls, hash, d)
+		}
+		window[hash] = signers
+		for _, addr := range signers {
+			signed[addr]++
+		}
+	}
+	// Blocks which dropped out of the window, or off the chain, are forgotten
+	t.signers = window
+
+	rates := make(map[common.Address]float64)
+	if len(window) == 0 {
+		return rates
+	}
+	for addr, count := range signed {
+		rates[addr] = float64(count) / float64(len(window))
+	}
+	return rates
+}
+
+// precommitSigners recovers the signers of the precommit votes of a commit
+// certificate which vote for the block of the given hash.
+func precommitSigners(pls *btypes.PrecommitLockSet, hash common.Hash, d *btypes.Domain) []common.Address {
+	signers := []common.Address{}
+	for _, v := range pls.PrecommitVotes {
+		if v.VoteType != 1 || v.Blockhash != hash {
+			continue
+		}
+		if addr, err := v.From(d); err == nil {
+			signers = append(signers, addr)
+		}
+	}
+	return signers
+}
+
+// participation counts, for every validator, the share of the recent blocks
+// whose commit certificate carries its precommit vote.
+func (cm *ConsensusManager) participation() map[common.Address]float64 {
+	hashOf := func(n uint64) (common.Hash, bool) {
+		header := cm.chain.GetHeaderByNumber(n)
+		if header == nil {
+			return common.Hash{}, false
+		}
+		return header.Hash(), true
+	}
+	return cm.participants.rates(cm.Head().NumberU64(), hashOf, cm.loadPrecommitLockset, cm.domain)
+}
+
+// Status assembles a snapshot of the current consensus state.
+func (cm *ConsensusManager) Status() *Status {
+	// Read the active round without creating a height manager for it
+	var round uint64
+	height := cm.Height()
+	cm.getHeightMu.RLock()
+	if hm, ok := cm.heights[height]; ok {
+		round = hm.Round()
+	}
+	cm.getHeightMu.RUnlock()
+
+	status := &Status{
+		Height:    height,
+		Round:     round,
+		Proposer:  cm.contract.proposer(height, round),
+		Validator: cm.contract.isValidators(cm.coinbase),
+		Ready:     cm.isReady(),
+	}
+	rates := cm.participation()
+
+	cm.statsMu.RLock()
+	defer cm.statsMu.RUnlock()
+
+	status.CommitLatency = int64(cm.commitLatency / time.Millisecond)
+	for _, addr := range cm.contract.validators {
+		vs := &ValidatorStatus{
+			Address:       addr,
+			Participation: rates[addr],
+		}
+		if addr == cm.coinbase {
+			vs.Alive, vs.LastSeen = true, time.Now().Unix()
+		} else if seen, ok := cm.lastSeen[addr]; ok {
+			vs.Alive, vs.LastSeen = time.Since(seen) < livenessTimeout, seen.Unix()
+		}
+		status.Validators = append(status.Validators, vs)
+	}
+	return status
+}
@@ -0,0 +1,72 @@
+package bft
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+)
+
+// Tests that the participation rates count the precommit votes of the recent
+// certified blocks and recover every certificate only once.
+func TestParticipation(t *testing.T) {
+	keys, validators := testValidators(4)
+
+	var (
+		hashes = make(map[uint64]common.Hash)
+		certs  = make(map[common.Hash]*btypes.PrecommitLockSet)
+		loads  = make(map[common.Hash]int)
+	)
+	for n := uint64(1); n <= 6; n++ {
+		hash := common.BigToHash(new(big.Int).SetUint64(n))
+		hashes[n] = hash
+
+		// The last validator only signs the even blocks
+		signers := keys[:3]
+		if n%2 == 0 {
+			signers = keys
+		}
+		certs[hash] = testCertificate(signers, 4, n, hash)
+	}
+	hashOf := func(n uint64) (common.Hash, bool) {
+		hash, ok := hashes[n]
+		return hash, ok
+	}
+	certOf := func(hash common.Hash) *btypes.PrecommitLockSet {
+		loads[hash]++
+		return certs[hash]
+	}
+	tracker := newParticipationTracker(4)
+
+	// Blocks 3..6 are in the window, the last validator signed 2 of them
+	for i := 0; i < 2; i++ {
+		rates := tracker.rates(6, hashOf, certOf, nil)
+		if rates[validators[0]] != 1 || rates[validators[3]] != 0.5 {
+			t.Errorf("call %d: rates mismatch: have %v / %v, want 1 / 0.5", i, rates[validators[0]], rates[validators[3]])
+		}
+	}
+	for n := uint64(3); n <= 6; n++ {
+		if loads[hashes[n]] != 1 {
+			t.Errorf("block %d: certificate loaded %d times, want 1", n, loads[hashes[n]])
+		}
+	}
+	// Blocks dropping out of the window are forgotten, missing ones retried
+	hashes[7] = common.BigToHash(big.NewInt(7))
+	delete(certs, hashes[7])
+
+	rates := tracker.rates(7, hashOf, certOf, nil)
+	if rates[validators[3]] != 2.0/3 {
+		t.Errorf("rate with uncertified head mismatch: have %v, want %v", rates[validators[3]], 2.0/3)
+	}
+	if _, ok := tracker.signers[hashes[3]]; ok {
+		t.Errorf("block out of the window still cached")
+	}
+	certs[hashes[7]] = testCertificate(keys[:3], 4, 7, hashes[7])
+	if rates := tracker.rates(7, hashOf, certOf, nil); rates[validators[3]] != 0.5 {
+		t.Errorf("rate with certified head mismatch: have %v, want 0.5", rates[validators[3]])
+	}
+	if loads[hashes[7]] != 2 {
+		t.Errorf("head certificate loaded %d times, want 2", loads[hashes[7]])
+	}
+}
@@ -0,0 +1,75 @@
+package bft
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	errBlockPeriod       = errors.New("timestamp within block period of parent")
+	errInvalidMedianTime = errors.New("timestamp differs from median attested time")
+)
+
+// verifyTime checks the timestamp of a header against its parent's: time must
+// increase with every block, by at least the block period if one is set.
+func verifyTime(config *params.BFTConfig, header, parent *types.Header) error {
+	if header.Time.Cmp(parent.Time) <= 0 {
+		return errZeroBlockTime
+	}
+	if config != nil && config.BlockPeriod > 0 {
+		earliest := new(big.Int).Add(parent.Time, new(big.Int).SetUint64(config.BlockPeriod))
+		if header.Time.Cmp(earliest) < 0 {
+			return errBlockPeriod
+		}
+	}
+	return nil
+}
+
+// attestedTime returns the time a precommit attests for a block with the given
+// timestamp: the local time, but no earlier than the block period after the
+// block, and always later than it. The median of attested times thus always
+// makes a valid timestamp for the successor, also without a block period.
+func attestedTime(config *params.BFTConfig, now, block uint64) uint64 {
+	period := config.BlockPeriod
+	if period == 0 {
+		period = 1
+	}
+	if earliest := block + period; now < earliest {
+		return earliest
+	}
+	return now
+}
+
+// medianTime returns the median of the times the votes of a commit certificate
+// attest for the given block, or false if none attests a time. Of an even number
+// of times the lower median is taken.
+func medianTime(pls *btypes.PrecommitLockSet, hash common.Hash) (uint64, bool) {
+	var times []uint64
+	for _, vote := range pls.PrecommitVotes {
+		if vote.VoteType != 1 || vote.Blockhash != hash {
+			continue
+		}
+		if time, ok := vote.Time(); ok {
+			times = append(times, time)
+		}
+	}
+	if len(times) == 0 {
+		return 0, false
+	}
+	sort.Sort(timesAscending(times))
+	return times[(len(times)-1)/2], true
+}
+
+// timesAscending implements the sort interface to allow sorting a list of unix
+// times.
+type timesAscending []uint64
+
+func (s timesAscending) Len() int           { return len(s) }
+func (s timesAscending) Less(i, j int) bool { return s[i] < s[j] }
+func (s timesAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,92 @@
+package bft
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestVerifyTime(t *testing.T) {
+	parent := &types.Header{Number: big.NewInt(1), Time: big.NewInt(100)}
+
+	tests := []struct {
+		config *params.BFTConfig
+		time   int64
+		err    error
+	}{
+		{nil, 101, nil},
+		{nil, 100, errZeroBlockTime},
+		{nil, 50, errZeroBlockTime},
+		{&params.BFTConfig{BlockPeriod: 5}, 105, nil},
+		{&params.BFTConfig{BlockPeriod: 5}, 104, errBlockPeriod},
+	}
+	for i, tt := range tests {
+		header := &types.Header{Number: big.NewInt(2), Time: big.NewInt(tt.time)}
+		if err := verifyTime(tt.config, header, parent); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that attested times always make a valid successor timestamp, also with
+// a zero block period where the local clock may not have advanced yet.
+func TestAttestedTime(t *testing.T) {
+	tests := []struct {
+		period, now, block, want uint64
+	}{
+		{0, 100, 100, 101},
+		{0, 90, 100, 101},
+		{0, 120, 100, 120},
+		{5, 100, 100, 105},
+		{5, 120, 100, 120},
+	}
+	for i, tt := range tests {
+		config := &params.BFTConfig{BlockPeriod: tt.period}
+		have := attestedTime(config, tt.now, tt.block)
+		if have != tt.want {
+			t.Errorf("test %d: attested time mismatch: have %d, want %d", i, have, tt.want)
+		}
+		parent := &types.Header{Number: big.NewInt(1), Time: new(big.Int).SetUint64(tt.block)}
+		header := &types.Header{Number: big.NewInt(2), Time: new(big.Int).SetUint64(have)}
+		if err := verifyTime(config, header, parent); err != nil {
+			t.Errorf("test %d: attested time rejected: %v", i, err)
+		}
+	}
+}
+
+func TestMedianTime(t *testing.T) {
+	keys, _ := testValidators(5)
+	hash := common.HexToHash("0x01")
+
+	// Certificates without attested times have no median
+	if _, ok := medianTime(decodeCertificate(t, testCertificate(keys, 5, 1, hash)), hash); ok {
+		t.Fatalf("median of unattested certificate")
+	}
+	// Attested times survive encoding and the lower median is taken
+	pls := btypes.NewPrecommitLockSet(5, btypes.PrecommitVotes{})
+	for i, time := range []uint64{130, 100, 120, 110} {
+		vote := btypes.NewPrecommitVote(1, 0, hash, 1)
+		vote.SetTime(time)
+		vote.Sign(keys[i], nil)
+		pls.Add(vote, false)
+	}
+	// Votes for nil do not attest the time of the block
+	vote := btypes.NewPrecommitVote(1, 0, common.Hash{}, 2)
+	vote.SetTime(1000)
+	vote.Sign(keys[4], nil)
+	pls.Add(vote, false)
+
+	pls = decodeCertificate(t, pls)
+	if median, ok := medianTime(pls, hash); !ok || median != 110 {
+		t.Errorf("median mismatch: have %d/%v, want 110/true", median, ok)
+	}
+	for i, vote := range pls.PrecommitVotes {
+		if _, err := vote.From(nil); err != nil {
+			t.Errorf("vote %d: signature invalid after decoding: %v", i, err)
+		}
+	}
+}
@@ -30,6 +30,7 @@ func rlpHash(x interface{}) (h common.Hash) {
 type Vote struct {
 	// signed signed
 	sender    *common.Address
+	domain    *Domain  // Domain the sender was recovered under
 	V         *big.Int // signature
 	R, S      *big.Int // signature
 	Height    uint64
@@ -59,40 +60,45 @@ func (v *Vote) Hash() common.Hash {
 		v.Blockhash,
 	})
 }
-func (v *Vote) SigHash() common.Hash {
-	return rlpHash([]interface{}{
+func (v *Vote) SigHash(d *Domain) common.Hash {
+	return sigHash(d, kindVote, v.Height,
 		v.Height,
 		v.Round,
 		v.VoteType,
 		v.Blockhash,
-	})
+	)
 }
-func (v *Vote) Sign(prv *ecdsa.PrivateKey) error {
+func (v *Vote) Sign(prv *ecdsa.PrivateKey, d *Domain) error {
 	if v.V != nil {
 		return errors.New("already sign")
 	}
-	_, err := v.SignECDSA(prv, v.SigHash())
+	_, err := v.SignECDSA(prv, v.SigHash(d))
 	if err != nil {
 		return err
 	}
+	// Cache the signer, so that the vote can be added to locksets right away
+	if _, err := v.From(d); err != nil {
+		return err
+	}
 	return nil
 }
 func (vote *Vote) hr() (uint64, uint64) {
 	return vote.Height, vote.Round
 }
-func (v *Vote) From() (common.Address, error) {
-	if v.sender != nil {
+func (v *Vote) From(d *Domain) (common.Address, error) {
+	if v.sender != nil && v.domain.same(d) {
 		return *v.sender, nil
 	} else {
 		if v.V == nil {
 			return common.Address{}, errors.New("no signature")
 		}
-		addr, err := v.recoverSender(v.SigHash())
+		addr, err := v.recoverSender(v.SigHash(d))
 		if err != nil {
 			log.Error("sender() error ", err)
 			return common.Address{}, err
 		}
 		v.sender = &addr
+		v.domain = d
 		return addr, nil
 
 	}
@@ -139,7 +145,7 @@ func (vote *Vote) publicKey(hash common.Hash) ([]byte, error) {
 //sign
 func (vote *Vote) WithSignature(sig []byte) (*Vote, error) {
 	if len(sig) != 65 {
-		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
+		return nil, fmt.Errorf("wrong size for signature: got %d, want 65", len(sig))
 	}
 	vote.R = new(big.Int).SetBytes(sig[:32])
 	vote.S = new(big.Int).SetBytes(sig[32:64])
@@ -159,12 +165,14 @@ func (vote *Vote) SignECDSA(prv *ecdsa.PrivateKey, hash common.Hash) (*Vote, err
 type PrecommitVote struct {
 	// signed signed
 	sender    *common.Address
+	domain    *Domain  // Domain the sender was recovered under
 	V         *big.Int // signature
 	R, S      *big.Int // signature
 	Height    uint64
 	Round     uint64
 	Blockhash common.Hash
-	VoteType  uint64 // 1: voteblock , 2: votenil
+	VoteType  uint64   // 1: voteblock , 2: votenil
+	Timestamp []uint64 `rlp:"tail"` // attested unix time, if any
 }
 type PrecommitVotes []*PrecommitVote
 
@@ -180,48 +188,76 @@ func NewPrecommitVote(height uint64, round uint64, blockhash common.Hash, voteTy
 }
 
 func (v *PrecommitVote) Hash() common.Hash {
-	return rlpHash([]interface{}{
+	fields := []interface{}{
 		v.sender,
 		v.Height,
 		v.Round,
 		v.VoteType,
 		v.Blockhash,
-	})
+	}
+	if len(v.Timestamp) > 0 {
+		fields = append(fields, v.Timestamp)
+	}
+	return rlpHash(fields)
 }
-func (v *PrecommitVote) SigHash() common.Hash {
-	return rlpHash([]interface{}{
+func (v *PrecommitVote) SigHash(d *Domain) common.Hash {
+	fields := []interface{}{
 		v.Height,
 		v.Round,
 		v.VoteType,
 		v.Blockhash,
-	})
+	}
+	// Votes without a time keep the signatures of earlier releases valid
+	if len(v.Timestamp) > 0 {
+		fields = append(fields, v.Timestamp)
+	}
+	return sigHash(d, kindPrecommitVote, v.Height, fields...)
+}
+
+// SetTime attests the given unix time with the vote. It must be called before
+// the vote is signed.
+func (v *PrecommitVote) SetTime(time uint64) {
+	v.Timestamp = []uint64{time}
+}
+
+// Time returns the unix time attested with the vote, if any.
+func (v *PrecommitVote) Time() (uint64, bool) {
+	if len(v.Timestamp) != 1 {
+		return 0, false
+	}
+	return v.Timestamp[0], true
 }
-func (v *PrecommitVote) Sign(prv *ecdsa.PrivateKey) error {
+func (v *PrecommitVote) Sign(prv *ecdsa.PrivateKey, d *Domain) error {
 	if v.V != nil {
 		return errors.New("already sign")
 	}
-	_, err := v.SignECDSA(prv, v.SigHash())
+	_, err := v.SignECDSA(prv, v.SigHash(d))
 	if err != nil {
 		return err
 	}
+	// Cache the signer, so that the vote can be added to locksets right away
+	if _, err := v.From(d); err != nil {
+		return err
+	}
 	return nil
 }
 func (vote *PrecommitVote) hr() (uint64, uint64) {
 	return vote.Height, vote.Round
 }
-func (v *PrecommitVote) From() (common.Address, error) {
-	if v.sender != nil {
+func (v *PrecommitVote) From(d *Domain) (common.Address, error) {
+	if v.sender != nil && v.domain.same(d) {
 		return *v.sender, nil
 	} else {
 		if v.V == nil {
 			return common.Address{}, errors.New("no signature")
 		}
-		addr, err := v.recoverSender(v.SigHash())
+		addr, err := v.recoverSender(v.SigHash(d))
 		if err != nil {
 			log.Error("sender() error ", err)
 			return common.Address{}, err
 		}
 		v.sender = &addr
+		v.domain = d
 		return addr, nil
 
 	}
@@ -268,7 +304,7 @@ func (vote *PrecommitVote) publicKey(hash common.Hash) ([]byte, error) {
 //sign
 func (vote *PrecommitVote) WithSignature(sig []byte) (*PrecommitVote, error) {
 	if len(sig) != 65 {
-		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
+		return nil, fmt.Errorf("wrong size for signature: got %d, want 65", len(sig))
 	}
 	vote.R = new(big.Int).SetBytes(sig[:32])
 	vote.S = new(big.Int).SetBytes(sig[32:64])
@@ -288,6 +324,7 @@ func (vote *PrecommitVote) SignECDSA(prv *ecdsa.PrivateKey, hash common.Hash) (*
 type LockSet struct {
 	// signed           signed
 	sender           *common.Address
+	domain           *Domain  // Domain the sender was recovered under
 	V                *big.Int // signature
 	R, S             *big.Int // signature
 	EligibleVotesNum uint64
@@ -357,19 +394,20 @@ func (lockset *LockSet) hr() (uint64, uint64) {
 	}
 	return lockset.Votes[0].Height, lockset.Votes[0].Round
 }
-func (lockset *LockSet) From() (common.Address, error) {
-	if lockset.sender != nil {
+func (lockset *LockSet) From(d *Domain) (common.Address, error) {
+	if lockset.sender != nil && lockset.domain.same(d) {
 		return *lockset.sender, nil
 	} else {
 		if lockset.V == nil {
 			return common.Address{}, errors.New("no signature")
 		}
-		addr, err := lockset.recoverSender(lockset.SigHash())
+		addr, err := lockset.recoverSender(lockset.SigHash(d))
 		if err != nil {
 			log.Error("sender() error ", err)
 			return common.Address{}, err
 		}
 		lockset.sender = &addr
+		lockset.domain = d
 		return addr, nil
 	}
 }
@@ -389,17 +427,26 @@ func (lockset *LockSet) Round() uint64 {
 	_, r := lockset.hr()
 	return r
 }
-func (lockset *LockSet) SigHash() common.Hash {
-	return rlpHash([]interface{}{
+func (lockset *LockSet) SigHash(d *Domain) common.Hash {
+	return sigHash(d, kindLockSet, lockset.signedHeight(),
 		lockset.EligibleVotesNum,
 		lockset.Votes,
-	})
+	)
 }
-func (lockset *LockSet) Sign(prv *ecdsa.PrivateKey) error {
+
+// signedHeight returns the height the lockset signature is bound to, which
+// is zero for locksets without votes.
+func (lockset *LockSet) signedHeight() uint64 {
+	if lockset == nil || len(lockset.Votes) == 0 {
+		return 0
+	}
+	return lockset.Votes[0].Height
+}
+func (lockset *LockSet) Sign(prv *ecdsa.PrivateKey, d *Domain) error {
 	if lockset.V != nil {
 		return errors.New("already sign")
 	}
-	_, err := lockset.SignECDSA(prv, lockset.SigHash())
+	_, err := lockset.SignECDSA(prv, lockset.SigHash(d))
 	if err != nil {
 		return err
 	}
@@ -409,12 +456,12 @@ func (lockset *LockSet) Sign(prv *ecdsa.PrivateKey) error {
 var ErrInvalidVote = errors.New("inconsistent height, round")
 var ErrInvalidVoteSig = errors.New("no signature")
 var ErrDoubleVoting = errors.New("different votes on the same H,R")
+var ErrInvalidSigner = errors.New("vote signer is not a validator")
 
 func (lockset *LockSet) Add(vote *Vote, force bool) error {
-	// log.Info(*vote.signed.sender)
-	vote.From()
+	// The signer must have been recovered with From under the domain of the lockset
 	if vote.sender == nil {
-		log.Error("Could not get pubkey from signature: ", ErrInvalidVote)
+		log.Error("Vote signer not recovered", "err", ErrInvalidVote)
 		return ErrInvalidVote
 	}
 
@@ -459,11 +506,12 @@ func (lockset *LockSet) Contain(vote *Vote) bool {
 }
 
 func containsVote(s []*Vote, e *Vote) bool {
-	addr, _ := e.From()
+	if e.sender == nil {
+		return false
+	}
 	for _, a := range s {
 		if a.Height == e.Height && a.Round == e.Round && a.Blockhash == e.Blockhash && a.VoteType == e.VoteType {
-			addr2, _ := a.From()
-			if addr == addr2 {
+			if a.sender != nil && *a.sender == *e.sender {
 				return true
 			}
 		}
@@ -508,31 +556,40 @@ func (lockset *LockSet) NoQuorum() bool {
 	}
 }
 
-// func (lockset *LockSet) QuorumPossible() (bool, common.Hash) {
-
-// 	if result, hs := lockset.HasQuorum(); result != false {
-// 		return false, hs
-// 	}
-// 	if !lockset.IsValid() {
-// 		return false, common.Hash{}
-// 	}
-// 	hs := lockset.sortByBlockhash()
-// 	if len(hs) == 0 {
-// 		return false, common.Hash{}
-// 	}
-// 	if float64(hs[0].count) > 1/3.*float64(lockset.EligibleVotesNum) {
-// 		return true, hs[0].blockhash
-// 	} else {
-// 		return false, common.Hash{}
-// 	}
-// }
-func checkVotes(lockset *LockSet, validators []common.Address) error {
+// QuorumPossible reports whether a valid lockset without quorum has more than a
+// third of the eligible votes for one block, which may thus still be committed.
+func (lockset *LockSet) QuorumPossible() (bool, common.Hash) {
+	if result, hs := lockset.HasQuorum(); result != false {
+		return false, hs
+	}
+	if !lockset.IsValid() {
+		return false, common.Hash{}
+	}
+	hs := lockset.sortByBlockhash()
+	if len(hs) == 0 {
+		return false, common.Hash{}
+	}
+	if float64(hs[0].count) > 1/3.*float64(lockset.EligibleVotesNum) {
+		return true, hs[0].blockhash
+	} else {
+		return false, common.Hash{}
+	}
+}
+
+// checkVotes verifies that the lockset counts the votes of the given validators
+// and that every vote is signed by one of them in domain d. Votes of signers
+// outside of the validator set would otherwise count towards a quorum.
+func checkVotes(lockset *LockSet, validators []common.Address, d *Domain) error {
 	if int(lockset.EligibleVotesNum) != len(validators) {
 		return errors.New("lockset EligibleVotesNum mismatch")
 	}
 	for _, v := range lockset.Votes {
-		if containsAddress(validators, *v.sender) {
-			errors.New("invalid signer")
+		signer, err := v.From(d)
+		if err != nil {
+			return err
+		}
+		if !containsAddress(validators, signer) {
+			return ErrInvalidSigner
 		}
 	}
 	return nil
@@ -580,7 +637,7 @@ func (lockset *LockSet) publicKey(hash common.Hash) ([]byte, error) {
 //sign
 func (lockset *LockSet) WithSignature(sig []byte) (*LockSet, error) {
 	if len(sig) != 65 {
-		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
+		return nil, fmt.Errorf("wrong size for signature: got %d, want 65", len(sig))
 	}
 	lockset.R = new(big.Int).SetBytes(sig[:32])
 	lockset.S = new(big.Int).SetBytes(sig[32:64])
@@ -603,9 +660,9 @@ func (lockset *LockSet) SignECDSA(prv *ecdsa.PrivateKey, hash common.Hash) (*Loc
 
 /////////////////////////////////////////////
 
-func GenesisSigningLockset(genesis *types.Block, prv *ecdsa.PrivateKey) *LockSet {
+func GenesisSigningLockset(genesis *types.Block, prv *ecdsa.PrivateKey, d *Domain) *LockSet {
 	v := NewVote(0, 0, genesis.Hash(), 1)
-	v.Sign(prv)
+	v.Sign(prv, d)
 	ls := NewLockSet(1, nil)
 	ls.Add(v, false)
 	if result, _ := ls.HasQuorum(); result == false {
@@ -617,6 +674,7 @@ func GenesisSigningLockset(genesis *types.Block, prv *ecdsa.PrivateKey) *LockSet
 type PrecommitLockSet struct {
 	// signed           signed
 	sender           *common.Address
+	domain           *Domain  // Domain the sender was recovered under
 	V                *big.Int // signature
 	R, S             *big.Int // signature
 	EligibleVotesNum uint64
@@ -674,19 +732,20 @@ func (lockset *PrecommitLockSet) hr() (uint64, uint64) {
 	}
 	return lockset.PrecommitVotes[0].Height, lockset.PrecommitVotes[0].Round
 }
-func (lockset *PrecommitLockSet) From() (common.Address, error) {
-	if lockset.sender != nil {
+func (lockset *PrecommitLockSet) From(d *Domain) (common.Address, error) {
+	if lockset.sender != nil && lockset.domain.same(d) {
 		return *lockset.sender, nil
 	} else {
 		if lockset.V == nil {
 			return common.Address{}, errors.New("no signature")
 		}
-		addr, err := lockset.recoverSender(lockset.SigHash())
+		addr, err := lockset.recoverSender(lockset.SigHash(d))
 		if err != nil {
 			log.Error("sender() error ", err)
 			return common.Address{}, err
 		}
 		lockset.sender = &addr
+		lockset.domain = d
 		return addr, nil
 	}
 }
@@ -705,27 +764,35 @@ func (lockset *PrecommitLockSet) Round() uint64 {
 	_, r := lockset.hr()
 	return r
 }
-func (lockset *PrecommitLockSet) SigHash() common.Hash {
-	return rlpHash([]interface{}{
+func (lockset *PrecommitLockSet) SigHash(d *Domain) common.Hash {
+	return sigHash(d, kindPrecommitLockSet, lockset.signedHeight(),
 		lockset.EligibleVotesNum,
 		lockset.PrecommitVotes,
-	})
+	)
+}
+
+// signedHeight returns the height the lockset signature is bound to, which
+// is zero for locksets without votes.
+func (lockset *PrecommitLockSet) signedHeight() uint64 {
+	if len(lockset.PrecommitVotes) == 0 {
+		return 0
+	}
+	return lockset.PrecommitVotes[0].Height
 }
-func (lockset *PrecommitLockSet) Sign(prv *ecdsa.PrivateKey) error {
+func (lockset *PrecommitLockSet) Sign(prv *ecdsa.PrivateKey, d *Domain) error {
 	if lockset.V != nil {
 		return errors.New("already sign")
 	}
-	_, err := lockset.SignECDSA(prv, lockset.SigHash())
+	_, err := lockset.SignECDSA(prv, lockset.SigHash(d))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 func (lockset *PrecommitLockSet) Add(vote *PrecommitVote, force bool) error {
-	// log.Info(*vote.signed.sender)
-	vote.From()
+	// The signer must have been recovered with From under the domain of the lockset
 	if vote.sender == nil {
-		log.Error("Could not get pubkey from signature: ", ErrInvalidVote)
+		log.Error("Vote signer not recovered", "err", ErrInvalidVote)
 		return ErrInvalidVote
 	}
 
@@ -771,11 +838,12 @@ func (lockset *PrecommitLockSet) Contain(vote *PrecommitVote) bool {
 }
 
 func containsPrecommitVote(s []*PrecommitVote, e *PrecommitVote) bool {
-	addr, _ := e.From()
+	if e.sender == nil {
+		return false
+	}
 	for _, a := range s {
 		if a.Height == e.Height && a.Round == e.Round && a.Blockhash == e.Blockhash && a.VoteType == e.VoteType {
-			addr2, _ := a.From()
-			if addr == addr2 {
+			if a.sender != nil && *a.sender == *e.sender {
 				return true
 			}
 		}
@@ -817,13 +885,20 @@ func (lockset *PrecommitLockSet) NoQuorum() bool {
 	}
 
 }
-func checkPrecommitVotes(lockset *PrecommitLockSet, validators []common.Address) error {
+
+// checkPrecommitVotes verifies that the lockset counts the precommit votes of
+// the given validators and that every vote is signed by one of them in domain d.
+func checkPrecommitVotes(lockset *PrecommitLockSet, validators []common.Address, d *Domain) error {
 	if int(lockset.EligibleVotesNum) != len(validators) {
 		return errors.New("lockset EligibleVotesNum mismatch")
 	}
 	for _, v := range lockset.PrecommitVotes {
-		if containsAddress(validators, *v.sender) {
-			errors.New("invalid signer")
+		signer, err := v.From(d)
+		if err != nil {
+			return err
+		}
+		if !containsAddress(validators, signer) {
+			return ErrInvalidSigner
 		}
 	}
 	return nil
@@ -871,7 +946,7 @@ func (lockset *PrecommitLockSet) publicKey(hash common.Hash) ([]byte, error) {
 //sign
 func (lockset *PrecommitLockSet) WithSignature(sig []byte) (*PrecommitLockSet, error) {
 	if len(sig) != 65 {
-		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
+		return nil, fmt.Errorf("wrong size for signature: got %d, want 65", len(sig))
 	}
 	lockset.R = new(big.Int).SetBytes(sig[:32])
 	lockset.S = new(big.Int).SetBytes(sig[32:64])
@@ -891,6 +966,7 @@ func (lockset *PrecommitLockSet) SignECDSA(prv *ecdsa.PrivateKey, hash common.Ha
 type Ready struct {
 	// signed         signed
 	sender         *common.Address
+	domain         *Domain  // Domain the sender was recovered under
 	V              *big.Int // signature
 	R, S           *big.Int // signature
 	Nonce          uint64
@@ -912,32 +988,33 @@ func (r *Ready) Hash() common.Hash {
 		r.CurrentLockSet,
 	})
 }
-func (r *Ready) SigHash() common.Hash {
-	return rlpHash([]interface{}{
+func (r *Ready) SigHash(d *Domain) common.Hash {
+	return sigHash(d, kindReady, r.CurrentLockSet.signedHeight(),
 		r.Nonce,
 		r.CurrentLockSet,
-	})
+	)
 }
-func (r *Ready) From() (common.Address, error) {
-	if r.sender != nil {
+func (r *Ready) From(d *Domain) (common.Address, error) {
+	if r.sender != nil && r.domain.same(d) {
 		return *r.sender, nil
 	} else {
 		if r.V == nil {
 			return common.Address{}, errors.New("no signature")
 		}
-		addr, err := r.recoverSender(r.SigHash())
+		addr, err := r.recoverSender(r.SigHash(d))
 		if err != nil {
 			return common.Address{}, err
 		}
 		r.sender = &addr
+		r.domain = d
 		return addr, nil
 	}
 }
-func (r *Ready) Sign(prv *ecdsa.PrivateKey) error {
+func (r *Ready) Sign(prv *ecdsa.PrivateKey, d *Domain) error {
 	if r.V != nil {
 		return errors.New("already sign")
 	}
-	_, err := r.SignECDSA(prv, r.SigHash())
+	_, err := r.SignECDSA(prv, r.SigHash(d))
 	if err != nil {
 		return err
 	}
@@ -986,7 +1063,7 @@ func (ready *Ready) publicKey(hash common.Hash) ([]byte, error) {
 //sign
 func (r *Ready) WithSignature(sig []byte) (*Ready, error) {
 	if len(sig) != 65 {
-		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
+		return nil, fmt.Errorf("wrong size for signature: got %d, want 65", len(sig))
 	}
 	r.R = new(big.Int).SetBytes(sig[:32])
 	r.S = new(big.Int).SetBytes(sig[32:64])
@@ -1004,8 +1081,8 @@ func (r *Ready) SignECDSA(prv *ecdsa.PrivateKey, hash common.Hash) (*Ready, erro
 }
 
 type Proposal interface {
-	Sign(prv *ecdsa.PrivateKey) error
-	From() (common.Address, error)
+	Sign(prv *ecdsa.PrivateKey, d *Domain) error
+	From(d *Domain) (common.Address, error)
 	GetHeight() uint64
 	GetRound() uint64
 	Blockhash() common.Hash
@@ -1014,6 +1091,7 @@ type Proposal interface {
 type BlockProposal struct {
 	// signed signed
 	sender         *common.Address
+	domain         *Domain  // Domain the sender was recovered under
 	V              *big.Int // signature
 	R, S           *big.Int // signature
 	Height         uint64
@@ -1075,8 +1153,8 @@ func NewBlockProposal(height uint64, round uint64, block *types.Block, signingLo
 
 func (bp *BlockProposal) GetHeight() uint64 { return bp.Height }
 func (bp *BlockProposal) GetRound() uint64  { return bp.Round }
-func (bp *BlockProposal) From() (common.Address, error) {
-	if bp.sender != nil {
+func (bp *BlockProposal) From(d *Domain) (common.Address, error) {
+	if bp.sender != nil && bp.domain.same(d) {
 		if *bp.sender != bp.Block.Coinbase() {
 			return common.Address{}, errors.New("signature does not match")
 
@@ -1086,7 +1164,7 @@ func (bp *BlockProposal) From() (common.Address, error) {
 		if bp.V == nil {
 			return common.Address{}, errors.New("no signature")
 		}
-		addr, err := bp.recoverSender(bp.SigHash())
+		addr, err := bp.recoverSender(bp.SigHash(d))
 		if err != nil {
 			return common.Address{}, err
 		}
@@ -1094,6 +1172,7 @@ func (bp *BlockProposal) From() (common.Address, error) {
 			return common.Address{}, errors.New("signature does not match")
 		}
 		bp.sender = &addr
+		bp.domain = d
 		return addr, nil
 	}
 }
@@ -1107,43 +1186,43 @@ func (bp *BlockProposal) Hash() common.Hash {
 		bp.RoundLockset,
 	})
 }
-func (bp *BlockProposal) SigHash() common.Hash {
-	return rlpHash([]interface{}{
+func (bp *BlockProposal) SigHash(d *Domain) common.Hash {
+	return sigHash(d, kindBlockProposal, bp.Height,
 		bp.Height,
 		bp.Round,
 		bp.Block,
 		bp.SigningLockset,
 		bp.RoundLockset,
-	})
+	)
 }
 
 // func (bp *BlockProposal) SigningLockset() *LockSet { return bp.SigningLockset }
 func (bp *BlockProposal) Blockhash() common.Hash { return bp.Block.Hash() }
 func (bp *BlockProposal) LockSet() *LockSet      { return bp.RoundLockset }
 
-func (bp *BlockProposal) Sign(prv *ecdsa.PrivateKey) error {
+func (bp *BlockProposal) Sign(prv *ecdsa.PrivateKey, d *Domain) error {
 	if bp.V != nil {
 		return errors.New("already sign")
 	}
-	_, err := bp.SignECDSA(prv, bp.SigHash())
+	_, err := bp.SignECDSA(prv, bp.SigHash(d))
 	if err != nil {
 		return err
 	}
-	if _, err := bp.From(); err != nil {
+	if _, err := bp.From(d); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (bp *BlockProposal) ValidateVotes(validators_H []common.Address, validators_prevH []common.Address) error {
-	if _, err := bp.From(); err != nil {
+func (bp *BlockProposal) ValidateVotes(validators_H []common.Address, validators_prevH []common.Address, d *Domain) error {
+	if _, err := bp.From(d); err != nil {
 		return err
 	}
 
 	if bp.RoundLockset != nil && bp.RoundLockset.EligibleVotesNum != 0 {
-		return checkVotes(bp.RoundLockset, validators_H)
+		return checkVotes(bp.RoundLockset, validators_H, d)
 	}
-	return checkPrecommitVotes(bp.SigningLockset, validators_prevH)
+	return checkPrecommitVotes(bp.SigningLockset, validators_prevH, d)
 
 }
 
@@ -1200,7 +1279,7 @@ func (bp *BlockProposal) publicKey(hash common.Hash) ([]byte, error) {
 //sign
 func (bp *BlockProposal) WithSignature(sig []byte) (*BlockProposal, error) {
 	if len(sig) != 65 {
-		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
+		return nil, fmt.Errorf("wrong size for signature: got %d, want 65", len(sig))
 	}
 	bp.R = new(big.Int).SetBytes(sig[:32])
 	bp.S = new(big.Int).SetBytes(sig[32:64])
@@ -1220,6 +1299,7 @@ func (bp *BlockProposal) SignECDSA(prv *ecdsa.PrivateKey, hash common.Hash) (*Bl
 type VotingInstruction struct {
 	// signed signed
 	sender       *common.Address
+	domain       *Domain  // Domain the sender was recovered under
 	V            *big.Int // signature
 	R, S         *big.Int // signature
 	Height       uint64
@@ -1254,18 +1334,19 @@ func NewVotingInstruction(height uint64, round uint64, roundLockset *LockSet) (*
 func (vi *VotingInstruction) GetHeight() uint64 { return vi.Height }
 func (vi *VotingInstruction) GetRound() uint64  { return vi.Round }
 
-func (vi *VotingInstruction) From() (common.Address, error) {
-	if vi.sender != nil {
+func (vi *VotingInstruction) From(d *Domain) (common.Address, error) {
+	if vi.sender != nil && vi.domain.same(d) {
 		return *vi.sender, nil
 	} else {
 		if vi.V == nil {
 			return common.Address{}, errors.New("no signature")
 		}
-		addr, err := vi.recoverSender(vi.SigHash())
+		addr, err := vi.recoverSender(vi.SigHash(d))
 		if err != nil {
 			return common.Address{}, err
 		}
 		vi.sender = &addr
+		vi.domain = d
 		return addr, nil
 	}
 }
@@ -1277,42 +1358,33 @@ func (vi *VotingInstruction) Hash() common.Hash {
 		vi.RoundLockset,
 	})
 }
-func (vi *VotingInstruction) SigHash() common.Hash {
-	return rlpHash([]interface{}{
+func (vi *VotingInstruction) SigHash(d *Domain) common.Hash {
+	return sigHash(d, kindVotingInstruction, vi.Height,
 		vi.Height,
 		vi.Round,
 		vi.RoundLockset,
-	})
+	)
 }
 func (vi *VotingInstruction) Blockhash() common.Hash {
 	_, hash := vi.RoundLockset.HasQuorum()
 	return hash
 }
 func (vi *VotingInstruction) LockSet() *LockSet { return vi.RoundLockset }
-func (vi *VotingInstruction) ValidateVotes(validators []common.Address) error {
-	if _, err := vi.From(); err != nil {
+func (vi *VotingInstruction) ValidateVotes(validators []common.Address, d *Domain) error {
+	if _, err := vi.From(d); err != nil {
 		return err
 	}
-
-	if int(vi.RoundLockset.EligibleVotesNum) != len(validators) {
-		return errors.New("roundLockset EligibleVotes mismatch")
-	}
-	for _, v := range vi.RoundLockset.Votes {
-		if containsAddress(validators, *v.sender) {
-			return errors.New("invalid signer")
-		}
-	}
-	return nil
+	return checkVotes(vi.RoundLockset, validators, d)
 }
-func (vi *VotingInstruction) Sign(prv *ecdsa.PrivateKey) error {
+func (vi *VotingInstruction) Sign(prv *ecdsa.PrivateKey, d *Domain) error {
 	if vi.V != nil {
 		return errors.New("already sign")
 	}
-	_, err := vi.SignECDSA(prv, vi.SigHash())
+	_, err := vi.SignECDSA(prv, vi.SigHash(d))
 	if err != nil {
 		return err
 	}
-	if _, err := vi.From(); err != nil {
+	if _, err := vi.From(d); err != nil {
 		return err
 	}
 	return nil
@@ -1360,7 +1432,7 @@ func (vi *VotingInstruction) publicKey(hash common.Hash) ([]byte, error) {
 //sign
 func (vi *VotingInstruction) WithSignature(sig []byte) (*VotingInstruction, error) {
 	if len(sig) != 65 {
-		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
+		return nil, fmt.Errorf("wrong size for signature: got %d, want 65", len(sig))
 	}
 	vi.R = new(big.Int).SetBytes(sig[:32])
 	vi.S = new(big.Int).SetBytes(sig[32:64])
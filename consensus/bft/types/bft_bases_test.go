@@ -11,8 +11,17 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
+// makePrivateKey derives a deterministic test key from a seed.
+func makePrivateKey(seed string) *ecdsa.PrivateKey {
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte(seed)))
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
 func TestVote(t *testing.T) {
-	key1 := crypto.MakePrivatekey("1")
+	key1 := makePrivateKey("1")
 
 	height := uint64(2)
 	round := uint64(3)
@@ -22,10 +31,10 @@ func TestVote(t *testing.T) {
 	v1 := NewVote(height, round, common.Hash{}, 2)
 	v2 := NewVote(height, round, bh, 1)
 
-	if err := v1.Sign(key1); err != nil {
+	if err := v1.Sign(key1, nil); err != nil {
 		t.Error(err)
 	}
-	if addr, err := v1.From(); err == nil {
+	if addr, err := v1.From(nil); err == nil {
 		if addr != sender {
 			t.Error("derived address doesn't match")
 		}
@@ -33,8 +42,8 @@ func TestVote(t *testing.T) {
 		t.Error(err)
 	}
 
-	v2.Sign(key1)
-	if addr, err := v2.From(); err == nil {
+	v2.Sign(key1, nil)
+	if addr, err := v2.From(nil); err == nil {
 		if addr != sender {
 			t.Error("derived address doesn't match")
 		}
@@ -65,23 +74,23 @@ func TestVote(t *testing.T) {
 }
 
 func TestReady(t *testing.T) {
-	key1 := crypto.MakePrivatekey("1")
+	key1 := makePrivateKey("1")
 
 	ls := NewLockSet(10, nil)
 	s := NewReady(0, ls)
 	if s.CurrentLockSet != ls {
 		t.Error("ready's lockset doesn't match")
 	}
-	s.Sign(key1)
+	s.Sign(key1, nil)
 
 	s0 := NewReady(0, ls)
-	s0.Sign(key1)
+	s0.Sign(key1, nil)
 
 	s1 := NewReady(1, ls)
-	s1.Sign(key1)
+	s1.Sign(key1, nil)
 
-	s.From()
-	s0.From()
+	s.From(nil)
+	s0.From(nil)
 	if s.CurrentLockSet != s0.CurrentLockSet || s.Nonce != s0.Nonce || *s.sender != *s0.sender {
 		t.Error("readys doesn't match")
 	}
@@ -94,7 +103,7 @@ func TestLockSet(t *testing.T) {
 	var validators []common.Address
 	for i := 0; i < 10; i++ {
 		s := strconv.Itoa(i + 1)
-		keys = append(keys, crypto.MakePrivatekey(s))
+		keys = append(keys, makePrivateKey(s))
 	}
 	for _, key := range keys {
 		validators = append(validators, crypto.PubkeyToAddress(key.PublicKey))
@@ -115,7 +124,7 @@ func TestLockSet(t *testing.T) {
 		t.Error("there should be an error")
 	}
 	// add not signed vote
-	v1.Sign(keys[0])
+	v1.Sign(keys[0], nil)
 	if err := ls.Add(v1, false); err != nil {
 		t.Error("error occured")
 	}
@@ -133,7 +142,7 @@ func TestLockSet(t *testing.T) {
 
 	// second vote same sender
 	v2 := NewVote(height, round, bh, 1)
-	v2.Sign(keys[0])
+	v2.Sign(keys[0], nil)
 	ls.Add(v1, false)
 	ls.Add(v2, false)
 	if lsh != ls.Hash() {
@@ -145,7 +154,7 @@ func TestLockSet(t *testing.T) {
 
 	// third vote
 	v3 := NewVote(height, round, bh, 1)
-	v3.Sign(keys[1])
+	v3.Sign(keys[1], nil)
 	ls.Add(v3, false)
 	if lsh == ls.Hash() {
 		t.Error("lockset hash doesn't match")
@@ -160,7 +169,7 @@ func TestLockSet(t *testing.T) {
 
 	// vote wrong round
 	v4 := NewVote(height, round+1, bh, 1)
-	v4.Sign(keys[2])
+	v4.Sign(keys[2], nil)
 
 	if lsh != ls.Hash() {
 		t.Error("lockset hash doesn't match")
@@ -175,7 +184,7 @@ func TestLockSet(t *testing.T) {
 	// vote twice
 	bh2 := common.HexToHash("11111111111111111111111111111111")
 	v3_2 := NewVote(height, round, bh2, 1)
-	v3_2.Sign(keys[1])
+	v3_2.Sign(keys[1], nil)
 	if err := ls.Add(v3_2, false); err == nil {
 		t.Error("there should be an error")
 	}
@@ -189,7 +198,7 @@ func TestLockSet(t *testing.T) {
 		t.Error("vote should not be in lockset")
 	}
 }
-func TestoneVoteLockset(t *testing.T) {
+func TestOneVoteLockset(t *testing.T) {
 	ls := NewLockSet(1, nil)
 	if len(ls.Votes) != 0 {
 		t.Error("lockset votes number doesn't match")
@@ -212,7 +221,7 @@ func TestLockSetIsvalid(t *testing.T) {
 	var validators []common.Address
 	for i := 0; i < 10; i++ {
 		s := strconv.Itoa(i + 1)
-		keys = append(keys, crypto.MakePrivatekey(s))
+		keys = append(keys, makePrivateKey(s))
 	}
 	for _, key := range keys {
 		validators = append(validators, crypto.PubkeyToAddress(key.PublicKey))
@@ -228,11 +237,11 @@ func TestLockSetIsvalid(t *testing.T) {
 	var votes Votes
 	for _, key := range keys {
 		v := NewVote(height, round, bh, 1)
-		v.Sign(key)
+		v.Sign(key, nil)
 		votes = append(votes, v)
 	}
 	for i, v := range votes {
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 		if len(ls.Votes) != i+1 {
 			t.Error("lockset votes number doesn't match")
@@ -254,7 +263,7 @@ func TestLockSetWithQuorum(t *testing.T) {
 	var validators []common.Address
 	for i := 0; i < 10; i++ {
 		s := strconv.Itoa(i + 1)
-		keys = append(keys, crypto.MakePrivatekey(s))
+		keys = append(keys, makePrivateKey(s))
 	}
 	for _, key := range keys {
 		validators = append(validators, crypto.PubkeyToAddress(key.PublicKey))
@@ -263,8 +272,8 @@ func TestLockSetWithQuorum(t *testing.T) {
 	bh := common.HexToHash("00000000000000000000000000000000")
 	v1 := NewVote(0, 0, bh, 1)
 	v2 := NewVote(0, 0, common.Hash{}, 2)
-	v1.Sign(keys[0])
-	v2.Sign(keys[1])
+	v1.Sign(keys[0], nil)
+	v2.Sign(keys[1], nil)
 	ls.Add(v1, false)
 	ls.Add(v2, false)
 	if len(ls.Votes) != 2 {
@@ -274,7 +283,7 @@ func TestLockSetWithQuorum(t *testing.T) {
 		t.Error("lockset should be invalid")
 	}
 	v3 := NewVote(0, 0, common.Hash{}, 2)
-	v3.Sign(keys[2])
+	v3.Sign(keys[2], nil)
 	ls.Add(v3, false)
 	if !ls.IsValid() {
 		t.Error("lockset should be valid")
@@ -294,7 +303,7 @@ func TestLockSetQuorums(t *testing.T) {
 	var validators []common.Address
 	for i := 0; i < 10; i++ {
 		s := strconv.Itoa(i + 1)
-		keys = append(keys, crypto.MakePrivatekey(s))
+		keys = append(keys, makePrivateKey(s))
 	}
 	for _, key := range keys {
 		validators = append(validators, crypto.PubkeyToAddress(key.PublicKey))
@@ -325,7 +334,7 @@ func TestLockSetQuorums(t *testing.T) {
 		} else if j == 0 {
 			v = NewVote(height, round, common.Hash{}, 2)
 		}
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 	}
 
@@ -346,7 +355,7 @@ func TestLockSetQuorums(t *testing.T) {
 		} else if j == 0 {
 			v = NewVote(height, round, common.Hash{}, 2)
 		}
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 	}
 	if has, _ := ls.HasQuorum(); !has {
@@ -366,7 +375,7 @@ func TestLockSetQuorums(t *testing.T) {
 		} else if j == 0 {
 			v = NewVote(height, round, common.Hash{}, 2)
 		}
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 	}
 	if has, _ := ls.HasQuorum(); !has {
@@ -391,7 +400,7 @@ func TestLockSetQuorums(t *testing.T) {
 		} else if j == 0 {
 			v = NewVote(height, round, common.Hash{}, 2)
 		}
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 	}
 	if !ls.NoQuorum() {
@@ -412,7 +421,7 @@ func TestLockSetQuorums(t *testing.T) {
 		} else if j == 0 {
 			v = NewVote(height, round, common.Hash{}, 2)
 		}
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 	}
 
@@ -434,7 +443,7 @@ func TestLockSetQuorums(t *testing.T) {
 		} else if j == 0 {
 			v = NewVote(height, round, common.Hash{}, 2)
 		}
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 	}
 	if !ls.NoQuorum() {
@@ -459,7 +468,7 @@ func TestLockSetQuorums(t *testing.T) {
 		} else if j == 0 {
 			v = NewVote(height, round, common.Hash{}, 2)
 		}
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 	}
 	if has, _ := ls.QuorumPossible(); !has {
@@ -479,7 +488,7 @@ func TestLockSetQuorums(t *testing.T) {
 		} else if j == 0 {
 			v = NewVote(height, round, common.Hash{}, 2)
 		}
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 	}
 	if has, _ := ls.QuorumPossible(); !has {
@@ -499,7 +508,7 @@ func TestLockSetQuorums(t *testing.T) {
 		} else if j == 0 {
 			v = NewVote(height, round, common.Hash{}, 2)
 		}
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 	}
 	if has, _ := ls.QuorumPossible(); !has {
@@ -519,7 +528,7 @@ func TestLockSetQuorums(t *testing.T) {
 		} else if j == 0 {
 			v = NewVote(height, round, common.Hash{}, 2)
 		}
-		v.Sign(keys[i])
+		v.Sign(keys[i], nil)
 		ls.Add(v, false)
 	}
 	if has, _ := ls.QuorumPossible(); !has {
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// domainVersion is the version of the signature domain separation scheme,
+// hashed into every domain separated signature.
+const domainVersion = 1
+
+// Message kinds hashed into domain separated signatures, so that a signature
+// of one message type can never be replayed as another.
+const (
+	kindVote              = "vote"
+	kindPrecommitVote     = "precommitVote"
+	kindLockSet           = "lockset"
+	kindPrecommitLockSet  = "precommitLockset"
+	kindReady             = "ready"
+	kindBlockProposal     = "blockProposal"
+	kindVotingInstruction = "votingInstruction"
+	kindProposalHeader    = "proposalHeader"
+)
+
+// Domain separates the signatures of consensus messages of different networks.
+// Messages of heights below Block are signed without domain, which keeps the
+// commit certificates of blocks sealed before the fork verifiable.
+type Domain struct {
+	ChainID *big.Int    // Chain identifier of the network
+	Genesis common.Hash // Genesis block hash of the network
+	Block   *big.Int    // First height signing with the domain (nil = never)
+}
+
+// same reports whether d and o separate signatures alike, so that a sender
+// recovered under one is valid under the other.
+func (d *Domain) same(o *Domain) bool {
+	if d == o {
+		return true
+	}
+	if d == nil || o == nil {
+		return false
+	}
+	return d.Genesis == o.Genesis && sameBig(d.ChainID, o.ChainID) && sameBig(d.Block, o.Block)
+}
+
+func sameBig(x, y *big.Int) bool {
+	if x == nil || y == nil {
+		return x == y
+	}
+	return x.Cmp(y) == 0
+}
+
+// sigHash returns the hash signed by a consensus message of the given kind and
+// height, consisting of the message fields prefixed by the signature domain d if
+// the height is past the domain fork.
+func sigHash(d *Domain, kind string, height uint64, fields ...interface{}) common.Hash {
+	if d == nil || d.Block == nil || d.Block.Cmp(new(big.Int).SetUint64(height)) > 0 {
+		return rlpHash(fields)
+	}
+	prefix := []interface{}{uint(domainVersion), d.ChainID, d.Genesis, kind}
+	return rlpHash(append(prefix, fields...))
+}
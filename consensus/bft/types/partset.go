@@ -0,0 +1,290 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// BlockPartSize is the size of the parts block proposals are split into for
+// gossip.
+const BlockPartSize = 64 * 1024
+
+// MaxBlockParts is the maximum number of parts of a proposal, enough for any
+// proposal fitting the protocol message size cap.
+const MaxBlockParts = 256
+
+var (
+	errPartIndex   = errors.New("block part index out of range")
+	errPartProof   = errors.New("invalid block part proof")
+	errPartsTotal  = errors.New("invalid number of block parts")
+	errIncomplete  = errors.New("part set incomplete")
+	errPartSetRoot = errors.New("block part of another part set")
+)
+
+// PartSetHeader commits to the parts a proposal was split into.
+type PartSetHeader struct {
+	Total uint32      // Number of parts
+	Root  common.Hash // Merkle root of the parts
+}
+
+// BlockPart is a chunk of an RLP encoded block proposal, along with the Merkle
+// proof of its inclusion in the part set.
+type BlockPart struct {
+	Root  common.Hash   // Merkle root of the part set the part belongs to
+	Index uint32        // Position of the part in the part set
+	Bytes []byte        // Content of the part
+	Proof []common.Hash // Sibling hashes from the part up to the root
+}
+
+// Hash returns the identifier of the part used to filter duplicates.
+func (p *BlockPart) Hash() common.Hash {
+	return rlpHash([]interface{}{p.Root, p.Index})
+}
+
+// ProposalHeader announces the part set of a block proposal. It is signed by
+// the proposer, so a part verified against its root is known to stem from the
+// proposer before the proposal itself is reassembled.
+type ProposalHeader struct {
+	sender *common.Address
+	domain *Domain  // Domain the sender was recovered under
+	V      *big.Int // signature
+	R, S   *big.Int // signature
+	Height uint64
+	Round  uint64
+	Parts  PartSetHeader
+}
+
+func NewProposalHeader(height uint64, round uint64, parts PartSetHeader) *ProposalHeader {
+	return &ProposalHeader{
+		R:      new(big.Int),
+		S:      new(big.Int),
+		Height: height,
+		Round:  round,
+		Parts:  parts,
+	}
+}
+
+// Hash returns the identifier of the header used to filter duplicates. Unlike
+// the hashes of the other messages, it is the same before and after the signer
+// was recovered.
+func (h *ProposalHeader) Hash() common.Hash {
+	return rlpHash([]interface{}{
+		h.Height,
+		h.Round,
+		h.Parts,
+		h.V, h.R, h.S,
+	})
+}
+func (h *ProposalHeader) SigHash(d *Domain) common.Hash {
+	return sigHash(d, kindProposalHeader, h.Height,
+		h.Height,
+		h.Round,
+		h.Parts,
+	)
+}
+func (h *ProposalHeader) From(d *Domain) (common.Address, error) {
+	if h.sender != nil && h.domain.same(d) {
+		return *h.sender, nil
+	}
+	if h.V == nil || h.V.BitLen() > 8 {
+		return common.Address{}, ErrInvalidSig
+	}
+	V := byte(h.V.Uint64() - 27)
+	if !crypto.ValidateSignatureValues(V, h.R, h.S, true) {
+		return common.Address{}, ErrInvalidSig
+	}
+	r, s := h.R.Bytes(), h.S.Bytes()
+	sig := make([]byte, 65)
+	copy(sig[32-len(r):32], r)
+	copy(sig[64-len(s):64], s)
+	sig[64] = V
+
+	hash := h.SigHash(d)
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	addr := crypto.PubkeyToAddress(*pub)
+	h.sender = &addr
+	h.domain = d
+	return addr, nil
+}
+
+// WithSignature sets the 65 byte [R || S || V] signature of the header.
+func (h *ProposalHeader) WithSignature(sig []byte) (*ProposalHeader, error) {
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("wrong size for signature: got %d, want 65", len(sig))
+	}
+	h.R = new(big.Int).SetBytes(sig[:32])
+	h.S = new(big.Int).SetBytes(sig[32:64])
+	h.V = new(big.Int).SetBytes([]byte{sig[64] + 27})
+	return h, nil
+}
+
+// Sign this with a privacy key
+func (h *ProposalHeader) SignECDSA(prv *ecdsa.PrivateKey, hash common.Hash) (*ProposalHeader, error) {
+	sig, err := crypto.Sign(hash[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	return h.WithSignature(sig)
+}
+
+// PartSet is a block proposal split into Merkle committed parts, either built
+// from a whole proposal or being reassembled from gossiped parts.
+type PartSet struct {
+	header PartSetHeader
+	parts  []*BlockPart
+	count  uint32
+}
+
+// NewPartSetFromData splits data into parts of the given size.
+func NewPartSetFromData(data []byte, partSize int) *PartSet {
+	total := (len(data) + partSize - 1) / partSize
+	if total == 0 {
+		total = 1
+	}
+	leaves := make([]common.Hash, total)
+	parts := make([]*BlockPart, total)
+	for i := range parts {
+		start, end := i*partSize, (i+1)*partSize
+		if end > len(data) {
+			end = len(data)
+		}
+		parts[i] = &BlockPart{Index: uint32(i), Bytes: common.CopyBytes(data[start:end])}
+		leaves[i] = partLeaf(parts[i].Bytes)
+	}
+	root := merkleRoot(leaves)
+	for i, part := range parts {
+		part.Root = root
+		part.Proof = merkleProof(leaves, i)
+	}
+	return &PartSet{
+		header: PartSetHeader{Total: uint32(total), Root: root},
+		parts:  parts,
+		count:  uint32(total),
+	}
+}
+
+// NewPartSetFromHeader creates an empty part set to be filled with the parts
+// committed to by header.
+func NewPartSetFromHeader(header PartSetHeader) (*PartSet, error) {
+	if header.Total == 0 || header.Total > MaxBlockParts {
+		return nil, errPartsTotal
+	}
+	return &PartSet{
+		header: header,
+		parts:  make([]*BlockPart, header.Total),
+	}, nil
+}
+
+func (ps *PartSet) Header() PartSetHeader { return ps.header }
+func (ps *PartSet) Count() uint32         { return ps.count }
+func (ps *PartSet) IsComplete() bool      { return ps.count == ps.header.Total }
+
+// GetPart returns the part at index, or nil if it is not known yet.
+func (ps *PartSet) GetPart(index uint32) *BlockPart {
+	if index >= ps.header.Total {
+		return nil
+	}
+	return ps.parts[index]
+}
+
+// AddPart verifies a part against the root of the set and stores it. It returns
+// false if the part was known already.
+func (ps *PartSet) AddPart(part *BlockPart) (bool, error) {
+	if part.Root != ps.header.Root {
+		return false, errPartSetRoot
+	}
+	if part.Index >= ps.header.Total {
+		return false, errPartIndex
+	}
+	if ps.parts[part.Index] != nil {
+		return false, nil
+	}
+	if len(part.Bytes) > BlockPartSize || !verifyMerkleProof(ps.header.Root, partLeaf(part.Bytes), int(part.Index), int(ps.header.Total), part.Proof) {
+		return false, errPartProof
+	}
+	ps.parts[part.Index] = part
+	ps.count++
+	return true, nil
+}
+
+// Assemble concatenates the parts of a complete set.
+func (ps *PartSet) Assemble() ([]byte, error) {
+	if !ps.IsComplete() {
+		return nil, errIncomplete
+	}
+	var data []byte
+	for _, part := range ps.parts {
+		data = append(data, part.Bytes...)
+	}
+	return data, nil
+}
+
+// The part set tree hashes leaves and inner nodes with distinct prefixes, so
+// that no inner node can pass for a part. A node without sibling is carried up
+// to the next level unchanged.
+
+func partLeaf(data []byte) common.Hash {
+	return crypto.Keccak256Hash([]byte{0x00}, data)
+}
+
+func partNode(left, right common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte{0x01}, left[:], right[:])
+}
+
+func merkleRoot(leaves []common.Hash) common.Hash {
+	level := leaves
+	for len(level) > 1 {
+		level = merkleLevel(level)
+	}
+	return level[0]
+}
+
+func merkleLevel(nodes []common.Hash) []common.Hash {
+	next := make([]common.Hash, 0, (len(nodes)+1)/2)
+	for i := 0; i < len(nodes); i += 2 {
+		if i+1 < len(nodes) {
+			next = append(next, partNode(nodes[i], nodes[i+1]))
+		} else {
+			next = append(next, nodes[i])
+		}
+	}
+	return next
+}
+
+func merkleProof(leaves []common.Hash, index int) []common.Hash {
+	var proof []common.Hash
+	for level := leaves; len(level) > 1; level = merkleLevel(level) {
+		if sibling := index ^ 1; sibling < len(level) {
+			proof = append(proof, level[sibling])
+		}
+		index /= 2
+	}
+	return proof
+}
+
+func verifyMerkleProof(root, leaf common.Hash, index, total int, proof []common.Hash) bool {
+	hash := leaf
+	for n := total; n > 1; n = (n + 1) / 2 {
+		if sibling := index ^ 1; sibling < n {
+			if len(proof) == 0 {
+				return false
+			}
+			if index&1 == 0 {
+				hash = partNode(hash, proof[0])
+			} else {
+				hash = partNode(proof[0], hash)
+			}
+			proof = proof[1:]
+		}
+		index /= 2
+	}
+	return len(proof) == 0 && hash == root
+}
@@ -0,0 +1,114 @@
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that data split into a part set is reassembled from its parts, whatever
+// the number of parts and the order they arrive in.
+func TestPartSetAssemble(t *testing.T) {
+	for _, size := range []int{0, 1, 63, 64, 65, 64 * 5, 64*7 + 1} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		set := NewPartSetFromData(data, 64)
+		if !set.IsComplete() {
+			t.Errorf("size %d: split part set incomplete", size)
+		}
+		recv, err := NewPartSetFromHeader(set.Header())
+		if err != nil {
+			t.Fatalf("size %d: failed to create part set: %v", size, err)
+		}
+		if _, err := recv.Assemble(); err != errIncomplete {
+			t.Errorf("size %d: empty set assembled: %v", size, err)
+		}
+		for i := int(set.Header().Total) - 1; i >= 0; i-- {
+			added, err := recv.AddPart(set.GetPart(uint32(i)))
+			if !added || err != nil {
+				t.Fatalf("size %d: part %d rejected: %v", size, i, err)
+			}
+			if added, err := recv.AddPart(set.GetPart(uint32(i))); added || err != nil {
+				t.Errorf("size %d: duplicate part %d: added %v, err %v", size, i, added, err)
+			}
+		}
+		assembled, err := recv.Assemble()
+		if err != nil {
+			t.Fatalf("size %d: failed to assemble: %v", size, err)
+		}
+		if !bytes.Equal(assembled, data) {
+			t.Errorf("size %d: assembled data mismatch", size)
+		}
+	}
+}
+
+// Tests that parts not committed to by the part set header are rejected.
+func TestPartSetInvalidParts(t *testing.T) {
+	data := make([]byte, 64*5)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	set := NewPartSetFromData(data, 64)
+	other := NewPartSetFromData(data[64:], 64)
+
+	recv, _ := NewPartSetFromHeader(set.Header())
+	if _, err := recv.AddPart(other.GetPart(0)); err != errPartSetRoot {
+		t.Errorf("part of another set: have %v, want %v", err, errPartSetRoot)
+	}
+	part := *set.GetPart(1)
+	part.Index = 7
+	if _, err := recv.AddPart(&part); err != errPartIndex {
+		t.Errorf("part out of range: have %v, want %v", err, errPartIndex)
+	}
+	part = *set.GetPart(1)
+	part.Index = 2
+	if _, err := recv.AddPart(&part); err != errPartProof {
+		t.Errorf("part at wrong index: have %v, want %v", err, errPartProof)
+	}
+	part = *set.GetPart(1)
+	part.Bytes = common.CopyBytes(part.Bytes)
+	part.Bytes[0]++
+	if _, err := recv.AddPart(&part); err != errPartProof {
+		t.Errorf("tampered part: have %v, want %v", err, errPartProof)
+	}
+	part = *set.GetPart(1)
+	part.Proof = part.Proof[1:]
+	if _, err := recv.AddPart(&part); err != errPartProof {
+		t.Errorf("truncated proof: have %v, want %v", err, errPartProof)
+	}
+	if recv.Count() != 0 {
+		t.Errorf("invalid parts stored: %d", recv.Count())
+	}
+	for _, total := range []uint32{0, MaxBlockParts + 1} {
+		if _, err := NewPartSetFromHeader(PartSetHeader{Total: total}); err != errPartsTotal {
+			t.Errorf("%d parts: have %v, want %v", total, err, errPartsTotal)
+		}
+	}
+}
+
+// Tests that proposal headers recover their signer in the domain they were
+// signed in and reject malformed signatures.
+func TestProposalHeaderSignature(t *testing.T) {
+	key := makePrivateKey("1")
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	domain := &Domain{ChainID: big.NewInt(1), Block: new(big.Int)}
+
+	header := NewProposalHeader(2, 0, NewPartSetFromData([]byte("proposal"), 64).Header())
+	if _, err := header.SignECDSA(key, header.SigHash(domain)); err != nil {
+		t.Fatalf("failed to sign header: %v", err)
+	}
+	if sender, err := header.From(domain); err != nil || sender != addr {
+		t.Errorf("sender mismatch: have %x (%v), want %x", sender, err, addr)
+	}
+	if sender, err := header.From(nil); err == nil && sender == addr {
+		t.Errorf("signer recovered outside of the domain")
+	}
+	if _, err := NewProposalHeader(2, 0, PartSetHeader{}).WithSignature(make([]byte, 64)); err == nil {
+		t.Errorf("short signature accepted")
+	}
+}
@@ -0,0 +1,150 @@
+package bft
+
+import (
+	"runtime"
+	"sync"
+
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+)
+
+// verifyQueueSize is the number of incoming messages that may wait for their
+// signatures to be recovered before peer handlers block.
+const verifyQueueSize = 1024
+
+// verifyTask is an incoming consensus message travelling through verification.
+type verifyTask struct {
+	msg  interface{}
+	peer *peer
+}
+
+// msgVerifier recovers the signers of incoming consensus messages on a pool of
+// workers and hands the messages, with their signers cached, to a single
+// consensus goroutine. This keeps the elliptic curve work out of the consensus
+// locks.
+type msgVerifier struct {
+	workers  int
+	domain   *btypes.Domain // Signature domain the signers are recovered under
+	tasks    chan *verifyTask
+	verified chan *verifyTask
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newMsgVerifier(workers int, domain *btypes.Domain) *msgVerifier {
+	return &msgVerifier{
+		workers:  workers,
+		domain:   domain,
+		tasks:    make(chan *verifyTask, verifyQueueSize),
+		verified: make(chan *verifyTask, verifyQueueSize),
+		quit:     make(chan struct{}),
+	}
+}
+
+// start launches the verification workers and the consensus goroutine that
+// passes every verified message to deliver.
+func (v *msgVerifier) start(deliver func(msg interface{}, p *peer)) {
+	for i := 0; i < v.workers; i++ {
+		v.wg.Add(1)
+		go v.work()
+	}
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+		for {
+			select {
+			case task := <-v.verified:
+				deliver(task.msg, task.peer)
+			case <-v.quit:
+				return
+			}
+		}
+	}()
+}
+
+func (v *msgVerifier) stop() {
+	close(v.quit)
+	v.wg.Wait()
+}
+
+// enqueue schedules a message received from a peer for verification, blocking
+// while the queue is full.
+func (v *msgVerifier) enqueue(msg interface{}, p *peer) {
+	select {
+	case v.tasks <- &verifyTask{msg: msg, peer: p}:
+	case <-v.quit:
+	}
+}
+
+func (v *msgVerifier) work() {
+	defer v.wg.Done()
+	for {
+		select {
+		case task := <-v.tasks:
+			recoverSigners(task.msg, v.domain)
+			select {
+			case v.verified <- task:
+			case <-v.quit:
+				return
+			}
+		case <-v.quit:
+			return
+		}
+	}
+}
+
+// recoverSigners recovers and caches the signer of a consensus message and of
+// all the votes embedded in it under the signature domain d. Failures are left
+// for admission to report.
+func recoverSigners(msg interface{}, d *btypes.Domain) {
+	switch m := msg.(type) {
+	case *btypes.Vote:
+		m.From(d)
+	case *btypes.PrecommitVote:
+		m.From(d)
+	case *btypes.Ready:
+		m.From(d)
+		recoverBatch(locksetVotes(m.CurrentLockSet), d)
+	case *btypes.BlockProposal:
+		m.From(d)
+		batch := locksetVotes(m.RoundLockset)
+		if m.SigningLockset != nil {
+			for _, vote := range m.SigningLockset.PrecommitVotes {
+				batch = append(batch, vote)
+			}
+		}
+		recoverBatch(batch, d)
+	case *btypes.VotingInstruction:
+		m.From(d)
+		recoverBatch(locksetVotes(m.RoundLockset), d)
+	}
+}
+
+func locksetVotes(ls *btypes.LockSet) []consensusMsg {
+	if ls == nil {
+		return nil
+	}
+	votes := make([]consensusMsg, 0, len(ls.Votes))
+	for _, vote := range ls.Votes {
+		votes = append(votes, vote)
+	}
+	return votes
+}
+
+// recoverBatch recovers the signers of a batch of messages concurrently.
+func recoverBatch(msgs []consensusMsg, d *btypes.Domain) {
+	threads := runtime.NumCPU()
+	if len(msgs) < threads {
+		threads = len(msgs)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := id; j < len(msgs); j += threads {
+				msgs[j].From(d)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
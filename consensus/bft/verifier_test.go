@@ -0,0 +1,50 @@
+package bft
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	btypes "github.com/ethereum/go-ethereum/consensus/bft/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestMsgVerifierDelivers(t *testing.T) {
+	keys, validators := testValidators(8)
+
+	delivered := make(chan interface{}, len(keys))
+	verifier := newMsgVerifier(4, nil)
+	verifier.start(func(msg interface{}, p *peer) { delivered <- msg })
+	defer verifier.stop()
+
+	// Queue votes arriving from the network, without their signers cached
+	for _, key := range keys {
+		vote := btypes.NewVote(1, 0, common.HexToHash("0x01"), 1)
+		vote.Sign(key, nil)
+		blob, _ := rlp.EncodeToBytes(vote)
+
+		decoded := new(btypes.Vote)
+		if err := rlp.DecodeBytes(blob, decoded); err != nil {
+			t.Fatalf("failed to decode vote: %v", err)
+		}
+		verifier.enqueue(decoded, nil)
+	}
+	signers := make(map[common.Address]bool)
+	for range keys {
+		select {
+		case msg := <-delivered:
+			addr, err := msg.(*btypes.Vote).From(nil)
+			if err != nil {
+				t.Fatalf("delivered vote without signer: %v", err)
+			}
+			signers[addr] = true
+		case <-time.After(time.Second):
+			t.Fatalf("vote not delivered")
+		}
+	}
+	for _, addr := range validators {
+		if !signers[addr] {
+			t.Errorf("vote of %x not delivered", addr)
+		}
+	}
+}
@@ -644,6 +644,17 @@ func (c *Clique) Seal(chain consensus.ChainReader, block *types.Block, stop <-ch
 	return block.WithSeal(header), nil
 }
 
+// Signers retrieves the list of signers authorized after the given header, as
+// needed to hand the chain over to another engine. Parents are the ancestors of
+// the header not yet in the chain, if any.
+func (c *Clique) Signers(chain consensus.ChainReader, header *types.Header, parents []*types.Header) ([]common.Address, error) {
+	snap, err := c.snapshot(chain, header.Number.Uint64(), header.Hash(), parents)
+	if err != nil {
+		return nil, err
+	}
+	return snap.signers(), nil
+}
+
 // APIs implements consensus.Engine, returning the user facing RPC API to allow
 // controlling the signer voting.
 func (c *Clique) APIs(chain consensus.ChainReader) []rpc.API {
@@ -92,6 +92,16 @@ type Engine interface {
 	APIs(chain ChainReader) []rpc.API
 }
 
+// SealPolicy is a consensus engine that decides itself when a block is worth
+// sealing, e.g. to avoid producing empty blocks.
+type SealPolicy interface {
+	Engine
+
+	// Ready reports whether the block should be sealed now. If not, the returned
+	// channel is closed once the decision may have changed.
+	Ready(chain ChainReader, block *types.Block) (bool, <-chan struct{})
+}
+
 // PoW is a consensus engine based on proof-of-work.
 type PoW interface {
 	Engine
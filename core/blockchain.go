@@ -40,6 +40,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/private"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/hashicorp/golang-lru"
@@ -92,7 +93,8 @@ type BlockChain struct {
 	currentBlock     *types.Block // Current head of the block chain
 	currentFastBlock *types.Block // Current head of the fast-sync chain (may be above the block chain!)
 
-	stateCache   state.Database // State database to reuse between imports (contains state cache)
+	stateCache   state.Database  // State database to reuse between imports (contains state cache)
+	triecache    *trie.NodeCache // Write cache of the state tries, flushed to disk as configured by gc
 	bodyCache    *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache     // Cache for the most recent entire blocks
@@ -109,6 +111,12 @@ type BlockChain struct {
 	validator Validator // block and state validator interface
 	vmConfig  vm.Config
 
+	privateManager private.TransactionManager // Payload source of private transactions (nil = public only)
+
+	gc     *StateGCConfig           // Garbage collection of the recent states (nil = archive, every state flushed)
+	triegc map[uint64][]common.Hash // Roots of the recent states kept in memory, by block number
+	gcTail uint64                   // Lowest block number of the states kept in memory
+
 	badBlocks *lru.Cache // Bad block cache
 }
 
@@ -121,11 +129,13 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 	blockCache, _ := lru.New(blockCacheLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
 	badBlocks, _ := lru.New(badBlockLimit)
+	triecache := state.NewTrieCache(chainDb)
 
 	bc := &BlockChain{
 		config:       config,
 		chainDb:      chainDb,
-		stateCache:   state.NewDatabase(chainDb),
+		stateCache:   state.NewDatabase(triecache),
+		triecache:    triecache,
 		eventMux:     mux,
 		quit:         make(chan struct{}),
 		bodyCache:    bodyCache,
@@ -151,6 +161,9 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	if err := bc.reprocess(); err != nil {
+		return nil, err
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -192,9 +205,12 @@ func (bc *BlockChain) loadLastState() error {
 	}
 	// Make sure the state associated with the block is available
 	if _, err := state.New(currentBlock.Root(), bc.stateCache); err != nil {
-		// Dangling block without a state associated, init from scratch
-		log.Warn("Head state missing, resetting chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
-		return bc.Reset()
+		// Dangling block without a state associated, rewind to the last flushed one
+		log.Warn("Head state missing, repairing chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
+		if err := bc.repair(&currentBlock); err != nil {
+			log.Warn("Failed to repair chain, resetting", "err", err)
+			return bc.Reset()
+		}
 	}
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock = currentBlock
@@ -257,8 +273,11 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	}
 	if bc.currentBlock != nil {
 		if _, err := state.New(bc.currentBlock.Root(), bc.stateCache); err != nil {
-			// Rewound state missing, rolled back to before pivot, reset to genesis
-			bc.currentBlock = nil
+			// Rewound state missing, rewind further to the last flushed one or,
+			// if rolled back to before pivot, reset to genesis
+			if err := bc.repair(&bc.currentBlock); err != nil {
+				bc.currentBlock = nil
+			}
 		}
 	}
 	// Rewind the fast block in a simpleton way to the target head
@@ -281,6 +300,78 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	return bc.loadLastState()
 }
 
+// repair rewinds the head block to its closest ancestor with an associated
+// state, as the states kept in memory are lost if the node crashes.
+func (bc *BlockChain) repair(head **types.Block) error {
+	for {
+		if _, err := state.New((*head).Root(), bc.stateCache); err == nil {
+			log.Info("Rewound blockchain to past state", "number", (*head).Number(), "hash", (*head).Hash())
+			return nil
+		}
+		if (*head).NumberU64() == 0 {
+			return errors.New("genesis state missing")
+		}
+		block := bc.GetBlock((*head).ParentHash(), (*head).NumberU64()-1)
+		if block == nil {
+			return fmt.Errorf("missing block %d [%x…]", (*head).NumberU64()-1, (*head).ParentHash().Bytes()[:4])
+		}
+		*head = block
+	}
+}
+
+// reprocess re-executes the canonical blocks between the head block and the
+// stored head, whose states were lost in a crash since the last flush, rather
+// than rewinding past them. Blocks of instant finality engines are final, so
+// the chain must not fall behind them. Blocks failing to execute are left for
+// the chain to rewind to. Private states are rebuilt by SetPrivateManager, as the
+// transaction manager is only set up afterwards.
+func (bc *BlockChain) reprocess() error {
+	target := bc.GetBlockByHash(GetHeadBlockHash(bc.chainDb))
+	if target == nil || target.NumberU64() <= bc.currentBlock.NumberU64() {
+		return nil
+	}
+	var blocks types.Blocks
+	for block := target; block.NumberU64() > bc.currentBlock.NumberU64(); {
+		blocks = append(blocks, block)
+		if block = bc.GetBlock(block.ParentHash(), block.NumberU64()-1); block == nil {
+			return nil
+		}
+	}
+	log.Info("Reprocessing blocks lost since the last state flush", "count", len(blocks), "from", bc.currentBlock.Number())
+
+	start := time.Now()
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block, parent := blocks[i], bc.currentBlock
+		if block.ParentHash() != parent.Hash() {
+			break
+		}
+		statedb, err := state.New(parent.Root(), bc.stateCache)
+		if err != nil {
+			return err
+		}
+		receipts, _, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
+		if err == nil {
+			err = bc.Validator().ValidateState(block, parent, statedb, receipts, usedGas)
+		}
+		if err != nil {
+			log.Error("Failed to reprocess block", "number", block.Number(), "hash", block.Hash(), "err", err)
+			break
+		}
+		if _, err := statedb.CommitTo(bc.triecache, bc.config.IsEIP158(block.Number())); err != nil {
+			return err
+		}
+		if err := bc.triecache.Commit(block.Root()); err != nil {
+			return err
+		}
+		bc.currentBlock = block
+	}
+	if err := WriteHeadBlockHash(bc.chainDb, bc.currentBlock.Hash()); err != nil {
+		log.Crit("Failed to update head block hash", "err", err)
+	}
+	log.Info("Reprocessed lost blocks", "number", bc.currentBlock.Number(), "hash", bc.currentBlock.Hash(), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
 // FastSyncCommitHead sets the current head block to the one defined by the hash
 // irrelevant what the chain contents were prior.
 func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
@@ -301,6 +392,80 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	return nil
 }
 
+// InsertCheckpoint starts an empty chain from a trusted checkpoint block whose
+// state was synced already. The checkpoint becomes the head of the chain, with
+// td as its total difficulty; the given ancestor headers, ordered by number and
+// ending with the parent of the checkpoint, are written as the canonical chain
+// preceding it, with total difficulties derived backwards from td.
+//
+// The td of a checkpoint may only be a lower bound of the actual one, so the
+// derived total difficulties are clamped to stay above the genesis.
+func (bc *BlockChain) InsertCheckpoint(ancestors []*types.Header, block *types.Block, td *big.Int) error {
+	bc.wg.Add(1)
+	defer bc.wg.Done()
+
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	if head := bc.CurrentBlock(); head.NumberU64() > 0 {
+		return fmt.Errorf("chain not empty: head #%d [%x…]", head.NumberU64(), head.Hash().Bytes()[:4])
+	}
+	if block.NumberU64() == 0 {
+		return errors.New("checkpoint is the genesis")
+	}
+	if _, err := trie.NewSecure(block.Root(), bc.chainDb, 0); err != nil {
+		return err
+	}
+	child := block.Header()
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		header := ancestors[i]
+		if header.Hash() != child.ParentHash || header.Number.Uint64()+1 != child.Number.Uint64() {
+			return fmt.Errorf("non contiguous checkpoint ancestor #%d [%x…]", header.Number, header.Hash().Bytes()[:4])
+		}
+		child = header
+	}
+	// Write the ancestors above the genesis, newest first to derive their TDs
+	var (
+		floor = new(big.Int).Add(bc.genesisBlock.Difficulty(), common.Big1)
+		ptd   = new(big.Int).Sub(td, block.Difficulty())
+	)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if ptd.Cmp(floor) < 0 {
+			ptd.Set(floor)
+		}
+		header := ancestors[i]
+		number := header.Number.Uint64()
+		if number == 0 {
+			if header.Hash() != bc.genesisBlock.Hash() {
+				return fmt.Errorf("checkpoint of another genesis [%x…]", header.Hash().Bytes()[:4])
+			}
+			break
+		}
+		if err := WriteHeader(bc.chainDb, header); err != nil {
+			return err
+		}
+		if err := WriteTd(bc.chainDb, header.Hash(), number, ptd); err != nil {
+			return err
+		}
+		if err := WriteCanonicalHash(bc.chainDb, header.Hash(), number); err != nil {
+			return err
+		}
+		ptd = new(big.Int).Sub(ptd, header.Difficulty)
+	}
+	if err := bc.hc.WriteTd(block.Hash(), block.NumberU64(), td); err != nil {
+		return err
+	}
+	if err := WriteBlock(bc.chainDb, block); err != nil {
+		return err
+	}
+	bc.mu.Lock()
+	bc.insert(block)
+	bc.mu.Unlock()
+
+	log.Info("Committed checkpoint as head block", "number", block.Number(), "hash", block.Hash(), "td", td)
+	return nil
+}
+
 // GasLimit returns the gas limit of the current HEAD block.
 func (bc *BlockChain) GasLimit() *big.Int {
 	bc.mu.RLock()
@@ -379,7 +544,14 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	statedb, err := state.New(root, bc.stateCache)
+	if err != nil {
+		// Garbage collected states may still be rebuilt from their diffs
+		if rebuilt, rerr := bc.rebuildState(root); rerr == nil {
+			return rebuilt, nil
+		}
+	}
+	return statedb, err
 }
 
 // Reset purges the entire blockchain, restoring it to its genesis state.
@@ -598,6 +770,8 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+	bc.flushState()
+
 	log.Info("Blockchain manager stopped")
 }
 
@@ -819,7 +993,22 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 	return 0, nil
 }
 
-// WriteBlock writes the block to the chain.
+// WriteBlockWithState commits the state of the block into the trie cache and
+// writes the block to the chain. The state is held in memory until the block is
+// written, so that it is released again if writing the block fails.
+func (bc *BlockChain) WriteBlockWithState(block *types.Block, state *state.StateDB) (WriteStatus, error) {
+	root, err := state.CommitTo(bc.triecache, bc.config.IsEIP158(block.Number()))
+	if err != nil {
+		return NonStatTy, err
+	}
+	bc.triecache.Reference(root)
+	defer bc.triecache.Dereference(root)
+
+	return bc.WriteBlock(block)
+}
+
+// WriteBlock writes the block to the chain. The state of the block must have
+// been committed into the trie cache already, see WriteBlockWithState.
 func (bc *BlockChain) WriteBlock(block *types.Block) (status WriteStatus, err error) {
 	bc.wg.Add(1)
 	defer bc.wg.Done()
@@ -829,6 +1018,18 @@ func (bc *BlockChain) WriteBlock(block *types.Block) (status WriteStatus, err er
 	if ptd == nil {
 		return NonStatTy, consensus.ErrUnknownAncestor
 	}
+	// Bring the private state of the local participant along. Failures only cost
+	// the participant its private state, they must not stall the public chain.
+	// The private state is held in memory until the block is written.
+	if root, err := bc.processPrivateTransactions(block); err != nil {
+		log.Error("Failed to process private transactions", "number", block.Number(), "hash", block.Hash(), "err", err)
+		if err := markPrivateStateUnavailable(bc.chainDb, block.Hash()); err != nil {
+			log.Crit("Failed to mark private state unavailable", "err", err)
+		}
+	} else {
+		defer bc.triecache.Dereference(root)
+	}
+
 	// Make sure no inconsistent state is leaked during insertion
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
@@ -862,6 +1063,9 @@ func (bc *BlockChain) WriteBlock(block *types.Block) (status WriteStatus, err er
 
 	bc.futureBlocks.Remove(block.Hash())
 
+	if err := bc.collectState(block); err != nil {
+		return NonStatTy, err
+	}
 	return
 }
 
@@ -976,11 +1180,6 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 			bc.reportBlock(block, receipts, err)
 			return i, err
 		}
-		// Write state changes to database
-		if _, err = state.CommitTo(bc.chainDb, bc.config.IsEIP158(block.Number())); err != nil {
-			return i, err
-		}
-
 		// coalesce logs for later processing
 		coalescedLogs = append(coalescedLogs, logs...)
 
@@ -988,8 +1187,8 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 			return i, err
 		}
 
-		// write the block to the chain and get the status
-		status, err := bc.WriteBlock(block)
+		// write the block and its state to the chain and get the status
+		status, err := bc.WriteBlockWithState(block, state)
 		if err != nil {
 			return i, err
 		}
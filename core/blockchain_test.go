@@ -1151,3 +1151,47 @@ func TestEIP161AccountRemoval(t *testing.T) {
 		t.Error("account should not exist")
 	}
 }
+
+// Tests that the total difficulties derived for the ancestors of a checkpoint
+// from a lower bound stay above the genesis.
+func TestInsertCheckpointTd(t *testing.T) {
+	gspec, blocks := makeStateChain(10)
+
+	fulldb, _ := ethdb.NewMemDatabase()
+	gspec.MustCommit(fulldb)
+	full, _ := NewBlockChain(fulldb, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer full.Stop()
+
+	if _, err := full.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Start an empty chain with the state of the checkpoint synced
+	db, _ := ethdb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+	for _, key := range fulldb.Keys() {
+		if len(key) == common.HashLength {
+			value, _ := fulldb.Get(key)
+			db.Put(key, value)
+		}
+	}
+	chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer chain.Stop()
+
+	var ancestors []*types.Header
+	for _, block := range blocks[:9] {
+		ancestors = append(ancestors, block.Header())
+	}
+	checkpoint := blocks[9]
+	td := new(big.Int).Add(genesis.Difficulty(), big.NewInt(10))
+	if err := chain.InsertCheckpoint(ancestors, checkpoint, td); err != nil {
+		t.Fatalf("failed to insert checkpoint: %v", err)
+	}
+	if have := chain.GetTd(checkpoint.Hash(), checkpoint.NumberU64()); have.Cmp(td) != 0 {
+		t.Errorf("checkpoint td mismatch: have %v, want %v", have, td)
+	}
+	for _, header := range ancestors {
+		if have := chain.GetTd(header.Hash(), header.Number.Uint64()); have.Cmp(genesis.Difficulty()) <= 0 {
+			t.Errorf("block %d: td %v not above the genesis %v", header.Number, have, genesis.Difficulty())
+		}
+	}
+}
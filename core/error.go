@@ -28,4 +28,16 @@ var (
 
 	// ErrBlacklistedHash is returned if a block to import is on the blacklist.
 	ErrBlacklistedHash = errors.New("blacklisted hash")
+
+	// ErrGasPriceNotZero is returned if a transaction offers a gas price on a
+	// chain whose gas is free.
+	ErrGasPriceNotZero = errors.New("gas price must be zero")
+
+	// ErrPrivateDisabled is returned if a private transaction is included before
+	// the chain accepts private transactions.
+	ErrPrivateDisabled = errors.New("private transactions not enabled")
+
+	// ErrPrivateValue is returned if a private transaction transfers value, which
+	// only exists in the public state private transactions do not touch.
+	ErrPrivateValue = errors.New("private transaction with value")
 )
@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -106,6 +107,11 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	if genesis != nil && genesis.Config == nil {
 		return params.AllProtocolChanges, common.Hash{}, errGenesisNoConfig
 	}
+	if genesis != nil {
+		if err := vm.ValidatePrecompiles(genesis.Config); err != nil {
+			return params.AllProtocolChanges, common.Hash{}, err
+		}
+	}
 
 	// Just commit the new block if there is no stored genesis block.
 	stored := GetCanonicalHash(db, 0)
@@ -144,7 +150,7 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	// config is supplied. These chains would get AllProtocolChanges (and a compat error)
 	// if we just continued here.
 	if genesis == nil && stored != params.MainNetGenesisHash {
-		return storedcfg, stored, nil
+		return storedcfg, stored, vm.ValidatePrecompiles(storedcfg)
 	}
 
 	// Check config compatibility and write the config. Compatibility errors
@@ -185,6 +191,9 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB) {
 			statedb.SetState(addr, key, value)
 		}
 	}
+	if g.Config != nil {
+		setupPermissionRegistry(statedb, g.Config.Permissions)
+	}
 	root := statedb.IntermediateRoot(false)
 	head := &types.Header{
 		Nonce:      types.EncodeNonce(g.Nonce),
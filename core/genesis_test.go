@@ -159,3 +159,27 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the native contracts of a stored chain config are validated when
+// the node starts without a genesis.
+func TestSetupGenesisStoredPrecompiles(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	genesis := Genesis{Config: &params.ChainConfig{HomesteadBlock: big.NewInt(3)}}
+	hash := genesis.MustCommit(db).Hash()
+
+	config := *genesis.Config
+	config.Precompiles = []*params.PrecompileConfig{{Name: "unknown", Address: common.Address{1, 0}}}
+	if err := WriteChainConfig(db, hash, &config); err != nil {
+		t.Fatalf("failed to write chain config: %v", err)
+	}
+	if _, _, err := SetupGenesisBlock(db, nil); err == nil {
+		t.Error("stored config with unknown native contract accepted")
+	}
+	config.Precompiles[0].Name = "sm3"
+	if err := WriteChainConfig(db, hash, &config); err != nil {
+		t.Fatalf("failed to write chain config: %v", err)
+	}
+	if _, _, err := SetupGenesisBlock(db, nil); err != nil {
+		t.Errorf("stored config rejected: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Permission flags of an account.
+const (
+	PermissionSend   uint64 = 1 << iota // Send transactions
+	PermissionCreate                    // Deploy contracts
+	PermissionAdmin                     // Update the permission registry
+	PermissionNode                      // Connect to the network, for the address of a node key
+)
+
+var (
+	// ErrSendNotPermitted is returned if the sender of a transaction is not
+	// permitted to send transactions.
+	ErrSendNotPermitted = errors.New("sender not permitted")
+
+	// ErrCreateNotPermitted is returned if the sender of a contract creation is
+	// not permitted to deploy contracts.
+	ErrCreateNotPermitted = errors.New("contract creation not permitted")
+)
+
+// PermissionRegistryCode is the runtime code of the permission registry. It
+// keeps the flags of the accounts in a mapping at slot 0, as a Solidity
+// contract would, and implements the single method
+//
+//	setPermissions(address account, uint256 flags)
+//
+// which is only accepted from accounts holding PermissionAdmin.
+var PermissionRegistryCode = common.FromHex("60003560e060020a900463e19267ed14156051573360005260006020526040600020546004161560515760043573ffffffffffffffffffffffffffffffffffffffff1660005260406000206024359055005bfe")
+
+// setPermissionsID is the method ID of setPermissions(address,uint256).
+var setPermissionsID = crypto.Keccak256([]byte("setPermissions(address,uint256)"))[:4]
+
+// PermissionKey returns the registry storage slot of the flags of an account.
+func PermissionKey(addr common.Address) common.Hash {
+	return crypto.Keccak256Hash(common.LeftPadBytes(addr[:], 32), make([]byte, 32))
+}
+
+// SetPermissionsData returns the input of a registry call updating the flags of
+// an account.
+func SetPermissionsData(addr common.Address, flags uint64) []byte {
+	data := make([]byte, 0, 4+2*32)
+	data = append(data, setPermissionsID...)
+	data = append(data, common.LeftPadBytes(addr[:], 32)...)
+	return append(data, common.LeftPadBytes(new(big.Int).SetUint64(flags).Bytes(), 32)...)
+}
+
+// Permissions returns the permission flags of an account, read from the
+// registry if there is one or from the lists of the config otherwise.
+func Permissions(config *params.PermissionConfig, statedb vm.StateDB, addr common.Address) uint64 {
+	if config.Registry != nil {
+		flags := statedb.GetState(*config.Registry, PermissionKey(addr))
+		return binary.BigEndian.Uint64(flags[common.HashLength-8:])
+	}
+	var flags uint64
+	for _, sender := range config.Senders {
+		if sender == addr {
+			flags |= PermissionSend
+		}
+	}
+	for _, creator := range config.Creators {
+		if creator == addr {
+			flags |= PermissionCreate
+		}
+	}
+	for _, node := range config.Nodes {
+		if node == addr {
+			flags |= PermissionNode
+		}
+	}
+	return flags
+}
+
+// CheckPermission checks that from may send a transaction in block num, or
+// deploy a contract if contractCreation is set.
+func CheckPermission(config *params.ChainConfig, num *big.Int, statedb vm.StateDB, from common.Address, contractCreation bool) error {
+	if !config.IsPermissioned(num) {
+		return nil
+	}
+	return checkPermission(config.Permissions, statedb, from, contractCreation)
+}
+
+func checkPermission(config *params.PermissionConfig, statedb vm.StateDB, from common.Address, contractCreation bool) error {
+	flags := Permissions(config, statedb, from)
+	if contractCreation {
+		if flags&PermissionCreate == 0 {
+			return ErrCreateNotPermitted
+		}
+		return nil
+	}
+	if flags&PermissionSend == 0 {
+		return ErrSendNotPermitted
+	}
+	return nil
+}
+
+// setupPermissionRegistry deploys the permission registry in the genesis state,
+// unless the genesis allocates code there itself, and seeds it with the lists
+// of the config. Admins are permitted to send the transactions updating it.
+func setupPermissionRegistry(statedb vm.StateDB, config *params.PermissionConfig) {
+	if config == nil || config.Registry == nil {
+		return
+	}
+	registry := *config.Registry
+	if statedb.GetCodeSize(registry) == 0 {
+		statedb.SetCode(registry, PermissionRegistryCode)
+	}
+	seed := make(map[common.Address]uint64)
+	for _, addr := range config.Senders {
+		seed[addr] |= PermissionSend
+	}
+	for _, addr := range config.Creators {
+		seed[addr] |= PermissionCreate
+	}
+	for _, addr := range config.Admins {
+		seed[addr] |= PermissionAdmin | PermissionSend
+	}
+	for _, addr := range config.Nodes {
+		seed[addr] |= PermissionNode
+	}
+	for addr, flags := range seed {
+		statedb.SetState(registry, PermissionKey(addr), common.BigToHash(new(big.Int).SetUint64(flags)))
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the permission registry is seeded in the genesis and updated by
+// admins only.
+func TestPermissionRegistry(t *testing.T) {
+	var (
+		adminKey, _ = crypto.GenerateKey()
+		userKey, _  = crypto.GenerateKey()
+		admin       = crypto.PubkeyToAddress(adminKey.PublicKey)
+		user        = crypto.PubkeyToAddress(userKey.PublicKey)
+		registry    = common.HexToAddress("0x0000000000000000000000000000000000000a11")
+		signer      = types.HomesteadSigner{}
+		db, _       = ethdb.NewMemDatabase()
+		config      = *params.TestChainConfig
+	)
+	config.Permissions = &params.PermissionConfig{Admins: []common.Address{admin}, Registry: &registry}
+
+	genesis := (&Genesis{
+		Config: &config,
+		Alloc:  GenesisAlloc{admin: {Balance: big.NewInt(1000000000)}, user: {Balance: big.NewInt(1000000000)}},
+	}).MustCommit(db)
+
+	blocks, _ := GenerateChain(&config, genesis, db, 2, func(i int, b *BlockGen) {
+		switch i {
+		case 0:
+			// The admin permits the user to send and deploy
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(admin), registry, new(big.Int), big.NewInt(100000), new(big.Int), SetPermissionsData(user, PermissionSend|PermissionCreate)), signer, adminKey)
+			b.AddTx(tx)
+		case 1:
+			// The user cannot grant itself admin rights
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(user), registry, new(big.Int), big.NewInt(100000), new(big.Int), SetPermissionsData(user, PermissionSend|PermissionAdmin)), signer, userKey)
+			b.AddTx(tx)
+		}
+	})
+	statedb, _ := state.New(genesis.Root(), state.NewDatabase(db))
+	if flags := Permissions(config.Permissions, statedb, admin); flags != PermissionAdmin|PermissionSend {
+		t.Errorf("genesis admin flags mismatch: have %b, want %b", flags, PermissionAdmin|PermissionSend)
+	}
+	if err := CheckPermission(&config, common.Big0, statedb, user, false); err != ErrSendNotPermitted {
+		t.Errorf("genesis user error mismatch: have %v, want %v", err, ErrSendNotPermitted)
+	}
+	statedb, _ = state.New(blocks[1].Root(), state.NewDatabase(db))
+	if flags := Permissions(config.Permissions, statedb, user); flags != PermissionSend|PermissionCreate {
+		t.Errorf("user flags mismatch: have %b, want %b", flags, PermissionSend|PermissionCreate)
+	}
+}
+
+// Tests that transactions of accounts missing the permissions are rejected in
+// consensus, while calls are not restricted.
+func TestPermissionEnforcement(t *testing.T) {
+	var (
+		sender  = common.HexToAddress("0x01")
+		creator = common.HexToAddress("0x02")
+		other   = common.HexToAddress("0x03")
+		config  = *params.TestChainConfig
+	)
+	config.Permissions = &params.PermissionConfig{Senders: []common.Address{sender}, Creators: []common.Address{creator}}
+	config.PermissionBlock = big.NewInt(2)
+
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	for _, addr := range []common.Address{sender, creator, other} {
+		statedb.AddBalance(addr, big.NewInt(1000000000))
+	}
+	tests := []struct {
+		from       common.Address
+		to         *common.Address
+		number     int64
+		checkNonce bool
+		err        error
+	}{
+		{sender, &other, 2, true, nil},
+		{sender, nil, 2, true, ErrCreateNotPermitted},
+		{creator, nil, 2, true, nil},
+		{creator, &other, 2, true, ErrSendNotPermitted},
+		{other, &sender, 2, true, ErrSendNotPermitted},
+		{other, &sender, 1, true, nil},  // before the permission block
+		{other, &sender, 2, false, nil}, // calls
+	}
+	for i, tt := range tests {
+		msg := types.NewMessage(tt.from, tt.to, statedb.GetNonce(tt.from), new(big.Int), big.NewInt(100000), new(big.Int), nil, tt.checkNonce)
+		context := vm.Context{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			BlockNumber: big.NewInt(tt.number),
+			Time:        new(big.Int),
+			Difficulty:  new(big.Int),
+			GasLimit:    big.NewInt(1000000),
+			GasPrice:    new(big.Int),
+		}
+		evm := vm.NewEVM(context, statedb, &config, vm.Config{})
+		if _, _, err := ApplyMessage(evm, msg, new(GasPool).AddGas(big.NewInt(1000000))); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that the pool rejects transactions of senders not permitted.
+func TestPermissionedTransactionPool(t *testing.T) {
+	pool, key := setupTxPool()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.Permissions = &params.PermissionConfig{}
+	pool.chainconfig, pool.permissioned = &config, true
+
+	currentState, _ := pool.currentState()
+	currentState.AddBalance(from, big.NewInt(1000000))
+
+	if err := pool.Add(transaction(0, big.NewInt(100000), key)); err != ErrSendNotPermitted {
+		t.Errorf("unpermitted sender error mismatch: have %v, want %v", err, ErrSendNotPermitted)
+	}
+	config.Permissions.Senders = []common.Address{from}
+	if err := pool.Add(transaction(0, big.NewInt(100000), key)); err != nil {
+		t.Errorf("permitted sender rejected: %v", err)
+	}
+}
+
+// Tests that a pool started on a chain already past the permission block
+// enforces the permission config before the next head event.
+func TestPermissionedTransactionPoolStart(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	config := *params.TestChainConfig
+	config.PermissionBlock = big.NewInt(5)
+	config.Permissions = &params.PermissionConfig{}
+
+	for i, number := range []int64{3, 4, 10} {
+		head := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)})
+		pool := NewTxPool(testTxPoolConfig, &config, new(event.TypeMux), func() (*state.StateDB, error) { return statedb, nil }, func() *big.Int { return big.NewInt(1000000) }, func() *types.Block { return head })
+		if want := number >= 4; pool.permissioned != want {
+			t.Errorf("test %d: head %d permissioned mismatch: have %v, want %v", i, number, pool.permissioned, want)
+		}
+		pool.Stop()
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/private"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	privateRootPrefix        = []byte("private-root-")        // privateRootPrefix + block hash -> private state root
+	privateReceiptPrefix     = []byte("private-receipts-")    // privateReceiptPrefix + tx hash -> private receipt
+	privateUnavailablePrefix = []byte("private-unavailable-") // privateUnavailablePrefix + block hash -> private state failure
+)
+
+// errPrivateStateUnavailable is returned for the private state of a block whose
+// private transactions could not be executed, e.g. because the transaction
+// manager was unreachable. The private states of its descendants derive from it
+// and are unavailable as well.
+var errPrivateStateUnavailable = errors.New("private state unavailable")
+
+// GetPrivateStateRoot retrieves the root of the private state after the given
+// block, or the empty root if no private transaction was ever processed.
+func GetPrivateStateRoot(db ethdb.Database, hash common.Hash) common.Hash {
+	data, _ := db.Get(append(privateRootPrefix, hash[:]...))
+	return common.BytesToHash(data)
+}
+
+// WritePrivateStateRoot stores the root of the private state after the given block.
+func WritePrivateStateRoot(db ethdb.Database, hash common.Hash, root common.Hash) error {
+	return db.Put(append(privateRootPrefix, hash[:]...), root[:])
+}
+
+// markPrivateStateUnavailable records that the private state after the given
+// block could not be computed.
+func markPrivateStateUnavailable(db ethdb.Database, hash common.Hash) error {
+	return db.Put(append(privateUnavailablePrefix, hash[:]...), []byte{1})
+}
+
+// GetPrivateReceipt retrieves the receipt of the private execution of a
+// transaction, known only to the participants of the transaction.
+func GetPrivateReceipt(db ethdb.Database, hash common.Hash) *types.Receipt {
+	data, _ := db.Get(append(privateReceiptPrefix, hash[:]...))
+	if len(data) == 0 {
+		return nil
+	}
+	var receipt types.ReceiptForStorage
+	if err := rlp.DecodeBytes(data, &receipt); err != nil {
+		log.Error("Invalid private receipt RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return (*types.Receipt)(&receipt)
+}
+
+// WritePrivateReceipts stores the receipts of private executions.
+func WritePrivateReceipts(db ethdb.Database, receipts types.Receipts) error {
+	batch := db.NewBatch()
+	for _, receipt := range receipts {
+		data, err := rlp.EncodeToBytes((*types.ReceiptForStorage)(receipt))
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(append(privateReceiptPrefix, receipt.TxHash[:]...), data); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// SetPrivateManager sets the transaction manager delivering the payloads of the
+// private transactions the local node participates in. Without one, private
+// transactions only have their public effects. The private states lost in a
+// crash since the last flush are rebuilt once the manager is known.
+func (bc *BlockChain) SetPrivateManager(manager private.TransactionManager) {
+	bc.procmu.Lock()
+	bc.privateManager = manager
+	bc.procmu.Unlock()
+
+	if manager != nil {
+		bc.reprocessPrivate()
+	}
+}
+
+// reprocessPrivate rebuilds the private states of the canonical blocks whose
+// public states were reprocessed on startup, before the transaction manager
+// was set up. The rebuilt states are flushed to disk like the public ones.
+func (bc *BlockChain) reprocessPrivate() {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	var blocks types.Blocks
+	for block := bc.CurrentBlock(); block.NumberU64() > 0; {
+		root := GetPrivateStateRoot(bc.chainDb, block.Hash())
+		if root == (common.Hash{}) {
+			break
+		}
+		if _, err := state.New(root, bc.stateCache); err == nil {
+			break
+		}
+		blocks = append(blocks, block)
+		if block = bc.GetBlock(block.ParentHash(), block.NumberU64()-1); block == nil {
+			return
+		}
+	}
+	if len(blocks) == 0 {
+		return
+	}
+	log.Info("Reprocessing private states lost since the last state flush", "count", len(blocks), "from", blocks[len(blocks)-1].Number())
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+
+		root, err := bc.processPrivateTransactions(block)
+		if err == nil {
+			err = bc.triecache.Commit(root)
+			bc.triecache.Dereference(root)
+		}
+		if err != nil {
+			log.Error("Failed to reprocess private transactions", "number", block.Number(), "hash", block.Hash(), "err", err)
+			if err := markPrivateStateUnavailable(bc.chainDb, block.Hash()); err != nil {
+				log.Crit("Failed to mark private state unavailable", "err", err)
+			}
+		}
+	}
+}
+
+// PrivateManager returns the transaction manager of the private transactions.
+func (bc *BlockChain) PrivateManager() private.TransactionManager {
+	bc.procmu.RLock()
+	defer bc.procmu.RUnlock()
+	return bc.privateManager
+}
+
+// PrivateStateAt returns the private state after the given block.
+func (bc *BlockChain) PrivateStateAt(hash common.Hash) (*state.StateDB, error) {
+	if data, _ := bc.chainDb.Get(append(privateUnavailablePrefix, hash[:]...)); len(data) > 0 {
+		return nil, errPrivateStateUnavailable
+	}
+	return state.New(GetPrivateStateRoot(bc.chainDb, hash), bc.stateCache)
+}
+
+// processPrivateTransactions executes the private transactions of a block the
+// local node participates in against the private state of its parent, and
+// stores the resulting private state and receipts. The private state goes into
+// the trie cache, to be garbage collected along with the public one. It is held
+// in memory until the caller releases the returned root.
+func (bc *BlockChain) processPrivateTransactions(block *types.Block) (common.Hash, error) {
+	manager := bc.PrivateManager()
+	if manager == nil {
+		return common.Hash{}, nil
+	}
+	privateState, err := bc.PrivateStateAt(block.ParentHash())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var receipts types.Receipts
+	for i, tx := range block.Transactions() {
+		if !tx.IsPrivate() {
+			continue
+		}
+		privateState.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, err := ApplyPrivateTransaction(bc.config, bc, manager, nil, privateState, block.Header(), tx, bc.vmConfig)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if receipt != nil {
+			receipts = append(receipts, receipt)
+		}
+	}
+	root, err := privateState.CommitTo(bc.triecache, bc.config.IsEIP158(block.Number()))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	bc.triecache.Reference(root)
+
+	if err := WritePrivateStateRoot(bc.chainDb, block.Hash(), root); err != nil {
+		bc.triecache.Dereference(root)
+		return common.Hash{}, err
+	}
+	if err := WritePrivateReceipts(bc.chainDb, receipts); err != nil {
+		bc.triecache.Dereference(root)
+		return common.Hash{}, err
+	}
+	return root, nil
+}
+
+// ApplyPrivateTransaction executes the payload of a private transaction against
+// the private state, if the local node is one of its participants. It returns
+// the private receipt of the transaction, or nil if the node does not take part
+// in it. Failed executions are only logged, as other nodes cannot verify them.
+func ApplyPrivateTransaction(config *params.ChainConfig, bc *BlockChain, manager private.TransactionManager, author *common.Address, privateState *state.StateDB, header *types.Header, tx *types.Transaction, cfg vm.Config) (*types.Receipt, error) {
+	payload, err := manager.Receive(tx.Data())
+	if err != nil || payload == nil {
+		return nil, err
+	}
+	from, err := types.Sender(types.MakeSigner(config, header.Number), tx)
+	if err != nil {
+		return nil, err
+	}
+	// Private executions are free, their gas was paid publicly
+	msg := types.NewMessage(from, tx.To(), tx.Nonce(), tx.Value(), tx.Gas(), new(big.Int), payload, false)
+
+	// Align the private nonce of the sender with the public one, so contracts
+	// get the addresses derived from the nonce of the transaction.
+	privateState.SetNonce(from, tx.Nonce())
+	snap := privateState.Snapshot()
+
+	vmenv := vm.NewEVM(NewEVMContext(msg, header, bc, author), privateState, config, cfg)
+	_, gas, err := ApplyMessage(vmenv, msg, new(GasPool).AddGas(tx.Gas()))
+	if err != nil {
+		log.Warn("Failed to apply private transaction", "hash", tx.Hash(), "err", err)
+		privateState.RevertToSnapshot(snap)
+		return nil, nil
+	}
+	root := privateState.IntermediateRoot(config.IsEIP158(header.Number))
+	receipt := types.NewReceipt(root.Bytes(), gas)
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = new(big.Int).Set(gas)
+	if msg.To() == nil {
+		receipt.ContractAddress = crypto.CreateAddress(from, tx.Nonce())
+	}
+	receipt.Logs = privateState.GetLogs(tx.Hash())
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+	return receipt, nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/private"
+)
+
+// Tests that private transactions only bump the nonce publicly, and are
+// executed against the private state of their participants alone.
+func TestPrivateTransactions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		key, _  = crypto.GenerateKey()
+		from    = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: privateChainConfig(), Alloc: GenesisAlloc{from: {Balance: big.NewInt(1000000000)}}}
+		db, _   = ethdb.NewMemDatabase()
+		genesis = gspec.MustCommit(db)
+	)
+	alice, _ := private.NewFileManager(dir, "alice")
+	bob, _ := private.NewFileManager(dir, "bob")
+	carol, _ := private.NewFileManager(dir, "carol")
+
+	// Alice privately deploys a contract storing 42 in its first slot for Bob
+	payload, err := alice.Send(common.Hex2Bytes("602a600055"), "alice", []string{"bob"})
+	if err != nil {
+		t.Fatalf("failed to send payload: %v", err)
+	}
+	tx, _ := types.SignTx(types.NewContractCreation(0, new(big.Int), big.NewInt(100000), new(big.Int), payload), types.NewPrivateSigner(gspec.Config.ChainId), key)
+	contract := crypto.CreateAddress(from, 0)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, db, 1, func(i int, b *BlockGen) { b.AddTx(tx) })
+
+	for _, tt := range []struct {
+		manager     private.TransactionManager
+		participant bool
+	}{
+		{alice, true}, {bob, true}, {carol, false}, {nil, false},
+	} {
+		db, _ := ethdb.NewMemDatabase()
+		gspec.MustCommit(db)
+
+		chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+		chain.SetPrivateManager(tt.manager)
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+		// The public state only sees the nonce bump
+		statedb, _ := chain.State()
+		if nonce := statedb.GetNonce(from); nonce != 1 {
+			t.Errorf("public nonce mismatch: have %d, want 1", nonce)
+		}
+		if statedb.Exist(contract) {
+			t.Errorf("private contract exists in public state")
+		}
+		// The private state holds the contract for the participants only
+		privateState, _ := chain.PrivateStateAt(blocks[0].Hash())
+		if value := privateState.GetState(contract, common.Hash{}); (value == common.BigToHash(big.NewInt(42))) != tt.participant {
+			t.Errorf("participant %v: private storage mismatch: have %x", tt.participant, value)
+		}
+		receipt := GetPrivateReceipt(db, tx.Hash())
+		if (receipt != nil) != tt.participant {
+			t.Errorf("participant %v: private receipt presence mismatch", tt.participant)
+		} else if receipt != nil && receipt.ContractAddress != contract {
+			t.Errorf("private receipt contract mismatch: have %x, want %x", receipt.ContractAddress, contract)
+		}
+	}
+}
+
+// Tests that the private states lost in a crash since the last flush are
+// rebuilt once the transaction manager is set up.
+func TestPrivateStateReprocess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		key, _  = crypto.GenerateKey()
+		from    = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: privateChainConfig(), Alloc: GenesisAlloc{from: {Balance: big.NewInt(1000000000)}}}
+		db, _   = ethdb.NewMemDatabase()
+		genesis = gspec.MustCommit(db)
+	)
+	alice, _ := private.NewFileManager(dir, "alice")
+	payload, err := alice.Send(common.Hex2Bytes("602a600055"), "alice", []string{"alice"})
+	if err != nil {
+		t.Fatalf("failed to send payload: %v", err)
+	}
+	tx, _ := types.SignTx(types.NewContractCreation(0, new(big.Int), big.NewInt(100000), new(big.Int), payload), types.NewPrivateSigner(gspec.Config.ChainId), key)
+	contract := crypto.CreateAddress(from, 0)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, db, 5, func(i int, b *BlockGen) {
+		if i == 1 {
+			b.AddTx(tx)
+		}
+	})
+	db, _ = ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	chain.SetPrivateManager(alice)
+	chain.SetStateGC(StateGCConfig{Recent: 16, FlushInterval: 20, MemoryLimit: 256 * 1024 * 1024})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Without a clean shutdown the states of all blocks are lost
+	crashed, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer crashed.Stop()
+
+	head := blocks[len(blocks)-1]
+	if _, err := crashed.PrivateStateAt(head.Hash()); err == nil {
+		t.Fatalf("private state available before the manager is set")
+	}
+	crashed.SetPrivateManager(alice)
+
+	privateState, err := crashed.PrivateStateAt(head.Hash())
+	if err != nil {
+		t.Fatalf("private state unavailable: %v", err)
+	}
+	if value := privateState.GetState(contract, common.Hash{}); value != common.BigToHash(big.NewInt(42)) {
+		t.Errorf("private storage mismatch: have %x", value)
+	}
+}
+
+// Tests that blocks including private transactions which transfer value are
+// rejected on import, not only by the transaction pool.
+func TestPrivateValueRejected(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		from    = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: privateChainConfig(), Alloc: GenesisAlloc{from: {Balance: big.NewInt(1000000000)}}}
+		db, _   = ethdb.NewMemDatabase()
+		genesis = gspec.MustCommit(db)
+	)
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), big.NewInt(100000), new(big.Int), nil), types.NewPrivateSigner(gspec.Config.ChainId), key)
+
+	// The block generator refuses invalid transactions, so include it by hand
+	blocks, _ := GenerateChain(gspec.Config, genesis, db, 1, nil)
+	header := blocks[0].Header()
+	header.TxHash = types.DeriveSha(types.Transactions{tx})
+	block := types.NewBlockWithHeader(header).WithBody(types.Transactions{tx}, nil)
+
+	chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(types.Blocks{block}); err != ErrPrivateValue {
+		t.Fatalf("import error mismatch: have %v, want %v", err, ErrPrivateValue)
+	}
+	if chain.CurrentBlock().NumberU64() != 0 {
+		t.Errorf("block with private value imported")
+	}
+}
+
+// privateChainConfig returns a test chain configuration accepting private
+// transactions from the genesis.
+func privateChainConfig() *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.PrivateBlock = new(big.Int)
+	return &config
+}
+
+// failingManager is a transaction manager which cannot be reached.
+type failingManager struct{}
+
+func (failingManager) Send(payload []byte, from string, to []string) ([]byte, error) {
+	return nil, errors.New("unreachable")
+}
+
+func (failingManager) Receive(key []byte) ([]byte, error) {
+	return nil, errors.New("unreachable")
+}
+
+// Tests that an unreachable transaction manager does not stop the import of the
+// public chain, only leaving the private states unavailable.
+func TestPrivateManagerFailure(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		from    = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: privateChainConfig(), Alloc: GenesisAlloc{from: {Balance: big.NewInt(1000000000)}}}
+		db, _   = ethdb.NewMemDatabase()
+		genesis = gspec.MustCommit(db)
+	)
+	tx, _ := types.SignTx(types.NewContractCreation(0, new(big.Int), big.NewInt(100000), new(big.Int), []byte("key")), types.NewPrivateSigner(gspec.Config.ChainId), key)
+	blocks, _ := GenerateChain(gspec.Config, genesis, db, 2, func(i int, b *BlockGen) {
+		if i == 0 {
+			b.AddTx(tx)
+		}
+	})
+	db, _ = ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	chain.SetPrivateManager(failingManager{})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 2 {
+		t.Errorf("head mismatch: have %d, want 2", head)
+	}
+	for _, block := range blocks {
+		if _, err := chain.PrivateStateAt(block.Hash()); err != errPrivateStateUnavailable {
+			t.Errorf("block %d: private state error mismatch: have %v, want %v", block.NumberU64(), err, errPrivateStateUnavailable)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 	lru "github.com/hashicorp/golang-lru"
 )
@@ -60,18 +61,31 @@ type Trie interface {
 	CommitTo(trie.DatabaseWriter) (common.Hash, error)
 	Hash() common.Hash
 	NodeIterator(startKey []byte) trie.NodeIterator
+	TryProve(key []byte) ([]rlp.RawValue, error)
 	GetKey([]byte) []byte // TODO(fjl): remove this when SecureTrie is removed
 }
 
 // NewDatabase creates a backing store for state. The returned database is safe for
 // concurrent use and retains cached trie nodes in memory.
-func NewDatabase(db ethdb.Database) Database {
+func NewDatabase(db trie.Database) Database {
 	csc, _ := lru.New(codeSizeCacheSize)
 	return &cachingDB{db: db, codeSizeCache: csc}
 }
 
+// NewTrieCache creates a reference counted cache of state trie nodes on top of
+// db, tracking the storage tries and code referenced by the accounts.
+func NewTrieCache(db ethdb.Database) *trie.NodeCache {
+	return trie.NewNodeCache(db, func(leaf []byte) []common.Hash {
+		var account Account
+		if err := rlp.DecodeBytes(leaf, &account); err != nil {
+			return nil
+		}
+		return []common.Hash{account.Root, common.BytesToHash(account.CodeHash)}
+	})
+}
+
 type cachingDB struct {
-	db            ethdb.Database
+	db            trie.Database
 	mu            sync.Mutex
 	pastTries     []*trie.SecureTrie
 	codeSizeCache *lru.Cache
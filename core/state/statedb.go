@@ -250,6 +250,25 @@ func (self *StateDB) StorageTrie(a common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// GetProof returns the Merkle proof of the account at the given address
+// against the state root. Modifications not yet hashed into the account trie
+// are not reflected. Missing trie nodes, e.g. of pruned states, are reported
+// as an error instead of yielding an incomplete proof.
+func (self *StateDB) GetProof(a common.Address) ([]rlp.RawValue, error) {
+	return self.trie.TryProve(a[:])
+}
+
+// GetStorageProof returns the Merkle proof of the given storage slot of an
+// account against its storage root. Non-existent accounts have no storage to
+// prove and return nil.
+func (self *StateDB) GetStorageProof(a common.Address, key common.Hash) ([]rlp.RawValue, error) {
+	trie := self.StorageTrie(a)
+	if trie == nil {
+		return nil, nil
+	}
+	return trie.TryProve(key[:])
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
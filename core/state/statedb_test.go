@@ -32,7 +32,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -372,3 +375,80 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+// Tests that the account and storage proofs verify against the state root and
+// the storage root of the account respectively.
+func TestGetProof(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	addr := common.BytesToAddress([]byte{0x01})
+	state.SetBalance(addr, big.NewInt(42))
+	state.SetState(addr, common.BytesToHash([]byte{0x02}), common.BytesToHash([]byte{0x03}))
+	root, _ := state.CommitTo(db, false)
+	state, _ = New(root, NewDatabase(db))
+
+	// Verify the account proof
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	value, err := trie.VerifyProof(root, crypto.Keccak256(addr[:]), proof)
+	if err != nil {
+		t.Fatalf("failed to verify account proof: %v", err)
+	}
+	var account Account
+	if err := rlp.DecodeBytes(value, &account); err != nil {
+		t.Fatalf("failed to decode proven account: %v", err)
+	}
+	if account.Balance.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("proven balance mismatch: have %v, want 42", account.Balance)
+	}
+	// Verify the storage proof
+	key := common.BytesToHash([]byte{0x02})
+	if proof, err = state.GetStorageProof(addr, key); err != nil {
+		t.Fatalf("failed to prove storage slot: %v", err)
+	}
+	value, err = trie.VerifyProof(account.Root, crypto.Keccak256(key[:]), proof)
+	if err != nil {
+		t.Fatalf("failed to verify storage proof: %v", err)
+	}
+	var slot []byte
+	if err := rlp.DecodeBytes(value, &slot); err != nil {
+		t.Fatalf("failed to decode proven slot: %v", err)
+	}
+	if common.BytesToHash(slot) != common.BytesToHash([]byte{0x03}) {
+		t.Errorf("proven slot mismatch: have %x, want 03", slot)
+	}
+	// Missing accounts have no storage to prove
+	if proof, err := state.GetStorageProof(common.BytesToAddress([]byte{0xff}), key); proof != nil || err != nil {
+		t.Errorf("storage proof of missing account: %x, %v", proof, err)
+	}
+}
+
+// Tests that proving an account of a partially pruned state fails instead of
+// returning an incomplete proof.
+func TestGetProofMissingNode(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	for i := byte(1); i <= 50; i++ {
+		state.SetBalance(common.BytesToAddress([]byte{i}), big.NewInt(int64(i)))
+	}
+	root, _ := state.CommitTo(db, false)
+	state, _ = New(root, NewDatabase(db))
+
+	addr := common.BytesToAddress([]byte{0x01})
+	proof, err := state.GetProof(addr)
+	if err != nil || len(proof) < 2 {
+		t.Fatalf("failed to prove account: %d nodes, %v", len(proof), err)
+	}
+	db.Delete(crypto.Keccak256(proof[1]))
+
+	state, _ = New(root, NewDatabase(db))
+	if _, err := state.GetProof(addr); err == nil {
+		t.Errorf("proof of pruned account succeeded")
+	} else if _, ok := err.(*trie.MissingNodeError); !ok {
+		t.Errorf("proof error mismatch: have %v, want missing node", err)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// minRecentStates is the lowest number of recent states kept in memory. It
+// must exceed the tries reused by the state database between imports, which
+// are assumed to be still available.
+const minRecentStates = 16
+
+// StateGCConfig are the parameters of the garbage collection of the states of
+// the recent blocks, which are kept in memory instead of being written to disk.
+type StateGCConfig struct {
+	Recent        uint64             // Number of recent block states kept in memory
+	FlushInterval uint64             // Number of blocks between states flushed to disk
+	MemoryLimit   common.StorageSize // Memory allowance of the cached trie nodes, forcing a flush once exceeded
+	Diffs         bool               // Whether to store the state diffs of all blocks, to rebuild any historical state
+}
+
+// DefaultStateGCConfig contains the default garbage collection parameters.
+var DefaultStateGCConfig = StateGCConfig{
+	Recent:        128,
+	FlushInterval: 1024,
+	MemoryLimit:   256 * 1024 * 1024,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *StateGCConfig) sanitize() StateGCConfig {
+	conf := *config
+	if conf.Recent < minRecentStates {
+		log.Warn("Sanitizing invalid recent state count", "provided", conf.Recent, "updated", minRecentStates)
+		conf.Recent = minRecentStates
+	}
+	if conf.FlushInterval < 1 {
+		log.Warn("Sanitizing invalid state flush interval", "provided", conf.FlushInterval, "updated", DefaultStateGCConfig.FlushInterval)
+		conf.FlushInterval = DefaultStateGCConfig.FlushInterval
+	}
+	if conf.MemoryLimit <= 0 {
+		log.Warn("Sanitizing invalid state memory limit", "provided", conf.MemoryLimit, "updated", DefaultStateGCConfig.MemoryLimit)
+		conf.MemoryLimit = DefaultStateGCConfig.MemoryLimit
+	}
+	return conf
+}
+
+// SetStateGC enables the garbage collection of the states of the blocks
+// written from now on. Without it every state is flushed to disk (archive
+// mode), with it only every FlushInterval-th one is. Storing the state diffs
+// in between keeps all historical states available at a fraction of the cost.
+func (bc *BlockChain) SetStateGC(config StateGCConfig) {
+	config = (&config).sanitize()
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.gc = &config
+	bc.triegc = make(map[uint64][]common.Hash)
+	if head := bc.currentBlock.NumberU64(); head > config.Recent {
+		bc.gcTail = head - config.Recent
+	}
+}
+
+// TrieCache returns the cache the states of new blocks have to be committed
+// into before writing the blocks.
+func (bc *BlockChain) TrieCache() *trie.NodeCache {
+	return bc.triecache
+}
+
+// collectState takes care of the state of a freshly written block, along with
+// its private state if any. Archive nodes flush it right away, others only keep
+// it in memory until it drops out of the window of recent states, except for
+// the periodic flushes.
+//
+// Note, this method assumes the chain lock is held!
+func (bc *BlockChain) collectState(block *types.Block) error {
+	roots, number := bc.stateRoots(block), block.NumberU64()
+	if bc.gc == nil {
+		return bc.commitStates(roots)
+	}
+	if bc.gc.Diffs {
+		if err := bc.storeStateDiff(block); err != nil {
+			return err
+		}
+	}
+	// States of side blocks already out of the window go with the oldest ones
+	key := number
+	if key < bc.gcTail {
+		key = bc.gcTail
+	}
+	for _, root := range roots {
+		bc.triecache.Reference(root)
+	}
+	bc.triegc[key] = append(bc.triegc[key], roots...)
+
+	if nodes, size := bc.triecache.Size(); number%bc.gc.FlushInterval == 0 || size > bc.gc.MemoryLimit {
+		if err := bc.commitStates(roots); err != nil {
+			return err
+		}
+		log.Info("Flushed state to disk", "number", number, "hash", block.Hash(), "nodes", nodes, "size", size)
+	}
+	// Release the states which dropped out of the window
+	for ; bc.gcTail+bc.gc.Recent <= number; bc.gcTail++ {
+		for _, root := range bc.triegc[bc.gcTail] {
+			bc.triecache.Dereference(root)
+		}
+		delete(bc.triegc, bc.gcTail)
+	}
+	return nil
+}
+
+// stateRoots returns the roots of the public and, if the local node keeps one,
+// the private state after the block.
+func (bc *BlockChain) stateRoots(block *types.Block) []common.Hash {
+	roots := []common.Hash{block.Root()}
+	if root := GetPrivateStateRoot(bc.chainDb, block.Hash()); root != (common.Hash{}) {
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// commitStates flushes the states rooted at the given roots to disk.
+func (bc *BlockChain) commitStates(roots []common.Hash) error {
+	for _, root := range roots {
+		if err := bc.triecache.Commit(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushState writes the state of the head block to disk, so that the chain
+// does not have to be rewound and the recent blocks reprocessed on restart.
+func (bc *BlockChain) flushState() {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if bc.gc == nil {
+		return
+	}
+	if err := bc.commitStates(bc.stateRoots(bc.currentBlock)); err != nil {
+		log.Error("Failed to flush head state", "err", err)
+		return
+	}
+	log.Info("Flushed head state to disk", "number", bc.currentBlock.Number(), "hash", bc.currentBlock.Hash())
+}
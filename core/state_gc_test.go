@@ -0,0 +1,242 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeStateChain creates a genesis and a chain of n blocks on top of it, each
+// of them transferring funds to a new account.
+func makeStateChain(n int) (*Genesis, []*types.Block) {
+	var (
+		key, _ = crypto.GenerateKey()
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{from: {Balance: big.NewInt(1000000000)}}}
+		db, _  = ethdb.NewMemDatabase()
+		signer = types.HomesteadSigner{}
+	)
+	blocks, _ := GenerateChain(gspec.Config, gspec.MustCommit(db), db, n, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(from), common.BigToAddress(big.NewInt(int64(i+1))), big.NewInt(1000), bigTxGas, nil, nil), signer, key)
+		b.AddTx(tx)
+	})
+	return gspec, blocks
+}
+
+// Tests that with garbage collection enabled only the periodic states reach
+// the disk, the recent ones are kept in memory and the blocks since the last
+// flushed state are reprocessed after a crash.
+func TestStateGC(t *testing.T) {
+	gspec, blocks := makeStateChain(50)
+
+	db, _ := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	chain.SetStateGC(StateGCConfig{Recent: 16, FlushInterval: 20, MemoryLimit: 256 * 1024 * 1024})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for i, block := range blocks {
+		number := i + 1
+
+		_, err := trie.New(block.Root(), db)
+		if flushed := number%20 == 0; flushed != (err == nil) {
+			t.Errorf("block %d: flushed state mismatch: have %v, want %v", number, err == nil, flushed)
+		}
+		_, err = chain.StateAt(block.Root())
+		if available := number%20 == 0 || number > 50-16; available != (err == nil) {
+			t.Errorf("block %d: state availability mismatch: have %v, want %v", number, err == nil, available)
+		}
+	}
+	// Without a clean shutdown the blocks since the last flushed state are
+	// reprocessed, the head never falls behind
+	crashed, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if head := crashed.CurrentBlock().NumberU64(); head != 50 {
+		t.Fatalf("repaired head mismatch: have %d, want 50", head)
+	}
+	if _, err := crashed.State(); err != nil {
+		t.Fatalf("repaired head state missing: %v", err)
+	}
+	crashed.Stop()
+
+	// After a clean shutdown the head state is available right away
+	restarted, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer restarted.Stop()
+
+	if head := restarted.CurrentBlock().NumberU64(); head != 50 {
+		t.Fatalf("restarted head mismatch: have %d, want 50", head)
+	}
+}
+
+// Tests that the state of a block failing to be written is released from the
+// trie cache instead of being kept in memory forever.
+func TestStateGCRejectedBlock(t *testing.T) {
+	gspec, blocks := makeStateChain(5)
+
+	db, _ := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer chain.Stop()
+	chain.SetStateGC(StateGCConfig{Recent: 16, FlushInterval: 20, MemoryLimit: 256 * 1024 * 1024})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	nodes, size := chain.TrieCache().Size()
+
+	// Write a block of an unknown parent along with a state of its own
+	statedb, _ := chain.State()
+	statedb.AddBalance(common.Address{0xff}, big.NewInt(1))
+	block := types.NewBlockWithHeader(&types.Header{ParentHash: common.Hash{0xff}, Number: big.NewInt(7), Difficulty: big.NewInt(1)})
+
+	if _, err := chain.WriteBlockWithState(block, statedb); err != consensus.ErrUnknownAncestor {
+		t.Fatalf("write error mismatch: have %v, want %v", err, consensus.ErrUnknownAncestor)
+	}
+	if n, s := chain.TrieCache().Size(); n != nodes || s != size {
+		t.Errorf("trie cache size mismatch: have %d nodes (%v), want %d (%v)", n, s, nodes, size)
+	}
+}
+
+// Tests that with state diffs stored every historical state can be rebuilt,
+// even the ones between the flushed states lost in a crash.
+func TestStateHistory(t *testing.T) {
+	// Create a chain storing the block number into a contract in every block
+	var (
+		key, _   = crypto.GenerateKey()
+		from     = crypto.PubkeyToAddress(key.PublicKey)
+		contract = crypto.CreateAddress(from, 0)
+		gspec    = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{from: {Balance: big.NewInt(1000000000000)}}}
+		gendb, _ = ethdb.NewMemDatabase()
+		signer   = types.HomesteadSigner{}
+	)
+	blocks, _ := GenerateChain(gspec.Config, gspec.MustCommit(gendb), gendb, 50, func(i int, b *BlockGen) {
+		var tx *types.Transaction
+		if i == 0 {
+			// PUSH4 (NUMBER NUMBER SSTORE STOP) PUSH1 0 MSTORE PUSH1 4 PUSH1 28 RETURN
+			code := common.Hex2Bytes("63434355006000526004601cf3")
+			tx, _ = types.SignTx(types.NewContractCreation(b.TxNonce(from), new(big.Int), big.NewInt(100000), nil, code), signer, key)
+		} else {
+			tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(from), contract, new(big.Int), big.NewInt(100000), nil, nil), signer, key)
+		}
+		b.AddTx(tx)
+	})
+	db, _ := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	chain.SetStateGC(StateGCConfig{Recent: 16, FlushInterval: 20, MemoryLimit: 256 * 1024 * 1024, Diffs: true})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Without a clean shutdown only the flushed states and the diffs remain
+	restarted, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer restarted.Stop()
+
+	for _, bc := range []*BlockChain{chain, restarted} {
+		for i, block := range blocks {
+			number := i + 1
+
+			statedb, err := bc.StateAt(block.Root())
+			if err != nil {
+				t.Fatalf("block %d: state unavailable: %v", number, err)
+			}
+			if root := statedb.IntermediateRoot(false); root != block.Root() {
+				t.Errorf("block %d: root mismatch: have %x, want %x", number, root, block.Root())
+			}
+			if code := statedb.GetCode(contract); len(code) != 4 {
+				t.Errorf("block %d: contract code mismatch: have %x", number, code)
+			}
+			for n := 2; n <= number+1; n++ {
+				want := common.Hash{}
+				if n <= number {
+					want = common.BigToHash(big.NewInt(int64(n)))
+				}
+				if have := statedb.GetState(contract, common.BigToHash(big.NewInt(int64(n)))); have != want {
+					t.Errorf("block %d: slot %d mismatch: have %x, want %x", number, n, have, want)
+				}
+			}
+		}
+	}
+}
+
+// Tests that pruning retains the genesis, recent, periodic and checkpoint states
+// only, without touching any other data.
+func TestPruneState(t *testing.T) {
+	gspec, blocks := makeStateChain(30)
+
+	dir, err := ioutil.TempDir("", "prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	genesis := gspec.MustCommit(db)
+	chain, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.Stop()
+
+	if _, err := PruneState(db, 5, 8, []uint64{10}); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	for i, block := range append([]*types.Block{genesis}, blocks...) {
+		retained := i%8 == 0 || i == 10 || i > 30-5
+
+		_, err := trie.New(block.Root(), db)
+		if retained != (err == nil) {
+			t.Errorf("block %d: retained state mismatch: have %v, want %v", i, err == nil, retained)
+		}
+		for _, tx := range block.Transactions() {
+			if tx, _, _, _ := GetTransaction(db, tx.Hash()); tx == nil {
+				t.Errorf("block %d: transaction deleted", i)
+			}
+		}
+	}
+	// The retained states are complete
+	head, _ := NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	defer head.Stop()
+
+	statedb, err := head.StateAt(blocks[9].Root())
+	if err != nil {
+		t.Fatalf("checkpoint state missing: %v", err)
+	}
+	if balance := statedb.GetBalance(common.BigToAddress(big.NewInt(10))); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("checkpoint balance mismatch: have %v, want 1000", balance)
+	}
+}
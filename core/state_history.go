@@ -0,0 +1,210 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var stateDiffPrefix = []byte("state-diff-") // stateDiffPrefix + state root -> state diff
+
+// emptyCode is the code hash of accounts without code.
+var emptyCode = crypto.Keccak256(nil)
+
+// stateDiff is the set of trie nodes and code a state introduced over the state
+// of its parent block, allowing to rebuild it once garbage collected.
+type stateDiff struct {
+	Parent common.Hash // Root of the state the diff applies to
+	Nodes  [][]byte    // Trie nodes and code missing from the parent state
+}
+
+// getStateDiff retrieves the diff the state with the given root was stored as,
+// or nil if it has none.
+func getStateDiff(db ethdb.Database, root common.Hash) *stateDiff {
+	data, _ := db.Get(append(stateDiffPrefix, root[:]...))
+	if len(data) == 0 {
+		return nil
+	}
+	diff := new(stateDiff)
+	if err := rlp.DecodeBytes(data, diff); err != nil {
+		log.Error("Invalid state diff RLP", "root", root, "err", err)
+		return nil
+	}
+	return diff
+}
+
+// writeStateDiff stores the diff of the state with the given root.
+func writeStateDiff(db ethdb.Database, root common.Hash, diff *stateDiff) error {
+	data, err := rlp.EncodeToBytes(diff)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(stateDiffPrefix, root[:]...), data)
+}
+
+// diffState gathers the trie nodes and code of the state rooted at root which
+// are not part of the state rooted at parent.
+func diffState(db trie.Database, parent, root common.Hash) (*stateDiff, error) {
+	prev, err := trie.New(parent, db)
+	if err != nil {
+		return nil, err
+	}
+	diff := &stateDiff{Parent: parent}
+	err = diffTrie(db, parent, root, diff, func(key, leaf []byte) error {
+		var account, old state.Account
+		if err := rlp.DecodeBytes(leaf, &account); err != nil {
+			return err
+		}
+		blob, err := prev.TryGet(key)
+		if err != nil {
+			return err
+		}
+		if blob != nil {
+			if err := rlp.DecodeBytes(blob, &old); err != nil {
+				return err
+			}
+		}
+		if !bytes.Equal(account.CodeHash, old.CodeHash) && !bytes.Equal(account.CodeHash, emptyCode) {
+			code, err := db.Get(account.CodeHash)
+			if err != nil {
+				return err
+			}
+			diff.Nodes = append(diff.Nodes, code)
+		}
+		if account.Root == old.Root {
+			return nil
+		}
+		return diffTrie(db, old.Root, account.Root, diff, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// diffTrie appends the nodes of the trie rooted at root missing from the trie
+// rooted at parent to the diff, calling onleaf with the key and value of every
+// leaf which was added or changed.
+func diffTrie(db trie.Database, parent, root common.Hash, diff *stateDiff, onleaf func(key, leaf []byte) error) error {
+	a, err := trie.New(parent, db)
+	if err != nil {
+		return err
+	}
+	b, err := trie.New(root, db)
+	if err != nil {
+		return err
+	}
+	it, _ := trie.NewDifferenceIterator(a.NodeIterator(nil), b.NodeIterator(nil))
+	for it.Next(true) {
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			blob, err := db.Get(hash[:])
+			if err != nil {
+				return err
+			}
+			diff.Nodes = append(diff.Nodes, common.CopyBytes(blob))
+		}
+		if it.Leaf() && onleaf != nil {
+			if err := onleaf(it.LeafKey(), it.LeafBlob()); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Error()
+}
+
+// storeStateDiff writes the diff of the state of a freshly written block over
+// the state of its parent, unless the state was stored before. States reached
+// again later thus always refer back to an older one, never forming a cycle.
+//
+// Note, this method assumes the chain lock is held!
+func (bc *BlockChain) storeStateDiff(block *types.Block) error {
+	root, number := block.Root(), block.NumberU64()
+	if number == 0 || getStateDiff(bc.chainDb, root) != nil {
+		return nil
+	}
+	parent := bc.GetHeader(block.ParentHash(), number-1)
+	if parent == nil || parent.Root == root {
+		return nil
+	}
+	diff, err := diffState(bc.triecache, parent.Root, root)
+	if err != nil {
+		// The parent state of a side block may be long gone, nothing to diff
+		log.Warn("Failed to diff block state", "number", number, "hash", block.Hash(), "err", err)
+		return nil
+	}
+	return writeStateDiff(bc.chainDb, root, diff)
+}
+
+// rebuildState reconstructs a garbage collected state by applying the stored
+// diffs on top of the nearest available ancestor state. The states flushed
+// every FlushInterval blocks, and those kept by pruning every prune interval,
+// bound the number of diffs to apply.
+func (bc *BlockChain) rebuildState(root common.Hash) (*state.StateDB, error) {
+	overlay := &stateOverlay{base: bc.triecache}
+	overlay.nodes, _ = ethdb.NewMemDatabase()
+
+	var (
+		start = time.Now()
+		diffs int
+	)
+	for base := root; ; diffs++ {
+		if _, err := trie.New(base, bc.triecache); err == nil {
+			break
+		}
+		diff := getStateDiff(bc.chainDb, base)
+		if diff == nil {
+			return nil, &trie.MissingNodeError{NodeHash: base}
+		}
+		for _, blob := range diff.Nodes {
+			overlay.nodes.Put(crypto.Keccak256(blob), blob)
+		}
+		base = diff.Parent
+	}
+	log.Debug("Rebuilt historical state", "root", root, "diffs", diffs, "elapsed", common.PrettyDuration(time.Since(start)))
+	return state.New(root, state.NewDatabase(overlay))
+}
+
+// stateOverlay is the database of rebuilt states, serving the nodes of the
+// applied diffs on top of the nodes of the chain.
+type stateOverlay struct {
+	nodes *ethdb.MemDatabase // Nodes of the applied diffs and any later writes
+	base  trie.Database      // Node cache of the chain
+}
+
+// Get implements trie.DatabaseReader, preferring the nodes of the diffs.
+func (o *stateOverlay) Get(key []byte) ([]byte, error) {
+	if blob, err := o.nodes.Get(key); err == nil {
+		return blob, nil
+	}
+	return o.base.Get(key)
+}
+
+// Put implements trie.DatabaseWriter, keeping any modification of a rebuilt
+// state out of the chain.
+func (o *stateOverlay) Put(key []byte, value []byte) error {
+	return o.nodes.Put(key, value)
+}
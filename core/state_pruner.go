@@ -0,0 +1,172 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// PruneState deletes the trie nodes of all states from the database, except
+// for the states of the genesis, of the recent canonical blocks, of every
+// interval-th canonical block and of the given checkpoint blocks, along with
+// their private states. It returns the number of deleted nodes.
+//
+// The states kept every interval blocks are the bases stored state diffs are
+// applied to, so no historical state takes more than interval diffs to rebuild.
+// An interval of zero keeps no such states, leaving only the genesis to rebuild
+// the old states from.
+//
+// The database must not be in use by a running node. Contract code of retained
+// states is kept. Code referenced by pruned states only is mostly left behind,
+// as it is shared freely between states and small in comparison, but it is
+// deleted if it happens to decode as a trie node, which no pruned node can be
+// told apart from.
+func PruneState(db *ethdb.LDBDatabase, recent, interval uint64, checkpoints []uint64) (int, error) {
+	head := GetHeadBlockHash(db)
+	if head == (common.Hash{}) {
+		return 0, errors.New("empty database")
+	}
+	number := GetBlockNumber(db, head)
+	if header := GetHeader(db, head, number); header == nil {
+		return 0, errors.New("head block missing")
+	} else if _, err := trie.New(header.Root, db); err != nil {
+		return 0, errors.New("head state missing")
+	}
+	// Gather the blocks whose states are retained
+	retain := append([]uint64{0}, checkpoints...)
+	for n := uint64(0); n < recent && n <= number; n++ {
+		retain = append(retain, number-n)
+	}
+	for n := interval; interval > 0 && n <= number; n += interval {
+		retain = append(retain, n)
+	}
+	// Mark all nodes reachable from the retained states
+	var (
+		marked = make(map[common.Hash]struct{})
+		start  = time.Now()
+		states int
+	)
+	for _, n := range retain {
+		if n > number {
+			log.Warn("Skipping future checkpoint", "number", n)
+			continue
+		}
+		hash := GetCanonicalHash(db, n)
+		header := GetHeader(db, hash, n)
+		if header == nil {
+			return 0, errors.New("canonical block missing")
+		}
+		roots := []common.Hash{header.Root}
+		if root := GetPrivateStateRoot(db, hash); root != (common.Hash{}) {
+			roots = append(roots, root)
+		}
+		for _, root := range roots {
+			if _, err := trie.New(root, db); err != nil {
+				// States of blocks not flushed to disk are not an error
+				log.Debug("Skipping missing state", "number", n, "root", root)
+				continue
+			}
+			if err := markState(db, root, marked); err != nil {
+				return 0, err
+			}
+			states++
+		}
+	}
+	log.Info("Marked retained states", "states", states, "nodes", len(marked), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Sweep all trie nodes which were not marked
+	start = time.Now()
+
+	it := db.NewIterator()
+	defer it.Release()
+
+	deleted := 0
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+		if len(key) != common.HashLength {
+			continue
+		}
+		if _, ok := marked[common.BytesToHash(key)]; ok {
+			continue
+		}
+		// Other content addressed data (i.e. transactions) is not a trie node
+		if crypto.Keccak256Hash(value) != common.BytesToHash(key) || !trie.IsNode(value) {
+			continue
+		}
+		if err := db.Delete(common.CopyBytes(key)); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	if err := it.Error(); err != nil {
+		return deleted, err
+	}
+	log.Info("Deleted stale trie nodes", "nodes", deleted, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	return deleted, nil
+}
+
+// markState marks the nodes of the account trie rooted at root, along with the
+// storage tries and code of its accounts.
+func markState(db trie.Database, root common.Hash, marked map[common.Hash]struct{}) error {
+	return markTrie(db, root, marked, func(leaf []byte) error {
+		var account state.Account
+		if err := rlp.DecodeBytes(leaf, &account); err != nil {
+			return err
+		}
+		marked[common.BytesToHash(account.CodeHash)] = struct{}{}
+		return markTrie(db, account.Root, marked, nil)
+	})
+}
+
+// markTrie marks the nodes of the trie rooted at root, calling onleaf for the
+// value of every leaf. Subtries marked before are skipped in their entirety.
+func markTrie(db trie.Database, root common.Hash, marked map[common.Hash]struct{}, onleaf func([]byte) error) error {
+	if _, ok := marked[root]; ok {
+		return nil
+	}
+	t, err := trie.New(root, db)
+	if err != nil {
+		return err
+	}
+	it := t.NodeIterator(nil)
+	for descend := true; it.Next(descend); {
+		descend = true
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if _, ok := marked[hash]; ok {
+				descend = false
+				continue
+			}
+			marked[hash] = struct{}{}
+		}
+		if it.Leaf() && onleaf != nil {
+			if err := onleaf(it.LeafBlob()); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Error()
+}
@@ -75,6 +75,7 @@ type Message interface {
 
 	Nonce() uint64
 	CheckNonce() bool
+	IsPrivate() bool
 	Data() []byte
 }
 
@@ -172,7 +173,10 @@ func (st *StateTransition) buyGas() error {
 	if mgas.BitLen() > 64 {
 		return vm.ErrOutOfGas
 	}
-
+	// Free chains still meter gas, but never charge for it
+	if st.evm.ChainConfig().IsZeroGasPrice(st.evm.BlockNumber) && st.gasPrice.Sign() != 0 {
+		return ErrGasPriceNotZero
+	}
 	mgval := new(big.Int).Mul(mgas, st.gasPrice)
 
 	var (
@@ -196,11 +200,14 @@ func (st *StateTransition) preCheck() error {
 	msg := st.msg
 	sender := st.from()
 
-	// Make sure this transaction's nonce is correct
+	// Make sure this transaction's nonce is correct and its sender permitted
 	if msg.CheckNonce() {
 		if n := st.state.GetNonce(sender.Address()); n != msg.Nonce() {
 			return fmt.Errorf("invalid nonce: have %d, expected %d", msg.Nonce(), n)
 		}
+		if err := CheckPermission(st.evm.ChainConfig(), st.evm.BlockNumber, st.state, sender.Address(), msg.To() == nil); err != nil {
+			return err
+		}
 	}
 	return st.buyGas()
 }
@@ -209,6 +216,12 @@ func (st *StateTransition) preCheck() error {
 // including the required gas for the operation as well as the used gas. It returns an error if it
 // failed. An error indicates a consensus issue.
 func (st *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *big.Int, err error) {
+	if st.msg.IsPrivate() && !st.evm.ChainConfig().IsPrivate(st.evm.BlockNumber) {
+		return nil, nil, nil, ErrPrivateDisabled
+	}
+	if st.msg.IsPrivate() && st.msg.Value().Sign() != 0 {
+		return nil, nil, nil, ErrPrivateValue
+	}
 	if err = st.preCheck(); err != nil {
 		return
 	}
@@ -228,6 +241,17 @@ func (st *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *big
 		return nil, nil, nil, err
 	}
 
+	// The payload of a private transaction is only known to its participants,
+	// publicly it merely pays for its intrinsic gas and bumps the nonce.
+	if msg.IsPrivate() {
+		st.state.SetNonce(sender.Address(), st.state.GetNonce(sender.Address())+1)
+		requiredGas = new(big.Int).Set(st.gasUsed())
+
+		st.refundGas()
+		st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(st.gasUsed(), st.gasPrice))
+
+		return nil, requiredGas, st.gasUsed(), nil
+	}
 	var (
 		evm = st.evm
 		// vm errors do not effect consensus and are therefor
@@ -0,0 +1,75 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that free chains meter the gas of transactions without debiting any
+// balance, and reject transactions offering a gas price.
+func TestZeroGasPriceTransition(t *testing.T) {
+	config := *params.TestChainConfig
+	config.ZeroGasPriceBlock = new(big.Int)
+
+	var (
+		key, _        = crypto.GenerateKey()
+		from          = crypto.PubkeyToAddress(key.PublicKey)
+		to            = common.HexToAddress("0x01")
+		gspec         = &Genesis{Config: &config}
+		signer        = types.HomesteadSigner{}
+		db, _         = ethdb.NewMemDatabase()
+		genesis       = gspec.MustCommit(db)
+		blockchain, _ = NewBlockChain(db, gspec.Config, ethash.NewFaker(), new(event.TypeMux), vm.Config{})
+	)
+	free, _ := types.SignTx(types.NewTransaction(0, to, new(big.Int), big.NewInt(21000), new(big.Int), nil), signer, key)
+	blocks, receipts := GenerateChain(gspec.Config, genesis, db, 1, func(i int, b *BlockGen) { b.AddTx(free) })
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert free transaction: %v", err)
+	}
+	if used := receipts[0][0].GasUsed; used.Cmp(big.NewInt(21000)) != 0 {
+		t.Errorf("gas used mismatch: have %v, want 21000", used)
+	}
+	statedb, _ := blockchain.State()
+	if nonce := statedb.GetNonce(from); nonce != 1 {
+		t.Errorf("nonce mismatch: have %d, want 1", nonce)
+	}
+	// A transaction paying for its gas is invalid, even if the sender could afford it
+	priced, _ := types.SignTx(types.NewTransaction(1, to, new(big.Int), big.NewInt(21000), big.NewInt(1), nil), signer, key)
+	statedb.AddBalance(from, big.NewInt(21000))
+
+	gp := new(GasPool).AddGas(big.NewInt(21000))
+	if _, _, err := ApplyTransaction(gspec.Config, blockchain, nil, gp, statedb, blockchain.CurrentHeader(), priced, new(big.Int), vm.Config{}); err != ErrGasPriceNotZero {
+		t.Errorf("priced transaction error mismatch: have %v, want %v", err, ErrGasPriceNotZero)
+	}
+	// Before the zero gas price block, gas is paid for as usual
+	config.ZeroGasPriceBlock = big.NewInt(10)
+	gp = new(GasPool).AddGas(big.NewInt(21000))
+	if _, _, err := ApplyTransaction(gspec.Config, blockchain, nil, gp, statedb, blockchain.CurrentHeader(), priced, new(big.Int), vm.Config{}); err != nil {
+		t.Errorf("priced transaction rejected before the fork: %v", err)
+	}
+}
@@ -108,6 +108,9 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	Journal   string        // Journal of local transactions to survive node restarts (empty = disabled)
+	Rejournal time.Duration // Time interval to regenerate the local transaction journal
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -122,6 +125,9 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	Journal:   "transactions.rlp",
+	Rejournal: time.Hour,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -136,6 +142,10 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
 		conf.PriceBump = DefaultTxPoolConfig.PriceBump
 	}
+	if conf.Rejournal < time.Second {
+		log.Warn("Sanitizing invalid txpool journal time", "provided", conf.Rejournal, "updated", time.Second)
+		conf.Rejournal = time.Second
+	}
 	return conf
 }
 
@@ -156,6 +166,7 @@ type TxPool struct {
 	eventMux     *event.TypeMux
 	events       *event.TypeMuxSubscription
 	locals       *txSet
+	journal      *txJournal // Journal of local transactions to back up to disk
 	signer       types.Signer
 	mu           sync.RWMutex
 
@@ -168,12 +179,14 @@ type TxPool struct {
 	wg   sync.WaitGroup // for shutdown sync
 	quit chan struct{}
 
-	homestead bool
+	homestead    bool
+	permissioned bool // Whether the next block enforces the permission config
+	zeroGasPrice bool // Whether the next block only accepts transactions not paying for gas
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
 // trnsactions from the network.
-func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, eventMux *event.TypeMux, currentStateFn stateFn, gasLimitFn func() *big.Int) *TxPool {
+func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, eventMux *event.TypeMux, currentStateFn stateFn, gasLimitFn func() *big.Int, currentBlockFn func() *types.Block) *TxPool {
 	// Sanitize the input to ensure no vulnerable gas prices are set
 	config = (&config).sanitize()
 
@@ -196,8 +209,21 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, eventMux *e
 		quit:         make(chan struct{}),
 	}
 	pool.priced = newTxPricedList(&pool.all)
+	pool.setHead(currentBlockFn().Number())
 	pool.resetState()
 
+	// If journaling is enabled, load the local transactions from disk
+	if config.Journal != "" {
+		pool.journal = newTxJournal(config.Journal)
+
+		if err := pool.journal.load(pool.addLocal); err != nil {
+			log.Warn("Failed to load transaction journal", "err", err)
+		}
+		if err := pool.journal.rotate(pool.local()); err != nil {
+			log.Warn("Failed to rotate transaction journal", "err", err)
+		}
+	}
+
 	// Start the various events loops and return
 	pool.wg.Add(2)
 	go pool.eventLoop()
@@ -215,6 +241,9 @@ func (pool *TxPool) eventLoop() {
 	report := time.NewTicker(statsReportInterval)
 	defer report.Stop()
 
+	journal := time.NewTicker(pool.config.Rejournal)
+	defer journal.Stop()
+
 	// Track chain events. When a chain events occurs (new chain canon block)
 	// we need to know the new state. The new state will help us determine
 	// the nonces in the managed state
@@ -232,6 +261,7 @@ func (pool *TxPool) eventLoop() {
 					if pool.chainconfig.IsHomestead(ev.Block.Number()) {
 						pool.homestead = true
 					}
+					pool.setHead(ev.Block.Number())
 				}
 				pool.resetState()
 				pool.mu.Unlock()
@@ -251,6 +281,16 @@ func (pool *TxPool) eventLoop() {
 				log.Debug("Transaction pool status report", "executable", pending, "queued", queued, "stales", stales)
 				prevPending, prevQueued, prevStales = pending, queued, stales
 			}
+
+		// Handle local transaction journal rotation
+		case <-journal.C:
+			if pool.journal != nil {
+				pool.mu.Lock()
+				if err := pool.journal.rotate(pool.local()); err != nil {
+					log.Warn("Failed to rotate local tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -285,6 +325,10 @@ func (pool *TxPool) Stop() {
 	close(pool.quit)
 	pool.wg.Wait()
 
+	if pool.journal != nil {
+		pool.journal.close()
+	}
+
 	log.Info("Transaction pool stopped")
 }
 
@@ -296,12 +340,39 @@ func (pool *TxPool) GasPrice() *big.Int {
 	return new(big.Int).Set(pool.gasPrice)
 }
 
+// setHead updates the rules the pool enforces to those of the block following
+// the head of the given number.
+func (pool *TxPool) setHead(number *big.Int) {
+	next := new(big.Int).Add(number, common.Big1)
+	pool.permissioned = pool.chainconfig.IsPermissioned(next)
+	pool.setZeroGasPrice(pool.chainconfig.IsZeroGasPrice(next))
+}
+
+// setZeroGasPrice switches the pool to free transactions once the next block
+// requires them, dropping the transactions paying for their gas.
+func (pool *TxPool) setZeroGasPrice(free bool) {
+	if !free || pool.zeroGasPrice {
+		return
+	}
+	pool.zeroGasPrice = true
+	pool.gasPrice = new(big.Int)
+	for hash, tx := range pool.all {
+		if tx.GasPrice().Sign() != 0 {
+			pool.removeTx(hash)
+		}
+	}
+}
+
 // SetGasPrice updates the minimum price required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *TxPool) SetGasPrice(price *big.Int) {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
+	if pool.zeroGasPrice {
+		log.Debug("Ignoring price threshold of free chain", "price", price)
+		return
+	}
 	pool.gasPrice = price
 	for _, tx := range pool.priced.Cap(price, pool.locals) {
 		pool.removeTx(tx.Hash())
@@ -379,10 +450,52 @@ func (pool *TxPool) SetLocal(tx *types.Transaction) {
 	pool.locals.add(tx.Hash())
 }
 
+// addLocal marks a transaction as local and queues it in the pool.
+func (pool *TxPool) addLocal(tx *types.Transaction) error {
+	pool.SetLocal(tx)
+	return pool.Add(tx)
+}
+
+// local retrieves all currently known local transactions, groupped by origin
+// account and sorted by nonce. The returned transaction set is a copy and can be
+// freely modified by calling code.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) local() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for _, lists := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		for addr, list := range lists {
+			for _, tx := range list.Flatten() {
+				if pool.locals.contains(tx.Hash()) {
+					txs[addr] = append(txs[addr], tx)
+				}
+			}
+		}
+	}
+	return txs
+}
+
+// journalTx adds the specified transaction to the local disk journal if it is
+// deemed to have been sent from a local account.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) journalTx(tx *types.Transaction) {
+	if pool.journal == nil || !pool.locals.contains(tx.Hash()) {
+		return
+	}
+	if err := pool.journal.insert(tx); err != nil && err != errNoActiveJournal {
+		log.Warn("Failed to journal local transaction", "err", err)
+	}
+}
+
 // validateTx checks whether a transaction is valid according
 // to the consensus rules.
 func (pool *TxPool) validateTx(tx *types.Transaction) error {
 	local := pool.locals.contains(tx.Hash())
+	// Free chains only accept transactions not paying for their gas
+	if pool.zeroGasPrice && tx.GasPrice().Sign() != 0 {
+		return ErrGasPriceNotZero
+	}
 	// Drop transactions under our own minimal accepted gas price
 	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
 		return ErrUnderpriced
@@ -397,6 +510,12 @@ func (pool *TxPool) validateTx(tx *types.Transaction) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
+	// Only accept transactions the permission config allows
+	if pool.permissioned {
+		if err := checkPermission(pool.chainconfig.Permissions, currentState, from, tx.To() == nil); err != nil {
+			return err
+		}
+	}
 	// Last but not least check for nonce errors
 	if currentState.GetNonce(from) > tx.Nonce() {
 		return ErrNonceTooLow
@@ -414,6 +533,9 @@ func (pool *TxPool) validateTx(tx *types.Transaction) error {
 	if tx.Value().Sign() < 0 {
 		return ErrNegativeValue
 	}
+	if tx.IsPrivate() && tx.Value().Sign() != 0 {
+		return ErrPrivateValue
+	}
 
 	// Transactor should have enough funds to cover the costs
 	// cost == V + GP * GL
@@ -482,6 +604,7 @@ func (pool *TxPool) add(tx *types.Transaction) (bool, error) {
 		}
 		pool.all[tx.Hash()] = tx
 		pool.priced.Put(tx)
+		pool.journalTx(tx)
 
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 		return old != nil, nil
@@ -491,6 +614,7 @@ func (pool *TxPool) add(tx *types.Transaction) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	pool.journalTx(tx)
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
 	return replace, nil
 }
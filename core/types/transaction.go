@@ -37,9 +37,19 @@ var (
 	errNoSigner   = errors.New("missing signing methods")
 )
 
-// deriveSigner makes a *best* guess about which signer to use.
+// privateV replaces the 27 added to the recovery id in the V value of signatures
+// of private transactions. Neither homestead nor EIP155 signatures produce a V of
+// 29 or 30, so private transactions are never mistaken for public ones, and the
+// private signer hash covers the privacy, so V cannot be altered to change it.
+const privateV = 29
+
+// deriveSigner makes a *best* guess about which signer to use. The V of private
+// transactions does not carry the chain id, so their signer is only a guess for
+// chain id zero.
 func deriveSigner(V *big.Int) Signer {
-	if V.Sign() != 0 && isProtectedV(V) {
+	if V.Sign() != 0 && isPrivateV(V) {
+		return NewPrivateSigner(nil)
+	} else if V.Sign() != 0 && isProtectedV(V) {
 		return NewEIP155Signer(deriveChainId(V))
 	} else {
 		return HomesteadSigner{}
@@ -128,10 +138,25 @@ func (tx *Transaction) Protected() bool {
 	return isProtectedV(tx.data.V)
 }
 
+// IsPrivate returns whether the transaction is private, carrying the key of its
+// payload in the transaction manager instead of the payload itself. Only the
+// private signer recovers the sender of private transactions.
+func (tx *Transaction) IsPrivate() bool {
+	return isPrivateV(tx.data.V)
+}
+
+func isPrivateV(V *big.Int) bool {
+	if V.BitLen() <= 8 {
+		v := V.Uint64()
+		return v == privateV || v == privateV+1
+	}
+	return false
+}
+
 func isProtectedV(V *big.Int) bool {
 	if V.BitLen() <= 8 {
 		v := V.Uint64()
-		return v != 27 && v != 28
+		return v != 27 && v != 28 && !isPrivateV(V)
 	}
 	// anything not 27 or 28 are considered unprotected
 	return true
@@ -170,6 +195,8 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 	if isProtectedV(dec.V) {
 		chainId := deriveChainId(dec.V).Uint64()
 		V = byte(dec.V.Uint64() - 35 - 2*chainId)
+	} else if isPrivateV(dec.V) {
+		V = byte(dec.V.Uint64() - privateV)
 	} else {
 		V = byte(dec.V.Uint64() - 27)
 	}
@@ -239,6 +266,7 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 		amount:     tx.data.Amount,
 		data:       tx.data.Payload,
 		checkNonce: true,
+		private:    tx.IsPrivate(),
 	}
 
 	var err error
@@ -265,7 +293,10 @@ func (tx *Transaction) RawSignatureValues() (*big.Int, *big.Int, *big.Int) {
 
 func (tx *Transaction) String() string {
 	var from, to string
-	if tx.data.V != nil {
+	if sc, ok := tx.from.Load().(sigCache); ok {
+		// use the sender derived by the signer of the chain if cached
+		from = fmt.Sprintf("%x", sc.from[:])
+	} else if tx.data.V != nil {
 		// make a best guess about the signer and use that to derive
 		// the sender.
 		signer := deriveSigner(tx.data.V)
@@ -381,8 +412,9 @@ func (s *TxByPrice) Pop() interface{} {
 // transactions in a profit-maximising sorted order, while supporting removing
 // entire batches of transactions for non-executable accounts.
 type TransactionsByPriceAndNonce struct {
-	txs   map[common.Address]Transactions // Per account nonce-sorted list of transactions
-	heads TxByPrice                       // Next transaction for each unique account (price heap)
+	txs    map[common.Address]Transactions // Per account nonce-sorted list of transactions
+	heads  TxByPrice                       // Next transaction for each unique account (price heap)
+	signer Signer                          // Signer for the set of transactions
 }
 
 // NewTransactionsByPriceAndNonce creates a transaction set that can retrieve
@@ -390,7 +422,7 @@ type TransactionsByPriceAndNonce struct {
 //
 // Note, the input map is reowned so the caller should not interact any more with
 // if after providng it to the constructor.
-func NewTransactionsByPriceAndNonce(txs map[common.Address]Transactions) *TransactionsByPriceAndNonce {
+func NewTransactionsByPriceAndNonce(signer Signer, txs map[common.Address]Transactions) *TransactionsByPriceAndNonce {
 	// Initialize a price based heap with the head transactions
 	heads := make(TxByPrice, 0, len(txs))
 	for acc, accTxs := range txs {
@@ -401,8 +433,9 @@ func NewTransactionsByPriceAndNonce(txs map[common.Address]Transactions) *Transa
 
 	// Assemble and return the transaction set
 	return &TransactionsByPriceAndNonce{
-		txs:   txs,
-		heads: heads,
+		txs:    txs,
+		heads:  heads,
+		signer: signer,
 	}
 }
 
@@ -416,9 +449,7 @@ func (t *TransactionsByPriceAndNonce) Peek() *Transaction {
 
 // Shift replaces the current best head with the next one from the same account.
 func (t *TransactionsByPriceAndNonce) Shift() {
-	signer := deriveSigner(t.heads[0].data.V)
-	// derive signer but don't cache.
-	acc, _ := Sender(signer, t.heads[0]) // we only sort valid txs so this cannot fail
+	acc, _ := Sender(t.signer, t.heads[0]) // we only sort valid txs so this cannot fail
 	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
 		t.heads[0], t.txs[acc] = txs[0], txs[1:]
 		heap.Fix(&t.heads, 0)
@@ -444,6 +475,7 @@ type Message struct {
 	amount, price, gasLimit *big.Int
 	data                    []byte
 	checkNonce              bool
+	private                 bool
 }
 
 func NewMessage(from common.Address, to *common.Address, nonce uint64, amount, gasLimit, price *big.Int, data []byte, checkNonce bool) Message {
@@ -467,3 +499,4 @@ func (m Message) Gas() *big.Int        { return m.gasLimit }
 func (m Message) Nonce() uint64        { return m.nonce }
 func (m Message) Data() []byte         { return m.data }
 func (m Message) CheckNonce() bool     { return m.checkNonce }
+func (m Message) IsPrivate() bool      { return m.private }
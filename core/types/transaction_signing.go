@@ -52,6 +52,9 @@ func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	default:
 		signer = FrontierSigner{}
 	}
+	if config.IsPrivate(blockNumber) {
+		signer = privateTxSigner{Signer: signer, private: NewPrivateSigner(config.ChainId)}
+	}
 	return signer
 }
 
@@ -196,6 +199,104 @@ func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
 	})
 }
 
+// PrivateSigner implements TransactionInterface for private transactions. Its
+// hash covers a private marker, so a signature made for a public transaction
+// never recovers the same sender when marked as private, and vice versa, and
+// the chain id, so private transactions cannot be replayed on other chains.
+type PrivateSigner struct {
+	chainId *big.Int
+}
+
+func NewPrivateSigner(chainId *big.Int) PrivateSigner {
+	if chainId == nil {
+		chainId = new(big.Int)
+	}
+	return PrivateSigner{chainId: chainId}
+}
+
+// privateMarker is appended to the signed fields of private transactions.
+var privateMarker = []byte("private")
+
+func (s PrivateSigner) Equal(s2 Signer) bool {
+	private, ok := s2.(PrivateSigner)
+	return ok && private.chainId.Cmp(s.chainId) == 0
+}
+
+// WithSignature returns a new private transaction with the given signature. This
+// signature needs to be in the [R || S || V] format where V is 0 or 1.
+func (s PrivateSigner) WithSignature(tx *Transaction, sig []byte) (*Transaction, error) {
+	if len(sig) != 65 {
+		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
+	}
+	cpy := &Transaction{data: tx.data}
+	cpy.data.R = new(big.Int).SetBytes(sig[:32])
+	cpy.data.S = new(big.Int).SetBytes(sig[32:64])
+	cpy.data.V = new(big.Int).SetBytes([]byte{sig[64] + privateV})
+	return cpy, nil
+}
+
+// Hash returns the hash to be signed by the sender of a private transaction.
+// It does not uniquely identify the transaction.
+func (s PrivateSigner) Hash(tx *Transaction) common.Hash {
+	return rlpHash([]interface{}{
+		tx.data.AccountNonce,
+		tx.data.Price,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+		s.chainId,
+		privateMarker,
+	})
+}
+
+func (s PrivateSigner) PublicKey(tx *Transaction) ([]byte, error) {
+	if !tx.IsPrivate() {
+		return nil, ErrInvalidSig
+	}
+	V := byte(tx.data.V.Uint64() - privateV)
+	if !crypto.ValidateSignatureValues(V, tx.data.R, tx.data.S, true) {
+		return nil, ErrInvalidSig
+	}
+	// encode the signature in uncompressed format
+	r, sv := tx.data.R.Bytes(), tx.data.S.Bytes()
+	sig := make([]byte, 65)
+	copy(sig[32-len(r):32], r)
+	copy(sig[64-len(sv):64], sv)
+	sig[64] = V
+
+	// recover the public key from the signature
+	hash := s.Hash(tx)
+	pub, err := crypto.Ecrecover(hash[:], sig)
+	if err != nil {
+		return nil, err
+	}
+	if len(pub) == 0 || pub[0] != 4 {
+		return nil, errors.New("invalid public key")
+	}
+	return pub, nil
+}
+
+// privateTxSigner extends the signer of public transactions with the recovery of
+// private ones, once the chain configuration enables private transactions. It
+// signs public transactions, private ones are signed with the PrivateSigner.
+type privateTxSigner struct {
+	Signer
+	private PrivateSigner
+}
+
+func (s privateTxSigner) Equal(s2 Signer) bool {
+	p, ok := s2.(privateTxSigner)
+	return ok && p.Signer.Equal(s.Signer) && p.private.Equal(s.private)
+}
+
+func (s privateTxSigner) PublicKey(tx *Transaction) ([]byte, error) {
+	if tx.IsPrivate() {
+		return s.private.PublicKey(tx)
+	}
+	return s.Signer.PublicKey(tx)
+}
+
 // HomesteadTransaction implements TransactionInterface using the
 // homestead rules.
 type HomesteadSigner struct{ FrontierSigner }
@@ -310,7 +411,7 @@ func (fs FrontierSigner) PublicKey(tx *Transaction) ([]byte, error) {
 func deriveChainId(v *big.Int) *big.Int {
 	if v.BitLen() <= 64 {
 		v := v.Uint64()
-		if v == 27 || v == 28 {
+		if v == 27 || v == 28 || v == privateV || v == privateV+1 {
 			return new(big.Int)
 		}
 		return new(big.Int).SetUint64((v - 35) / 2)
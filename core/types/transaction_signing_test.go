@@ -22,6 +22,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -76,6 +77,52 @@ func TestEIP155ChainId(t *testing.T) {
 	}
 }
 
+func TestPrivateSigning(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	private, err := SignTx(NewTransaction(0, addr, new(big.Int), new(big.Int), new(big.Int), nil), NewPrivateSigner(big.NewInt(18)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !private.IsPrivate() {
+		t.Error("expected tx to be private")
+	}
+	if private.Protected() {
+		t.Error("didn't expect private tx to be protected")
+	}
+	// Private transactions are only recovered once the chain accepts them
+	config := &params.ChainConfig{ChainId: big.NewInt(18), HomesteadBlock: new(big.Int), EIP155Block: new(big.Int), PrivateBlock: big.NewInt(10)}
+	if _, err := Sender(MakeSigner(config, big.NewInt(9)), private); err != ErrInvalidSig {
+		t.Errorf("pre-fork error mismatch: have %v, want %v", err, ErrInvalidSig)
+	}
+	from, err := Sender(MakeSigner(config, big.NewInt(10)), private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != addr {
+		t.Errorf("sender mismatch: have %x, want %x", from, addr)
+	}
+	// Private signatures must not recover the signer on other chains
+	other := &params.ChainConfig{ChainId: big.NewInt(19), HomesteadBlock: new(big.Int), EIP155Block: new(big.Int), PrivateBlock: new(big.Int)}
+	if from, err := Sender(MakeSigner(other, big.NewInt(10)), private); err == nil && from == addr {
+		t.Error("private signature recovered the signer on another chain")
+	}
+	// Public signatures must not recover the signer once marked as private, nor
+	// private signatures once unmarked
+	public, _ := SignTx(NewTransaction(0, addr, new(big.Int), new(big.Int), new(big.Int), nil), HomesteadSigner{}, key)
+	v, r, s := public.RawSignatureValues()
+	forged, _ := public.WithSignature(NewPrivateSigner(big.NewInt(18)), append(append(common.LeftPadBytes(r.Bytes(), 32), common.LeftPadBytes(s.Bytes(), 32)...), byte(v.Uint64()-27)))
+	if from, err := Sender(MakeSigner(config, big.NewInt(10)), forged); err == nil && from == addr {
+		t.Error("public signature recovered the signer of a private transaction")
+	}
+	v, r, s = private.RawSignatureValues()
+	forged, _ = private.WithSignature(HomesteadSigner{}, append(append(common.LeftPadBytes(r.Bytes(), 32), common.LeftPadBytes(s.Bytes(), 32)...), byte(v.Uint64()-29)))
+	if from, err := Sender(MakeSigner(config, big.NewInt(10)), forged); err == nil && from == addr {
+		t.Error("private signature recovered the signer of a public transaction")
+	}
+}
+
 func TestEIP155SigningVitalik(t *testing.T) {
 	// Test vectors come from http://vitalik.ca/files/eip155_testvec.txt
 	for i, test := range []struct {
@@ -0,0 +1,151 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto/sm2"
+	"github.com/ethereum/go-ethereum/crypto/sm3"
+	"github.com/ethereum/go-ethereum/params"
+	"golang.org/x/crypto/ed25519"
+)
+
+// NativeContracts contains the native system contracts a chain configuration may
+// activate at addresses of its choice, in addition to the precompiled contracts.
+// Chains needing further built-in functions register them here by name.
+//
+// Native contracts only see their input, so reads of the BFT validator set are
+// out of scope: the set is kept by the consensus engine outside of the state,
+// taken from checkpoints, the chain config or the clique signers, and blocks do
+// not commit to it, so a contract reading it would not execute deterministically.
+var NativeContracts = map[string]PrecompiledContract{
+	"ed25519verify": &ed25519Verify{},
+	"sm2verify":     &sm2Verify{},
+	"sm3":           &sm3hash{},
+}
+
+// ValidatePrecompiles checks that the native contracts activated by the chain
+// configuration exist and neither shadow a precompiled contract nor each other.
+func ValidatePrecompiles(config *params.ChainConfig) error {
+	seen := make(map[common.Address]bool)
+	for _, p := range config.Precompiles {
+		if NativeContracts[p.Name] == nil {
+			return fmt.Errorf("unknown native contract %q", p.Name)
+		}
+		if PrecompiledContracts[p.Address] != nil {
+			return fmt.Errorf("native contract %q shadows precompiled contract %x", p.Name, p.Address)
+		}
+		if seen[p.Address] {
+			return fmt.Errorf("multiple native contracts at %x", p.Address)
+		}
+		seen[p.Address] = true
+	}
+	return nil
+}
+
+// activePrecompiles returns the contracts callable natively in the given block,
+// the precompiled contracts extended with the native contracts activated by the
+// chain configuration.
+func activePrecompiles(config *params.ChainConfig, num *big.Int) map[common.Address]PrecompiledContract {
+	if len(config.Precompiles) == 0 {
+		return PrecompiledContracts
+	}
+	contracts := make(map[common.Address]PrecompiledContract, len(PrecompiledContracts)+len(config.Precompiles))
+	for addr, p := range PrecompiledContracts {
+		contracts[addr] = p
+	}
+	for _, p := range config.Precompiles {
+		if c := NativeContracts[p.Name]; c != nil && p.IsActive(num) {
+			contracts[p.Address] = c
+		}
+	}
+	return contracts
+}
+
+// verified returns the result of a signature verification as a word.
+func verified(valid bool) []byte {
+	if valid {
+		return common.LeftPadBytes([]byte{1}, 32)
+	}
+	return make([]byte, 32)
+}
+
+// ed25519 signature verification implemented as a native contract
+type ed25519Verify struct{}
+
+// RequiredGas returns the gas required to execute the native contract.
+func (c *ed25519Verify) RequiredGas(input []byte) uint64 {
+	return uint64(len(input)+31)/32*params.Ed25519VerifyWordGas + params.Ed25519VerifyGas
+}
+
+// Run verifies the signature of the input, which is (public key, signature,
+// message), with a key of 32 and a signature of 64 bytes.
+func (c *ed25519Verify) Run(in []byte) ([]byte, error) {
+	if len(in) < ed25519.PublicKeySize+ed25519.SignatureSize {
+		return nil, errBadPrecompileInput
+	}
+	var (
+		key = ed25519.PublicKey(in[:ed25519.PublicKeySize])
+		sig = in[ed25519.PublicKeySize : ed25519.PublicKeySize+ed25519.SignatureSize]
+		msg = in[ed25519.PublicKeySize+ed25519.SignatureSize:]
+	)
+	return verified(ed25519.Verify(key, msg, sig)), nil
+}
+
+// SM2 signature verification implemented as a native contract
+type sm2Verify struct{}
+
+// RequiredGas returns the gas required to execute the native contract.
+func (c *sm2Verify) RequiredGas(input []byte) uint64 {
+	return params.Sm2VerifyGas
+}
+
+// Run verifies the signature of the input, which is (digest, r, s, x, y) with
+// each 32 bytes and x, y the public key of the signer.
+func (c *sm2Verify) Run(in []byte) ([]byte, error) {
+	const sm2VerifyInputLength = 160
+
+	in = common.RightPadBytes(in, sm2VerifyInputLength)
+	key := &ecdsa.PublicKey{
+		Curve: sm2.P256(),
+		X:     new(big.Int).SetBytes(in[96:128]),
+		Y:     new(big.Int).SetBytes(in[128:160]),
+	}
+	r := new(big.Int).SetBytes(in[32:64])
+	s := new(big.Int).SetBytes(in[64:96])
+
+	return verified(sm2.Verify(key, in[:32], r, s)), nil
+}
+
+// SM3 implemented as a native contract
+type sm3hash struct{}
+
+// RequiredGas returns the gas required to execute the native contract.
+//
+// This method does not require any overflow checking as the input size gas costs
+// required for anything significant is so high it's impossible to pay for.
+func (c *sm3hash) RequiredGas(input []byte) uint64 {
+	return uint64(len(input)+31)/32*params.Sm3WordGas + params.Sm3Gas
+}
+func (c *sm3hash) Run(in []byte) ([]byte, error) {
+	h := sm3.Sum(in)
+	return h[:], nil
+}
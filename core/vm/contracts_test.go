@@ -1 +1,98 @@
 package vm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto/sm2"
+	"github.com/ethereum/go-ethereum/params"
+	"golang.org/x/crypto/ed25519"
+)
+
+// Tests that the ed25519 contract accepts valid signatures only.
+func TestEd25519Verify(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	msg := []byte("native contract")
+	sig := ed25519.Sign(priv, msg)
+
+	input := append(append(append([]byte{}, pub...), sig...), msg...)
+	if out, err := new(ed25519Verify).Run(input); err != nil || !bytes.Equal(out, verified(true)) {
+		t.Errorf("valid signature: have %x, %v, want %x", out, err, verified(true))
+	}
+	input[len(input)-1] ^= 0x01
+	if out, err := new(ed25519Verify).Run(input); err != nil || !bytes.Equal(out, verified(false)) {
+		t.Errorf("invalid signature: have %x, %v, want %x", out, err, verified(false))
+	}
+	if _, err := new(ed25519Verify).Run(input[:95]); err != errBadPrecompileInput {
+		t.Errorf("short input: error mismatch: have %v, want %v", err, errBadPrecompileInput)
+	}
+}
+
+// Tests that the SM2 contract accepts valid signatures only.
+func TestSm2Verify(t *testing.T) {
+	key, _ := sm2.GenerateKey(rand.Reader)
+	digest := sm2.Digest(&key.PublicKey, sm2.DefaultID, []byte("native contract"))
+	r, s, err := sm2.Sign(rand.Reader, key, digest)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	input := append([]byte{}, digest...)
+	for _, n := range []*big.Int{r, s, key.PublicKey.X, key.PublicKey.Y} {
+		input = append(input, math.PaddedBigBytes(n, 32)...)
+	}
+	if out, err := new(sm2Verify).Run(input); err != nil || !bytes.Equal(out, verified(true)) {
+		t.Errorf("valid signature: have %x, %v, want %x", out, err, verified(true))
+	}
+	input[0] ^= 0x01
+	if out, err := new(sm2Verify).Run(input); err != nil || !bytes.Equal(out, verified(false)) {
+		t.Errorf("invalid signature: have %x, %v, want %x", out, err, verified(false))
+	}
+}
+
+// Tests that native contracts are only callable from their activation block.
+func TestActivePrecompiles(t *testing.T) {
+	addr := common.BytesToAddress([]byte{1, 0})
+	config := &params.ChainConfig{
+		Precompiles: []*params.PrecompileConfig{{Name: "sm3", Address: addr, Block: big.NewInt(10)}},
+	}
+	if p := activePrecompiles(config, big.NewInt(9))[addr]; p != nil {
+		t.Errorf("native contract active before its block")
+	}
+	contracts := activePrecompiles(config, big.NewInt(10))
+	if p := contracts[addr]; p != NativeContracts["sm3"] {
+		t.Errorf("native contract mismatch: have %v, want %v", p, NativeContracts["sm3"])
+	}
+	if len(contracts) != len(PrecompiledContracts)+1 {
+		t.Errorf("contract count mismatch: have %d, want %d", len(contracts), len(PrecompiledContracts)+1)
+	}
+	if p := activePrecompiles(params.TestChainConfig, big.NewInt(10))[addr]; p != nil {
+		t.Errorf("native contract active without being configured")
+	}
+}
+
+// Tests that invalid native contract configurations are rejected.
+func TestValidatePrecompiles(t *testing.T) {
+	tests := []struct {
+		precompiles []*params.PrecompileConfig
+		valid       bool
+	}{
+		{nil, true},
+		{[]*params.PrecompileConfig{{Name: "sm3", Address: common.BytesToAddress([]byte{1, 0})}}, true},
+		{[]*params.PrecompileConfig{{Name: "sm4", Address: common.BytesToAddress([]byte{1, 0})}}, false},
+		{[]*params.PrecompileConfig{{Name: "sm3", Address: common.BytesToAddress([]byte{2})}}, false},
+		{[]*params.PrecompileConfig{
+			{Name: "sm3", Address: common.BytesToAddress([]byte{1, 0})},
+			{Name: "sm2verify", Address: common.BytesToAddress([]byte{1, 0})},
+		}, false},
+	}
+	for i, tt := range tests {
+		err := ValidatePrecompiles(&params.ChainConfig{Precompiles: tt.precompiles})
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v (%v), want %v", i, valid, err, tt.valid)
+		}
+	}
+}
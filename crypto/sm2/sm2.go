@@ -0,0 +1,140 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package sm2 implements SM2 digital signatures over the recommended curve of
+// GB/T 32918-2016.
+package sm2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto/sm3"
+)
+
+// DefaultID is the user identity signers use unless agreed otherwise.
+var DefaultID = []byte("1234567812345678")
+
+var errInvalidKey = errors.New("invalid SM2 private key")
+
+var curve = &elliptic.CurveParams{Name: "SM2-P-256", BitSize: 256}
+
+func init() {
+	curve.P, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF", 16)
+	curve.N, _ = new(big.Int).SetString("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123", 16)
+	curve.B, _ = new(big.Int).SetString("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93", 16)
+	curve.Gx, _ = new(big.Int).SetString("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7", 16)
+	curve.Gy, _ = new(big.Int).SetString("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0", 16)
+}
+
+// P256 returns the recommended SM2 curve. Its coefficient a equals -3, so the
+// generic curve arithmetic of the elliptic package applies.
+func P256() elliptic.Curve {
+	return curve
+}
+
+// GenerateKey generates a new SM2 private key.
+func GenerateKey(rand io.Reader) (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(curve, rand)
+}
+
+// Digest computes the message digest e signed by the owner of the public key
+// with the given identity, hashing the identity, the curve and the key into Z
+// and then Z together with the message.
+func Digest(pub *ecdsa.PublicKey, id, msg []byte) []byte {
+	a := new(big.Int).Sub(curve.P, big.NewInt(3))
+
+	h := sm3.New()
+	bits := len(id) * 8
+	h.Write([]byte{byte(bits >> 8), byte(bits)})
+	h.Write(id)
+	h.Write(math.PaddedBigBytes(a, 32))
+	h.Write(math.PaddedBigBytes(curve.B, 32))
+	h.Write(math.PaddedBigBytes(curve.Gx, 32))
+	h.Write(math.PaddedBigBytes(curve.Gy, 32))
+	h.Write(math.PaddedBigBytes(pub.X, 32))
+	h.Write(math.PaddedBigBytes(pub.Y, 32))
+	z := h.Sum(nil)
+
+	h.Reset()
+	h.Write(z)
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// Sign signs the message digest with the private key, returning the signature
+// as the pair of integers r and s.
+func Sign(rand io.Reader, priv *ecdsa.PrivateKey, digest []byte) (r, s *big.Int, err error) {
+	n := curve.N
+	e := new(big.Int).SetBytes(digest)
+
+	// (1 + d)^-1 must exist for the key to be usable at all
+	inv := new(big.Int).Add(priv.D, big.NewInt(1))
+	if inv.ModInverse(inv, n) == nil {
+		return nil, nil, errInvalidKey
+	}
+	for {
+		k, err := ecdsa.GenerateKey(curve, rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		// r = (e + x1) mod n, retry if r = 0 or r + k = n
+		r = new(big.Int).Add(e, k.PublicKey.X)
+		r.Mod(r, n)
+		if r.Sign() == 0 || new(big.Int).Add(r, k.D).Cmp(n) == 0 {
+			continue
+		}
+		// s = (1 + d)^-1 * (k - r * d) mod n, retry if s = 0
+		s = new(big.Int).Mul(r, priv.D)
+		s.Sub(k.D, s)
+		s.Mul(s, inv)
+		s.Mod(s, n)
+		if s.Sign() != 0 {
+			return r, s, nil
+		}
+	}
+}
+
+// Verify reports whether the signature r, s of the message digest is valid for
+// the public key.
+func Verify(pub *ecdsa.PublicKey, digest []byte, r, s *big.Int) bool {
+	n := curve.N
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+	if pub.X == nil || pub.Y == nil || !curve.IsOnCurve(pub.X, pub.Y) {
+		return false
+	}
+	// t = (r + s) mod n, which must not be zero
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return false
+	}
+	// (x1, y1) = [s]G + [t]P, valid if r = (e + x1) mod n
+	x1, y1 := curve.ScalarBaseMult(s.Bytes())
+	x2, y2 := curve.ScalarMult(pub.X, pub.Y, t.Bytes())
+	x, _ := curve.Add(x1, y1, x2, y2)
+
+	v := new(big.Int).SetBytes(digest)
+	v.Add(v, x)
+	v.Mod(v, n)
+	return v.Cmp(r) == 0
+}
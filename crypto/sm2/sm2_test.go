@@ -0,0 +1,53 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package sm2
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+// Tests that signatures verify against the signed digest and key only.
+func TestSignVerify(t *testing.T) {
+	key, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	other, _ := GenerateKey(rand.Reader)
+
+	digest := Digest(&key.PublicKey, DefaultID, []byte("message digest"))
+	r, s, err := Sign(rand.Reader, key, digest)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if !Verify(&key.PublicKey, digest, r, s) {
+		t.Fatalf("valid signature rejected")
+	}
+	if Verify(&other.PublicKey, digest, r, s) {
+		t.Errorf("signature accepted for another key")
+	}
+	if Verify(&key.PublicKey, Digest(&key.PublicKey, DefaultID, []byte("message digesT")), r, s) {
+		t.Errorf("signature accepted for another message")
+	}
+	if Verify(&key.PublicKey, digest, new(big.Int).Add(r, big.NewInt(1)), s) {
+		t.Errorf("modified signature accepted")
+	}
+	if Verify(&key.PublicKey, digest, r, new(big.Int).Add(s, curve.N)) {
+		t.Errorf("out of range signature accepted")
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package sm3 implements the SM3 hash algorithm as defined in GB/T 32905-2016.
+package sm3
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// Size is the size of an SM3 checksum in bytes.
+const Size = 32
+
+// BlockSize is the block size of SM3 in bytes.
+const BlockSize = 64
+
+var iv = [8]uint32{0x7380166f, 0x4914b2b9, 0x172442d7, 0xda8a0600, 0xa96f30bc, 0x163138aa, 0xe38dee4d, 0xb0fb0e4e}
+
+// digest represents the partial evaluation of a checksum.
+type digest struct {
+	h   [8]uint32
+	x   [BlockSize]byte
+	nx  int
+	len uint64
+}
+
+// New returns a new hash.Hash computing the SM3 checksum.
+func New() hash.Hash {
+	d := new(digest)
+	d.Reset()
+	return d
+}
+
+// Sum returns the SM3 checksum of the data.
+func Sum(data []byte) [Size]byte {
+	var d digest
+	d.Reset()
+	d.Write(data)
+
+	var sum [Size]byte
+	d.checkSum(sum[:0])
+	return sum
+}
+
+func (d *digest) Reset() {
+	d.h = iv
+	d.nx = 0
+	d.len = 0
+}
+
+func (d *digest) Size() int { return Size }
+
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Write(p []byte) (int, error) {
+	n := len(p)
+	d.len += uint64(n)
+	if d.nx > 0 {
+		c := copy(d.x[d.nx:], p)
+		d.nx += c
+		if d.nx == BlockSize {
+			d.block(d.x[:])
+			d.nx = 0
+		}
+		p = p[c:]
+	}
+	for len(p) >= BlockSize {
+		d.block(p[:BlockSize])
+		p = p[BlockSize:]
+	}
+	if len(p) > 0 {
+		d.nx = copy(d.x[:], p)
+	}
+	return n, nil
+}
+
+func (d *digest) Sum(in []byte) []byte {
+	// Make a copy of d so that the caller can keep writing and summing
+	d0 := *d
+	return d0.checkSum(in)
+}
+
+// checkSum pads the message and appends the final digest to in.
+func (d *digest) checkSum(in []byte) []byte {
+	length := d.len
+
+	var pad [BlockSize + 8]byte
+	pad[0] = 0x80
+	if length%BlockSize < 56 {
+		d.Write(pad[:56-length%BlockSize])
+	} else {
+		d.Write(pad[:BlockSize+56-length%BlockSize])
+	}
+	binary.BigEndian.PutUint64(pad[:8], length<<3)
+	d.Write(pad[:8])
+
+	var out [Size]byte
+	for i, h := range d.h {
+		binary.BigEndian.PutUint32(out[i*4:], h)
+	}
+	return append(in, out[:]...)
+}
+
+func rotl(x uint32, n uint) uint32 {
+	n %= 32
+	return x<<n | x>>(32-n)
+}
+
+func p0(x uint32) uint32 { return x ^ rotl(x, 9) ^ rotl(x, 17) }
+func p1(x uint32) uint32 { return x ^ rotl(x, 15) ^ rotl(x, 23) }
+
+// block runs the compression function over a single message block.
+func (d *digest) block(p []byte) {
+	var w [68]uint32
+	var w1 [64]uint32
+
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(p[i*4:])
+	}
+	for i := 16; i < 68; i++ {
+		w[i] = p1(w[i-16]^w[i-9]^rotl(w[i-3], 15)) ^ rotl(w[i-13], 7) ^ w[i-6]
+	}
+	for i := 0; i < 64; i++ {
+		w1[i] = w[i] ^ w[i+4]
+	}
+	a, b, c, e, f, g, h, dd := d.h[0], d.h[1], d.h[2], d.h[4], d.h[5], d.h[6], d.h[7], d.h[3]
+	for i := 0; i < 64; i++ {
+		t := uint32(0x79cc4519)
+		if i >= 16 {
+			t = 0x7a879d8a
+		}
+		ss1 := rotl(rotl(a, 12)+e+rotl(t, uint(i)), 7)
+		ss2 := ss1 ^ rotl(a, 12)
+
+		var ff, gg uint32
+		if i < 16 {
+			ff = a ^ b ^ c
+			gg = e ^ f ^ g
+		} else {
+			ff = (a & b) | (a & c) | (b & c)
+			gg = (e & f) | (^e & g)
+		}
+		tt1 := ff + dd + ss2 + w1[i]
+		tt2 := gg + h + ss1 + w[i]
+
+		dd = c
+		c = rotl(b, 9)
+		b = a
+		a = tt1
+		h = g
+		g = rotl(f, 19)
+		f = e
+		e = p0(tt2)
+	}
+	d.h[0] ^= a
+	d.h[1] ^= b
+	d.h[2] ^= c
+	d.h[3] ^= dd
+	d.h[4] ^= e
+	d.h[5] ^= f
+	d.h[6] ^= g
+	d.h[7] ^= h
+}
@@ -0,0 +1,48 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package sm3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Tests the checksums against the examples of the standard.
+func TestSum(t *testing.T) {
+	tests := []struct {
+		input string
+		sum   string
+	}{
+		{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+		{string(bytes.Repeat([]byte("abcd"), 16)), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+	}
+	for i, tt := range tests {
+		sum := Sum([]byte(tt.input))
+		if have := hex.EncodeToString(sum[:]); have != tt.sum {
+			t.Errorf("test %d: checksum mismatch: have %s, want %s", i, have, tt.sum)
+		}
+		// Feed the input byte by byte to check the buffering too
+		h := New()
+		for j := 0; j < len(tt.input); j++ {
+			h.Write([]byte{tt.input[j]})
+		}
+		if have := hex.EncodeToString(h.Sum(nil)); have != tt.sum {
+			t.Errorf("test %d: streamed checksum mismatch: have %s, want %s", i, have, tt.sum)
+		}
+	}
+}
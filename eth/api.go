@@ -30,6 +30,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -232,8 +233,14 @@ func (api *PrivateAdminAPI) ExportChain(file string) (bool, error) {
 		defer writer.(*gzip.Writer).Close()
 	}
 
-	// Export the blockchain
-	if err := api.eth.BlockChain().Export(writer); err != nil {
+	// Export the blockchain, along with the commit certificates on BFT chains
+	chain := api.eth.BlockChain()
+	if engine, ok := bft.FromEngine(api.eth.Engine()); ok {
+		err = engine.ExportChain(chain, writer, 0, chain.CurrentBlock().NumberU64())
+	} else {
+		err = chain.Export(writer)
+	}
+	if err != nil {
 		return false, err
 	}
 	return true, nil
@@ -267,17 +274,23 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 
 	// Run actual the import in pre-configured batches
 	stream := rlp.NewStream(reader, 0)
+	engine, _ := bft.FromEngine(api.eth.Engine())
 
 	blocks, index := make([]*types.Block, 0, 2500), 0
 	for batch := 0; ; batch++ {
 		// Load a batch of blocks from the input file
 		for len(blocks) < cap(blocks) {
-			block := new(types.Block)
-			if err := stream.Decode(block); err == io.EOF {
+			block, cert, err := bft.DecodeBlock(stream)
+			if err == io.EOF {
 				break
 			} else if err != nil {
 				return false, fmt.Errorf("block %d: failed to parse: %v", index, err)
 			}
+			if cert != nil && engine != nil {
+				if err := engine.ImportCertificate(block.Header(), cert); err != nil {
+					return false, fmt.Errorf("block %d: invalid certificate: %v", index, err)
+				}
+			}
 			blocks = append(blocks, block)
 			index++
 		}
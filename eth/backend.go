@@ -83,6 +83,7 @@ type Ethereum struct {
 
 	networkId     uint64
 	netRPCService *ethapi.PublicNetAPI
+	nodeFilter    *registryNodeFilter // Filter of the nodes allowed to connect (nil = any)
 
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
 }
@@ -149,8 +150,23 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		eth.blockchain.SetHead(compat.RewindTo)
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
+	if config.PrivateManager != nil {
+		eth.blockchain.SetPrivateManager(config.PrivateManager)
+	}
+	if config.StateGC != nil {
+		eth.blockchain.SetStateGC(*config.StateGC)
+	}
 
-	newPool := core.NewTxPool(config.TxPool, eth.chainConfig, eth.EventMux(), eth.blockchain.State, eth.blockchain.GasLimit)
+	if config.NodeRegistry {
+		if chainConfig.Permissions == nil {
+			return nil, errors.New("node registry requires a chain with a permission config")
+		}
+		eth.nodeFilter = newRegistryNodeFilter(eth.blockchain, chainConfig.Permissions, eth.eventMux)
+	}
+	if config.TxPool.Journal != "" {
+		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
+	}
+	newPool := core.NewTxPool(config.TxPool, eth.chainConfig, eth.EventMux(), eth.blockchain.State, eth.blockchain.GasLimit, eth.blockchain.CurrentBlock)
 	eth.txPool = newPool
 
 	maxPeers := config.MaxPeers
@@ -167,18 +183,23 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, maxPeers, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	eth.protocolManager.checkpoint = config.Checkpoint
 
-	if config.BFT {
-		if bft, ok := eth.engine.(*bft.BFT); ok {
-			bftDb, err := ctx.OpenDatabase("bftData", config.DatabaseCache, config.DatabaseHandles)
-			if err != nil {
-				return nil, err
-			}
-			if err = bft.SetupProtocolManager(chainConfig, eth.protocolManager.networkId, eth.eventMux, eth.txPool, eth.blockchain, chainDb, bftDb, vmConfig, config.Validators, config.PrivateKeyHex, config.Etherbase, config.AllowEmpty, config.ByzantineMode); err != nil {
+	if engine, ok := bft.FromEngine(eth.engine); ok {
+		bftDb, err := ctx.OpenDatabase("bftData", config.DatabaseCache, config.DatabaseHandles)
+		if err != nil {
+			return nil, err
+		}
+		if err = engine.SetupProtocolManager(chainConfig, eth.protocolManager.networkId, eth.eventMux, eth.txPool, eth.blockchain, chainDb, bftDb, vmConfig, config.Validators, config.Signer, config.AllowEmpty, config.ByzantineMode); err != nil {
+			return nil, err
+		}
+		// Chains already past the switch to BFT join consensus right away
+		if migration, ok := eth.engine.(*bft.Migration); ok {
+			if err = migration.Activate(eth.blockchain); err != nil {
 				return nil, err
 			}
-			bft.Start()
 		}
+		engine.Start()
 	}
 
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine)
@@ -225,10 +246,25 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Data
 
 // CreateConsensusEngine creates the required type of consensus engine instance for an Ethereum service
 func CreateConsensusEngine(ctx *node.ServiceContext, config *Config, chainConfig *params.ChainConfig, db ethdb.Database) consensus.Engine {
+	// If the chain switches to byzantine fault tolerance, wrap the current engine
+	if chainConfig.BFT != nil && chainConfig.BftBlock != nil && chainConfig.BftBlock.Sign() > 0 {
+		legacyConfig, legacyChainConfig := *config, *chainConfig
+		legacyConfig.BFT = false
+		legacyChainConfig.BFT, legacyChainConfig.BftBlock = nil, nil
+		legacy := CreateConsensusEngine(ctx, &legacyConfig, &legacyChainConfig, db)
+
+		config.SyncMode = downloader.FullSync
+		return bft.NewMigration(chainConfig, legacy, bft.New(chainConfig, db))
+	}
 	// If proof-of-authority is requested, set it up
 	if chainConfig.Clique != nil {
 		return clique.New(chainConfig.Clique, db)
 	}
+	// If byzantine fault tolerance is requested, set it up (only full sync is supported)
+	if config.BFT || chainConfig.BFT != nil {
+		config.SyncMode = downloader.FullSync
+		return bft.New(chainConfig, db)
+	}
 	// Otherwise assume proof-of-work
 	switch {
 	case config.PowFake:
@@ -241,17 +277,10 @@ func CreateConsensusEngine(ctx *node.ServiceContext, config *Config, chainConfig
 		log.Warn("Ethash used in shared mode")
 		return ethash.NewShared()
 	default:
-		log.Info("config bft is ", "bft", config.BFT)
-		if config.BFT {
-			engine := bft.New(chainConfig, db)
-			config.SyncMode = downloader.FullSync
-			return engine
-		} else {
-			engine := ethash.New(ctx.ResolvePath(config.EthashCacheDir), config.EthashCachesInMem, config.EthashCachesOnDisk,
-				config.EthashDatasetDir, config.EthashDatasetsInMem, config.EthashDatasetsOnDisk)
-			engine.SetThreads(-1) // Disable CPU mining
-			return engine
-		}
+		engine := ethash.New(ctx.ResolvePath(config.EthashCacheDir), config.EthashCachesInMem, config.EthashCachesOnDisk,
+			config.EthashDatasetDir, config.EthashDatasetsInMem, config.EthashDatasetsOnDisk)
+		engine.SetThreads(-1) // Disable CPU mining
+		return engine
 	}
 }
 
@@ -347,7 +376,11 @@ func (s *Ethereum) StartMining(local bool) error {
 		log.Error("Cannot start mining without etherbase", "err", err)
 		return fmt.Errorf("etherbase missing: %v", err)
 	}
-	if clique, ok := s.engine.(*clique.Clique); ok {
+	engine := s.engine
+	if migration, ok := engine.(*bft.Migration); ok {
+		engine = migration.Legacy()
+	}
+	if clique, ok := engine.(*clique.Clique); ok {
 		wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
 		if wallet == nil || err != nil {
 			log.Error("Etherbase account unavailable locally", "err", err)
@@ -384,8 +417,8 @@ func (s *Ethereum) Downloader() *downloader.Downloader { return s.protocolManage
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
-	if bft, ok := s.engine.(*bft.BFT); ok {
-		return append(s.protocolManager.SubProtocols, bft.Protocols()...)
+	if engine, ok := bft.FromEngine(s.engine); ok {
+		return append(s.protocolManager.SubProtocols, engine.Protocols()...)
 	} else if s.lesServer == nil {
 		return s.protocolManager.SubProtocols
 	} else {
@@ -397,6 +430,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 // Ethereum protocol implementation.
 func (s *Ethereum) Start(srvr *p2p.Server) error {
 	s.netRPCService = ethapi.NewPublicNetAPI(srvr, s.NetVersion())
+	if s.nodeFilter != nil {
+		srvr.SetNodeFilter(s.nodeFilter)
+	}
 
 	s.protocolManager.Start()
 	if s.lesServer != nil {
@@ -411,6 +447,9 @@ func (s *Ethereum) Stop() error {
 	if s.stopDbUpgrade != nil {
 		s.stopDbUpgrade()
 	}
+	if s.nodeFilter != nil {
+		s.nodeFilter.stop()
+	}
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
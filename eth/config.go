@@ -25,10 +25,12 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/private"
 )
 
 // DefaultConfig contains default settings for use on the Ethereum main net.
@@ -86,6 +88,9 @@ type Config struct {
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
 
+	// Garbage collection of the states of old blocks (nil = archive, all states kept)
+	StateGC *core.StateGCConfig `toml:",omitempty"`
+
 	// Mining-related options
 	Etherbase    common.Address `toml:",omitempty"`
 	MinerThreads int            `toml:",omitempty"`
@@ -109,6 +114,12 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Only allow the nodes permitted by the permission config of the chain to connect
+	NodeRegistry bool
+
+	// Transaction manager of the private transactions (nil = public only)
+	PrivateManager private.TransactionManager `toml:"-"`
+
 	// Miscellaneous options
 	DocRoot   string `toml:"-"`
 	PowFake   bool   `toml:"-"`
@@ -118,7 +129,8 @@ type Config struct {
 	// bft parameters
 	BFT           bool
 	Validators    []common.Address
-	PrivateKeyHex string
+	Signer        bft.ConsensusSigner `toml:"-"` // Signer of the local validator (nil = observer)
+	Checkpoint    *bft.Checkpoint     `toml:"-"` // Trusted block to start syncing from (nil = genesis)
 	AllowEmpty    bool
 	ByzantineMode int
 }
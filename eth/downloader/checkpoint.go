@@ -0,0 +1,187 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// CheckpointAncestors is the number of headers preceding a checkpoint that are
+// retrieved along with it, so that the BLOCKHASH opcode works for the blocks
+// following the checkpoint.
+const CheckpointAncestors = 256
+
+// SyncCheckpoint retrieves a trusted checkpoint block from the given peer
+// together with up to CheckpointAncestors of its ancestor headers, and then the
+// state of the checkpoint through the regular state sync. The header of the
+// checkpoint is passed to verify before any state is downloaded. The ancestors
+// are returned ordered by number, for the caller to commit the checkpoint as
+// the head of the chain.
+func (d *Downloader) SyncCheckpoint(id string, hash common.Hash, verify func(*types.Header) error) ([]*types.Header, *types.Block, error) {
+	if !atomic.CompareAndSwapInt32(&d.synchronising, 0, 1) {
+		return nil, nil, errBusy
+	}
+	defer atomic.StoreInt32(&d.synchronising, 0)
+
+	d.cancelLock.Lock()
+	d.cancelCh = make(chan struct{})
+	d.cancelPeer = id
+	d.cancelLock.Unlock()
+
+	defer d.Cancel()
+
+	p := d.peers.Peer(id)
+	if p == nil {
+		return nil, nil, errUnknownPeer
+	}
+	if p.version < 63 {
+		return nil, nil, errTooOld
+	}
+	log.Info("Synchronising from checkpoint", "peer", id, "hash", hash)
+
+	// Retrieve the checkpoint and its ancestors, newest first
+	headers, err := d.fetchCheckpointHeaders(p, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := headers[0]
+	if err := verify(header); err != nil {
+		return nil, nil, err
+	}
+	body, err := d.fetchCheckpointBody(p, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Download the state of the checkpoint from all peers
+	start := time.Now()
+	if err := d.syncState(header.Root).Wait(); err != nil {
+		return nil, nil, err
+	}
+	log.Info("Checkpoint state synchronised", "number", header.Number, "hash", hash, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	ancestors := make([]*types.Header, 0, len(headers)-1)
+	for i := len(headers) - 1; i > 0; i-- {
+		ancestors = append(ancestors, headers[i])
+	}
+	return ancestors, types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles), nil
+}
+
+// fetchCheckpointHeaders retrieves the checkpoint header and its ancestors in
+// reverse order, checking that they form a chain.
+func (d *Downloader) fetchCheckpointHeaders(p *peer, hash common.Hash) ([]*types.Header, error) {
+	var headers []*types.Header
+	for origin := hash; len(headers) <= CheckpointAncestors; {
+		amount := CheckpointAncestors + 1 - len(headers)
+		if amount > MaxHeaderFetch {
+			amount = MaxHeaderFetch
+		}
+		batch, err := d.fetchReverseHeaders(p, origin, amount)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 || batch[0].Hash() != origin {
+			return nil, errInvalidChain
+		}
+		for i := 1; i < len(batch); i++ {
+			if batch[i].Hash() != batch[i-1].ParentHash || batch[i].Number.Uint64()+1 != batch[i-1].Number.Uint64() {
+				return nil, errInvalidChain
+			}
+		}
+		headers = append(headers, batch...)
+
+		// Stop at the genesis, or if the peer does not have more
+		last := headers[len(headers)-1]
+		if last.Number.Uint64() == 0 || len(batch) < amount {
+			break
+		}
+		origin = last.ParentHash
+	}
+	return headers, nil
+}
+
+// fetchReverseHeaders requests a batch of headers from origin towards the
+// genesis and waits for the response.
+func (d *Downloader) fetchReverseHeaders(p *peer, origin common.Hash, amount int) ([]*types.Header, error) {
+	go p.getRelHeaders(origin, amount, 0, true)
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelHeaderFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			return packet.(*headerPack).headers, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for checkpoint headers timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
+// fetchCheckpointBody retrieves the body of the checkpoint block.
+func (d *Downloader) fetchCheckpointBody(p *peer, header *types.Header) (*types.Body, error) {
+	go p.getBlockBodies([]common.Hash{header.Hash()})
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelBodyFetch
+
+		case packet := <-d.bodyCh:
+			if packet.PeerId() != p.id {
+				log.Debug("Received bodies from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			bodies := packet.(*bodyPack)
+			if len(bodies.transactions) != 1 || len(bodies.uncles) != 1 {
+				return nil, errInvalidBody
+			}
+			txs, uncles := bodies.transactions[0], bodies.uncles[0]
+			if types.DeriveSha(types.Transactions(txs)) != header.TxHash || types.CalcUncleHash(uncles) != header.UncleHash {
+				return nil, errInvalidBody
+			}
+			return &types.Body{Transactions: txs, Uncles: uncles}, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for checkpoint body timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.headerCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
@@ -34,17 +34,19 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/rcrowley/go-metrics"
 )
 
 var (
-	MaxHashFetch    = 512 // Amount of hashes to be fetched per retrieval request
-	MaxBlockFetch   = 128 // Amount of blocks to be fetched per retrieval request
-	MaxHeaderFetch  = 192 // Amount of block headers to be fetched per retrieval request
-	MaxSkeletonSize = 128 // Number of header fetches to need for a skeleton assembly
-	MaxBodyFetch    = 128 // Amount of block bodies to be fetched per retrieval request
-	MaxReceiptFetch = 256 // Amount of transaction receipts to allow fetching per request
-	MaxStateFetch   = 384 // Amount of node state values to allow fetching per request
+	MaxHashFetch    = 512  // Amount of hashes to be fetched per retrieval request
+	MaxBlockFetch   = 128  // Amount of blocks to be fetched per retrieval request
+	MaxHeaderFetch  = 192  // Amount of block headers to be fetched per retrieval request
+	MaxSkeletonSize = 128  // Number of header fetches to need for a skeleton assembly
+	MaxBodyFetch    = 128  // Amount of block bodies to be fetched per retrieval request
+	MaxReceiptFetch = 256  // Amount of transaction receipts to allow fetching per request
+	MaxStateFetch   = 384  // Amount of node state values to allow fetching per request
+	MaxRangeFetch   = 4096 // Amount of state trie entries to allow fetching per range request
 
 	MaxForkAncestry  = 3 * params.EpochDuration // Maximum chain reorganisation
 	rttMinEstimate   = 2 * time.Second          // Minimum round-trip time to target for download requests
@@ -147,6 +149,7 @@ type Downloader struct {
 	stateSyncStart chan *stateSync
 	trackStateReq  chan *stateReq
 	stateCh        chan dataPack // [eth/63] Channel receiving inbound node state data
+	rangeCh        chan dataPack // [eth/1063] Channel receiving inbound state trie ranges
 
 	// Cancellation and termination
 	cancelPeer string        // Identifier of the peer currently being used as the master (cancel on drop)
@@ -202,6 +205,7 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, hasHeader he
 		stateSyncStart: make(chan *stateSync),
 		trackStateReq:  make(chan *stateReq),
 		stateCh:        make(chan dataPack),
+		rangeCh:        make(chan dataPack),
 	}
 	go dl.qosTuner()
 	go dl.stateFetcher()
@@ -247,11 +251,11 @@ func (d *Downloader) Synchronising() bool {
 // used for fetching hashes and blocks from.
 func (d *Downloader) RegisterPeer(id string, version int, currentHead currentHeadRetrievalFn,
 	getRelHeaders relativeHeaderFetcherFn, getAbsHeaders absoluteHeaderFetcherFn, getBlockBodies blockBodyFetcherFn,
-	getReceipts receiptFetcherFn, getNodeData stateFetcherFn) error {
+	getReceipts receiptFetcherFn, getNodeData stateFetcherFn, getTrieRange trieRangeFetcherFn) error {
 
 	logger := log.New("peer", id)
 	logger.Trace("Registering sync peer")
-	if err := d.peers.Register(newPeer(id, version, currentHead, getRelHeaders, getAbsHeaders, getBlockBodies, getReceipts, getNodeData, logger)); err != nil {
+	if err := d.peers.Register(newPeer(id, version, currentHead, getRelHeaders, getAbsHeaders, getBlockBodies, getReceipts, getNodeData, getTrieRange, logger)); err != nil {
 		logger.Error("Failed to register sync peer", "err", err)
 		return err
 	}
@@ -1461,6 +1465,12 @@ func (d *Downloader) DeliverNodeData(id string, data [][]byte) (err error) {
 	return d.deliver(id, d.stateCh, &statePack{id, data}, stateInMeter, stateDropMeter)
 }
 
+// DeliverTrieRange injects a new range of state trie entries received from a
+// remote node, along with the proof of the range.
+func (d *Downloader) DeliverTrieRange(id string, keys [][]byte, values [][]byte, proof []rlp.RawValue) (err error) {
+	return d.deliver(id, d.rangeCh, &rangePack{id, keys, values, proof}, rangeInMeter, rangeDropMeter)
+}
+
 // deliver injects a new batch of data received from a remote node.
 func (d *Downloader) deliver(id string, destCh chan dataPack, packet dataPack, inMeter, dropMeter metrics.Meter) (err error) {
 	// Update the delivery metrics for both good and failed deliveries
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -408,11 +409,11 @@ func (dl *downloadTester) newSlowPeer(id string, version int, hashes []common.Ha
 	var err error
 	switch version {
 	case 62:
-		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), nil, nil)
+		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), nil, nil, nil)
 	case 63:
-		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), dl.peerGetReceiptsFn(id, delay), dl.peerGetNodeDataFn(id, delay))
+		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), dl.peerGetReceiptsFn(id, delay), dl.peerGetNodeDataFn(id, delay), nil)
 	case 64:
-		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), dl.peerGetReceiptsFn(id, delay), dl.peerGetNodeDataFn(id, delay))
+		err = dl.downloader.RegisterPeer(id, version, dl.peerCurrentHeadFn(id), dl.peerGetRelHeadersFn(id, delay), dl.peerGetAbsHeadersFn(id, delay), dl.peerGetBodiesFn(id, delay), dl.peerGetReceiptsFn(id, delay), dl.peerGetNodeDataFn(id, delay), dl.peerGetTrieRangeFn(id, delay))
 	}
 	if err == nil {
 		// Assign the owned hashes, headers and blocks to the peer (deep copy)
@@ -606,6 +607,39 @@ func (dl *downloadTester) peerGetNodeDataFn(id string, delay time.Duration) func
 	}
 }
 
+// peerGetTrieRangeFn constructs a getTrieRange method associated with a particular
+// peer in the download tester. The returned function can be used to retrieve
+// ranges of state trie entries from the particular peer. The ranges are kept
+// short to exercise their continuation.
+func (dl *downloadTester) peerGetTrieRangeFn(id string, delay time.Duration) func(common.Hash, common.Hash) error {
+	return func(root common.Hash, origin common.Hash) error {
+		time.Sleep(delay)
+
+		dl.lock.RLock()
+		defer dl.lock.RUnlock()
+
+		var (
+			keys, values [][]byte
+			proof        []rlp.RawValue
+		)
+		if t, err := trie.New(root, dl.peerDb); err == nil && !dl.peerMissingStates[id][root] {
+			it := trie.NewIterator(t.NodeIterator(origin[:]))
+			for len(keys) < 8 && it.Next() {
+				keys = append(keys, common.CopyBytes(it.Key))
+				values = append(values, common.CopyBytes(it.Value))
+			}
+			if len(keys) == 0 {
+				proof = t.Prove(origin[:])
+			} else {
+				proof = t.ProveRange(origin[:], keys[len(keys)-1])
+			}
+		}
+		go dl.downloader.DeliverTrieRange(id, keys, values, proof)
+
+		return nil
+	}
+}
+
 // assertOwnChain checks if the local chain contains the correct number of items
 // of the various chain components.
 func assertOwnChain(t *testing.T, tester *downloadTester, length int) {
@@ -1795,3 +1829,60 @@ func testFastCriticalRestarts(t *testing.T, protocol int, progress bool) {
 	// completed using a single mode of operation, whereas fast-then-slow can result
 	// in arbitrary intermediate state that's not cleanly verifiable.
 }
+
+// Tests that fast sync retrieves the state trie as ranges of entries from peers
+// supporting it, without having to resort to requesting individual trie nodes.
+func TestFastSyncStateRanges(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	// Create a small enough block chain to download
+	targetBlocks := blockCacheLimit - 15
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+
+	// Create a peer counting, but never answering node data requests
+	tester.newPeer("peer", 64, hashes, headers, blocks, receipts)
+
+	requests := int32(0)
+	tester.downloader.peers.peers["peer"].getNodeData = func(hashes []common.Hash) error {
+		atomic.AddInt32(&requests, 1)
+		go tester.downloader.DeliverNodeData("peer", nil)
+		return nil
+	}
+	if err := tester.sync("peer", nil, FastSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Fatalf("node data requests mismatch: have %d, want %d", n, 0)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+}
+
+// Tests that a timed out range request leaves the remaining ranges to the node
+// by node sync instead of retrying them with other peers.
+func TestStateRangeTimeoutFallback(t *testing.T) {
+	tester := newTester()
+	defer tester.terminate()
+
+	hashes, headers, blocks, receipts := tester.makeChain(1, 0, tester.genesis, nil, false)
+	tester.newPeer("peer", 64, hashes, headers, blocks, receipts)
+
+	r := newRangeSync(newStateSync(tester.downloader, blocks[hashes[0]].Root()), 4)
+	for _, task := range r.tasks {
+		task.inflight = true
+	}
+	if err := r.processRange(&stateReq{peer: tester.downloader.peers.Peer("peer"), task: r.tasks[0]}); err != nil {
+		t.Fatalf("failed to process timed out range: %v", err)
+	}
+	if !r.fallback {
+		t.Errorf("range sync not falling back after timeout")
+	}
+	if len(r.tasks) != 4 {
+		t.Errorf("remaining ranges mismatch: have %d, want %d", len(r.tasks), 4)
+	}
+	if len(r.tasks[0].attempts) != 0 {
+		t.Errorf("timed out range marked as failed by the peer")
+	}
+}
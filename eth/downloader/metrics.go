@@ -40,4 +40,7 @@ var (
 
 	stateInMeter   = metrics.NewMeter("eth/downloader/states/in")
 	stateDropMeter = metrics.NewMeter("eth/downloader/states/drop")
+
+	rangeInMeter   = metrics.NewMeter("eth/downloader/ranges/in")
+	rangeDropMeter = metrics.NewMeter("eth/downloader/ranges/drop")
 )
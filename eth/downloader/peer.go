@@ -48,6 +48,7 @@ type absoluteHeaderFetcherFn func(uint64, int, int, bool) error
 type blockBodyFetcherFn func([]common.Hash) error
 type receiptFetcherFn func([]common.Hash) error
 type stateFetcherFn func([]common.Hash) error
+type trieRangeFetcherFn func(common.Hash, common.Hash) error
 
 var (
 	errAlreadyFetching   = errors.New("already fetching blocks from peer")
@@ -87,6 +88,8 @@ type peer struct {
 	getReceipts receiptFetcherFn // [eth/63] Method to retrieve a batch of block transaction receipts
 	getNodeData stateFetcherFn   // [eth/63] Method to retrieve a batch of state trie data
 
+	getTrieRange trieRangeFetcherFn // [eth/1063] Method to retrieve a range of state trie entries
+
 	version int        // Eth protocol version number to switch strategies
 	log     log.Logger // Contextual logger to add extra infos to peer logs
 	lock    sync.RWMutex
@@ -96,7 +99,7 @@ type peer struct {
 // mechanisms.
 func newPeer(id string, version int, currentHead currentHeadRetrievalFn,
 	getRelHeaders relativeHeaderFetcherFn, getAbsHeaders absoluteHeaderFetcherFn, getBlockBodies blockBodyFetcherFn,
-	getReceipts receiptFetcherFn, getNodeData stateFetcherFn, logger log.Logger) *peer {
+	getReceipts receiptFetcherFn, getNodeData stateFetcherFn, getTrieRange trieRangeFetcherFn, logger log.Logger) *peer {
 
 	return &peer{
 		id:      id,
@@ -110,6 +113,8 @@ func newPeer(id string, version int, currentHead currentHeadRetrievalFn,
 		getReceipts: getReceipts,
 		getNodeData: getNodeData,
 
+		getTrieRange: getTrieRange,
+
 		version: version,
 		log:     logger,
 	}
@@ -210,6 +215,22 @@ func (p *peer) FetchNodeData(hashes []common.Hash) error {
 	return nil
 }
 
+// FetchTrieRange sends a state trie range retrieval request to the remote peer,
+// asking for the entries of the trie with the given root starting at origin.
+func (p *peer) FetchTrieRange(root common.Hash, origin common.Hash) error {
+	// Sanity check the protocol version
+	if p.getTrieRange == nil {
+		panic(fmt.Sprintf("trie range fetch [eth/1063] requested on eth/%d", p.version))
+	}
+	// Short circuit if the peer is already fetching
+	if !atomic.CompareAndSwapInt32(&p.stateIdle, 0, 1) {
+		return errAlreadyFetching
+	}
+	p.stateStarted = time.Now()
+	go p.getTrieRange(root, origin)
+	return nil
+}
+
 // SetHeadersIdle sets the peer to idle, allowing it to execute new header retrieval
 // requests. Its estimated header retrieval throughput is updated with that measured
 // just now.
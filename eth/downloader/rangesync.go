@@ -0,0 +1,488 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// rangeAccountSplits is the maximum number of parts the account trie is split
+// into to download them concurrently from multiple peers.
+const rangeAccountSplits = 16
+
+// emptyCode is the code hash of accounts without code.
+var emptyCode = crypto.Keccak256Hash(nil)
+
+// rangeTask is a range of consecutive entries of the account trie or of a storage
+// trie to download, containing a set of peers already attempted retrieval from.
+type rangeTask struct {
+	root     common.Hash         // Root of the trie the range belongs to
+	next     common.Hash         // Hashed key of the next entry to retrieve
+	last     common.Hash         // Hashed key of the last entry within the range
+	accounts []*rangeAccount     // Accounts owning the storage trie (nil for the account trie)
+	trie     *trie.Trie          // Storage trie rebuilt from the retrieved entries
+	inflight bool                // Whether the range is currently being retrieved
+	attempts map[string]struct{} // Peers failing to deliver the range
+}
+
+// rangeAccount is an account retrieved from the account trie, waiting for its
+// storage trie and code to be downloaded before being inserted into the state.
+type rangeAccount struct {
+	key     []byte // Hashed address of the account
+	value   []byte // RLP encoded account
+	pending int    // Number of storage tries and codes still missing
+}
+
+// rangeSync is the state of a range based state download, rebuilding the tries
+// locally from consecutive entries proven against their roots.
+type rangeSync struct {
+	s *stateSync // State sync the ranges are downloaded for
+
+	trie  *trie.Trie                 // Account trie rebuilt from the completed accounts
+	dirty int                        // Number of accounts inserted since the last commit
+	tasks []*rangeTask               // Trie ranges remaining to be downloaded
+	roots map[common.Hash]*rangeTask // Storage trie tasks by root to deduplicate them
+
+	codes   map[common.Hash]*stateTask      // Contract codes queued for retrieval
+	waiting map[common.Hash][]*rangeAccount // Accounts waiting for a contract code
+
+	inflight int  // Number of requests currently in flight
+	fallback bool // Whether the remaining ranges are left to the node sync
+}
+
+// newRangeSync creates a range download of the state of the given sync, split
+// into the given number of equal ranges of the account trie.
+func newRangeSync(s *stateSync, splits int) *rangeSync {
+	r := &rangeSync{
+		s:       s,
+		roots:   make(map[common.Hash]*rangeTask),
+		codes:   make(map[common.Hash]*stateTask),
+		waiting: make(map[common.Hash][]*rangeAccount),
+	}
+	r.trie, _ = trie.New(common.Hash{}, s.d.stateDB)
+
+	for i := 0; i < splits; i++ {
+		task := &rangeTask{root: s.root, attempts: make(map[string]struct{})}
+		task.next[0] = byte(i * 256 / splits)
+		for j := range task.last {
+			task.last[j] = 0xff
+		}
+		task.last[0] = byte((i+1)*256/splits - 1)
+		r.tasks = append(r.tasks, task)
+	}
+	return r
+}
+
+// syncRanges downloads the state as ranges of consecutive trie entries from the
+// peers supporting it. Accounts are only inserted once their storage and code
+// are complete, so every trie node written refers to complete data, allowing
+// the node by node sync afterwards to fill in whatever the ranges missed.
+//
+// The account trie is split into a part per peer serving ranges, so that a single
+// peer streams the state instead of paying a round trip per part. If a range
+// request times out, the remaining ranges are left to the node sync once the
+// requests in flight are done.
+func (s *stateSync) syncRanges() error {
+	if _, err := s.d.stateDB.Get(s.root[:]); err == nil {
+		return nil
+	}
+	splits := 0
+	for _, p := range s.d.peers.AllPeers() {
+		if p.getTrieRange != nil {
+			splits++
+		}
+	}
+	if splits == 0 {
+		return nil
+	}
+	if splits > rangeAccountSplits {
+		splits = rangeAccountSplits
+	}
+	// Listen for new peer events to assign tasks to them
+	newPeer := make(chan *peer, 1024)
+	peerSub := s.d.peers.SubscribeNewPeers(newPeer)
+	defer peerSub.Unsubscribe()
+
+	r := newRangeSync(s, splits)
+	for ((len(r.tasks) > 0 || len(r.waiting) > 0) && !r.fallback) || r.inflight > 0 {
+		if !r.fallback {
+			r.assignTasks()
+		}
+		// If no peer can be assigned anything, leave the rest to the node sync
+		if r.inflight == 0 {
+			log.Warn("State range sync stalled, falling back to node sync", "tasks", len(r.tasks), "codes", len(r.waiting))
+			break
+		}
+		select {
+		case <-newPeer:
+			// New peer arrived, try to assign it download tasks
+
+		case <-s.cancel:
+			return errCancelStateFetch
+
+		case req := <-s.deliver:
+			r.inflight--
+
+			var err error
+			if req.task != nil {
+				err = r.processRange(req)
+			} else {
+				err = r.processCodes(req)
+			}
+			if err != nil {
+				log.Warn("State range write error", "err", err)
+				return err
+			}
+		}
+	}
+	if root, err := r.commit(); err != nil {
+		return err
+	} else if root != s.root && !r.fallback && len(r.tasks) == 0 && len(r.waiting) == 0 {
+		log.Warn("State range sync root mismatch, falling back to node sync", "have", root, "want", s.root)
+	}
+	return nil
+}
+
+// assignTasks attempts to assign trie ranges to all idle peers supporting them,
+// and the retrieval of contract codes to the remaining ones.
+func (r *rangeSync) assignTasks() {
+	peers, _ := r.s.d.peers.NodeDataIdlePeers()
+	for _, p := range peers {
+		req := &stateReq{peer: p, timeout: r.s.d.requestTTL()}
+
+		if task := r.nextTask(p); task != nil {
+			req.task, task.inflight = task, true
+			req.peer.log.Trace("Requesting state range", "root", task.root, "origin", task.next)
+
+			select {
+			case r.s.d.trackStateReq <- req:
+				req.peer.FetchTrieRange(task.root, task.next)
+			case <-r.s.cancel:
+				return
+			}
+		} else {
+			r.fillCodes(p.NodeDataCapacity(r.s.d.requestRTT()), req)
+			if len(req.items) == 0 {
+				continue
+			}
+			req.peer.log.Trace("Requesting new batch of data", "type", "code", "count", len(req.items))
+
+			select {
+			case r.s.d.trackStateReq <- req:
+				req.peer.FetchNodeData(req.items)
+			case <-r.s.cancel:
+				return
+			}
+		}
+		r.inflight++
+	}
+}
+
+// nextTask returns the next trie range the peer may be requested for, preferring
+// storage tries to complete the accounts already retrieved.
+func (r *rangeSync) nextTask(p *peer) *rangeTask {
+	if p.getTrieRange == nil {
+		return nil
+	}
+	for i := len(r.tasks) - 1; i >= 0; i-- {
+		task := r.tasks[i]
+		if task.inflight {
+			continue
+		}
+		if _, ok := task.attempts[p.id]; ok {
+			continue
+		}
+		return task
+	}
+	return nil
+}
+
+// fillCodes fills the given request object with a maximum of n contract codes
+// to retrieve from the remote peer.
+func (r *rangeSync) fillCodes(n int, req *stateReq) {
+	req.items = make([]common.Hash, 0, n)
+	req.tasks = make(map[common.Hash]*stateTask, n)
+	for hash, t := range r.codes {
+		// Stop when we've gathered enough requests
+		if len(req.items) == n {
+			break
+		}
+		// Skip any requests we've already tried from this peer
+		if _, ok := t.attempts[req.peer.id]; ok {
+			continue
+		}
+		req.items = append(req.items, hash)
+		req.tasks[hash] = t
+		delete(r.codes, hash)
+	}
+}
+
+// processRange verifies a delivered trie range against the root of its trie and
+// injects the entries into the state, scheduling the rest of the range.
+func (r *rangeSync) processRange(req *stateReq) error {
+	task := req.task
+	task.inflight = false
+
+	// Timeouts leave the remaining ranges to the node sync, empty responses are
+	// retried with another peer
+	if req.timedOut() {
+		if !r.fallback {
+			log.Warn("State range request timed out, falling back to node sync", "peer", req.peer.id, "tasks", len(r.tasks), "codes", len(r.waiting))
+			r.fallback = true
+		}
+		req.peer.SetNodeDataIdle(0)
+		return nil
+	}
+	if len(req.entries.keys) == 0 && len(req.entries.proof) == 0 {
+		task.attempts[req.peer.id] = struct{}{}
+		req.peer.SetNodeDataIdle(0)
+		return nil
+	}
+	keys, values := req.entries.keys, req.entries.values
+
+	more, err := trie.VerifyRangeProof(task.root, task.next[:], keys, values, req.entries.proof)
+	if err != nil {
+		log.Warn("Invalid state range, dropping peer", "peer", req.peer.id, "root", task.root, "err", err)
+		task.attempts[req.peer.id] = struct{}{}
+		req.peer.SetNodeDataIdle(0)
+		r.s.d.dropPeer(req.peer.id)
+		return nil
+	}
+	task.attempts = make(map[string]struct{})
+
+	// Inject all the entries falling into the range of the task
+	processed := 0
+	for i, key := range keys {
+		if bytes.Compare(key, task.last[:]) > 0 {
+			more = false
+			break
+		}
+		if task.accounts == nil {
+			if err := r.processAccount(key, values[i]); err != nil {
+				return err
+			}
+		} else {
+			task.trie.Update(key, values[i])
+		}
+		processed++
+	}
+	req.peer.SetNodeDataIdle(processed)
+
+	if processed > 0 {
+		if last := keys[processed-1]; more && !bytes.Equal(last, task.last[:]) {
+			task.next = common.BytesToHash(last)
+			incHash(&task.next)
+		} else {
+			more = false
+		}
+	}
+	// Flush the storage trie retrieved so far and finalize the task if done
+	if task.accounts != nil {
+		batch := r.s.d.stateDB.NewBatch()
+		root, err := task.trie.CommitTo(batch)
+		if err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		if !more {
+			if root != task.root {
+				log.Warn("Storage range root mismatch", "have", root, "want", task.root)
+			} else {
+				for _, account := range task.accounts {
+					r.complete(account)
+				}
+			}
+		}
+	}
+	if !more {
+		r.removeTask(task)
+	}
+	root, err := r.commit()
+	if err != nil {
+		return err
+	}
+	r.updateStats(processed, root)
+	return nil
+}
+
+// processAccount decodes an account retrieved from the account trie, scheduling
+// its storage and code for retrieval if they are missing.
+func (r *rangeSync) processAccount(key, value []byte) error {
+	var obj state.Account
+	if err := rlp.DecodeBytes(value, &obj); err != nil {
+		return fmt.Errorf("invalid account %x: %v", key, err)
+	}
+	account := &rangeAccount{key: common.CopyBytes(key), value: common.CopyBytes(value)}
+
+	if obj.Root != types.EmptyRootHash && !r.has(obj.Root) {
+		account.pending++
+		if task := r.roots[obj.Root]; task != nil {
+			task.accounts = append(task.accounts, account)
+		} else {
+			task = &rangeTask{root: obj.Root, accounts: []*rangeAccount{account}, attempts: make(map[string]struct{})}
+			for i := range task.last {
+				task.last[i] = 0xff
+			}
+			task.trie, _ = trie.New(common.Hash{}, r.s.d.stateDB)
+
+			r.tasks = append(r.tasks, task)
+			r.roots[obj.Root] = task
+		}
+	}
+	if hash := common.BytesToHash(obj.CodeHash); hash != emptyCode && !r.has(hash) {
+		account.pending++
+		if _, ok := r.waiting[hash]; !ok {
+			r.codes[hash] = &stateTask{make(map[string]struct{})}
+		}
+		r.waiting[hash] = append(r.waiting[hash], account)
+	}
+	if account.pending == 0 {
+		r.insert(account)
+	}
+	return nil
+}
+
+// processCodes injects a batch of delivered contract codes into the state,
+// re-queuing any codes that were requested but not delivered.
+func (r *rangeSync) processCodes(req *stateReq) error {
+	processed := 0
+	for _, blob := range req.response {
+		hash := crypto.Keccak256Hash(blob)
+		accounts, ok := r.waiting[hash]
+		if !ok {
+			continue
+		}
+		if err := r.s.d.stateDB.Put(hash[:], blob); err != nil {
+			return err
+		}
+		delete(r.waiting, hash)
+		delete(req.tasks, hash)
+
+		for _, account := range accounts {
+			r.complete(account)
+		}
+		processed++
+	}
+	req.peer.SetNodeDataIdle(len(req.response))
+
+	// Put unfulfilled codes back into the retry queue
+	for hash, task := range req.tasks {
+		if len(req.response) == 0 {
+			task.attempts[req.peer.id] = struct{}{}
+		}
+		r.codes[hash] = task
+	}
+	root, err := r.commit()
+	if err != nil {
+		return err
+	}
+	r.updateStats(processed, root)
+	return nil
+}
+
+// has returns whether the trie node or code with the given hash is present.
+func (r *rangeSync) has(hash common.Hash) bool {
+	_, err := r.s.d.stateDB.Get(hash[:])
+	return err == nil
+}
+
+// complete marks a storage trie or code of an account as retrieved, inserting
+// the account into the state once nothing is missing any more.
+func (r *rangeSync) complete(account *rangeAccount) {
+	if account.pending--; account.pending == 0 {
+		r.insert(account)
+	}
+}
+
+// insert injects a completed account into the account trie.
+func (r *rangeSync) insert(account *rangeAccount) {
+	r.trie.Update(account.key, account.value)
+	r.dirty++
+}
+
+// removeTask drops a completed trie range from the download tasks.
+func (r *rangeSync) removeTask(task *rangeTask) {
+	for i, t := range r.tasks {
+		if t == task {
+			r.tasks = append(r.tasks[:i], r.tasks[i+1:]...)
+			break
+		}
+	}
+	if task.accounts != nil {
+		delete(r.roots, task.root)
+	}
+}
+
+// commit flushes the accounts inserted since the last commit into the database,
+// returning the root of the account trie rebuilt so far.
+func (r *rangeSync) commit() (common.Hash, error) {
+	if r.dirty == 0 {
+		return r.trie.Hash(), nil
+	}
+	batch := r.s.d.stateDB.NewBatch()
+	root, err := r.trie.CommitTo(batch)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := batch.Write(); err != nil {
+		return common.Hash{}, err
+	}
+	r.dirty = 0
+
+	// If we're inside the critical section, reset fail counter since we progressed
+	if atomic.LoadUint32(&r.s.d.fsPivotFails) > 1 {
+		log.Trace("Fast-sync progressed, resetting fail counter", "previous", atomic.LoadUint32(&r.s.d.fsPivotFails))
+		atomic.StoreUint32(&r.s.d.fsPivotFails, 1) // Don't ever reset to 0, as that will unlock the pivot block
+	}
+	return root, nil
+}
+
+// updateStats bumps the state sync progress counters and displays a log message
+// for the user to see.
+func (r *rangeSync) updateStats(processed int, root common.Hash) {
+	if processed == 0 {
+		return
+	}
+	r.s.d.syncStatsLock.Lock()
+	defer r.s.d.syncStatsLock.Unlock()
+
+	r.s.d.syncStatsState.processed += uint64(processed)
+
+	log.Info("Imported new state range", "count", processed, "root", root, "processed", r.s.d.syncStatsState.processed, "ranges", len(r.tasks), "codes", len(r.waiting))
+}
+
+// incHash increments a hash in place as a big endian number, wrapping around
+// on overflow.
+func incHash(h *common.Hash) {
+	for i := len(h) - 1; i >= 0; i-- {
+		if h[i]++; h[i] != 0 {
+			return
+		}
+	}
+}
@@ -39,11 +39,14 @@ type stateReq struct {
 	timer    *time.Timer                // Timer to fire when the RTT timeout expires
 	peer     *peer                      // Peer that we're requesting from
 	response [][]byte                   // Response data of the peer (nil for timeouts)
+
+	task    *rangeTask // Trie range to download instead of the items (nil for node data)
+	entries *rangePack // Trie range response of the peer (nil for timeouts)
 }
 
 // timedOut returns if this request timed out.
 func (req *stateReq) timedOut() bool {
-	return req.response == nil
+	return req.response == nil && req.entries == nil
 }
 
 // stateSyncStats is a collection of progress stats to report during a state trie
@@ -78,6 +81,8 @@ func (d *Downloader) stateFetcher() {
 			}
 		case <-d.stateCh:
 			// Ignore state responses while no sync is running.
+		case <-d.rangeCh:
+			// Ignore state range responses while no sync is running.
 		case <-d.quitCh:
 			return
 		}
@@ -131,7 +136,7 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 		case pack := <-d.stateCh:
 			// Discard any data not requested (or previsouly timed out)
 			req := active[pack.PeerId()]
-			if req == nil {
+			if req == nil || req.task != nil {
 				log.Debug("Unrequested node data", "peer", pack.PeerId(), "len", pack.Items())
 				continue
 			}
@@ -142,6 +147,20 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			finished = append(finished, req)
 			delete(active, pack.PeerId())
 
+		case pack := <-d.rangeCh:
+			// Discard any range not requested (or previsouly timed out)
+			req := active[pack.PeerId()]
+			if req == nil || req.task == nil {
+				log.Debug("Unrequested state range", "peer", pack.PeerId(), "len", pack.Items())
+				continue
+			}
+			// Finalize the request and queue up for processing
+			req.timer.Stop()
+			req.entries = pack.(*rangePack)
+
+			finished = append(finished, req)
+			delete(active, pack.PeerId())
+
 		// Handle timed-out requests:
 		case req := <-timeout:
 			// If the peer is already requesting something else, ignore the stale timeout.
@@ -185,7 +204,8 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 // stateSync schedules requests for downloading a particular state trie defined
 // by a given state root.
 type stateSync struct {
-	d *Downloader // Downloader instance to access and manage current peerset
+	d    *Downloader // Downloader instance to access and manage current peerset
+	root common.Hash // Root of the state trie to download
 
 	sched  *state.StateSync           // State trie sync scheduler defining the tasks
 	keccak hash.Hash                  // Keccak256 hasher to verify deliveries with
@@ -209,7 +229,7 @@ type stateTask struct {
 func newStateSync(d *Downloader, root common.Hash) *stateSync {
 	return &stateSync{
 		d:       d,
-		sched:   state.NewStateSync(root, d.stateDB),
+		root:    root,
 		keccak:  sha3.NewKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
 		deliver: make(chan *stateReq),
@@ -220,9 +240,14 @@ func newStateSync(d *Downloader, root common.Hash) *stateSync {
 
 // run starts the task assignment and response processing loop, blocking until
 // it finishes, and finally notifying any goroutines waiting for the loop to
-// finish.
+// finish. The state is first downloaded range by range from peers supporting
+// it, with the trie node sync filling in anything left missing afterwards.
 func (s *stateSync) run() {
-	s.err = s.loop()
+	s.err = s.syncRanges()
+	if s.err == nil {
+		s.sched = state.NewStateSync(s.root, s.d.stateDB)
+		s.err = s.loop()
+	}
 	close(s.done)
 }
 
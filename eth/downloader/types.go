@@ -22,6 +22,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // headerCheckFn is a callback type for verifying a header's presence in the local chain.
@@ -118,3 +119,15 @@ type statePack struct {
 func (p *statePack) PeerId() string { return p.peerId }
 func (p *statePack) Items() int     { return len(p.states) }
 func (p *statePack) Stats() string  { return fmt.Sprintf("%d", len(p.states)) }
+
+// rangePack is a range of state trie entries returned by a peer.
+type rangePack struct {
+	peerId string
+	keys   [][]byte
+	values [][]byte
+	proof  []rlp.RawValue
+}
+
+func (p *rangePack) PeerId() string { return p.peerId }
+func (p *rangePack) Items() int     { return len(p.keys) }
+func (p *rangePack) Stats() string  { return fmt.Sprintf("%d:%d", len(p.keys), len(p.proof)) }
@@ -80,6 +80,10 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	gpo.cacheLock.RUnlock()
 
 	head, _ := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	// Gas is not paid for on free chains
+	if gpo.backend.ChainConfig().IsZeroGasPrice(new(big.Int).Add(head.Number, big.NewInt(1))) {
+		return new(big.Int), nil
+	}
 	headHash := head.Hash()
 	if headHash == lastHead {
 		return lastPrice, nil
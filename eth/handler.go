@@ -28,6 +28,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -40,6 +41,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 const (
@@ -69,8 +71,11 @@ type ProtocolManager struct {
 	blockchain  *core.BlockChain
 	chaindb     ethdb.Database
 	chainconfig *params.ChainConfig
+	engine      consensus.Engine
 	maxPeers    int
 
+	checkpoint *bft.Checkpoint // Trusted block to start an empty BFT chain from
+
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
@@ -103,6 +108,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		blockchain:  blockchain,
 		chaindb:     chaindb,
 		chainconfig: config,
+		engine:      engine,
 		maxPeers:    maxPeers,
 		peers:       newPeerSet(),
 		newPeerCh:   make(chan *peer),
@@ -268,7 +274,11 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	defer pm.removePeer(p.id)
 
 	// Register the peer in the downloader. If the downloader considers it banned, we disconnect
-	if err := pm.downloader.RegisterPeer(p.id, p.version, p.Head, p.RequestHeadersByHash, p.RequestHeadersByNumber, p.RequestBodies, p.RequestReceipts, p.RequestNodeData); err != nil {
+	var requestTrieRange func(common.Hash, common.Hash) error
+	if p.version >= ethRange {
+		requestTrieRange = p.RequestTrieRange
+	}
+	if err := pm.downloader.RegisterPeer(p.id, p.version, p.Head, p.RequestHeadersByHash, p.RequestHeadersByNumber, p.RequestBodies, p.RequestReceipts, p.RequestNodeData, requestTrieRange); err != nil {
 		return err
 	}
 	// Propagate existing transactions. new transactions appearing
@@ -545,6 +555,29 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			log.Debug("Failed to deliver node state data", "err", err)
 		}
 
+	case p.version >= ethRange && msg.Code == GetTrieRangeMsg:
+		// Decode the range query and serve it from the state known
+		var query getTrieRangeData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		return p.SendTrieRange(pm.serveTrieRange(query.Root, query.Origin))
+
+	case p.version >= ethRange && msg.Code == TrieRangeMsg:
+		// A range of state entries arrived to one of our previous requests
+		var data trieRangeData
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		keys := make([][]byte, len(data.Keys))
+		for i := range data.Keys {
+			keys[i] = data.Keys[i][:]
+		}
+		// Deliver all to the downloader
+		if err := pm.downloader.DeliverTrieRange(p.id, keys, data.Values, data.Proof); err != nil {
+			log.Debug("Failed to deliver state range", "err", err)
+		}
+
 	case p.version >= eth63 && msg.Code == GetReceiptsMsg:
 		// Decode the retrieval message
 		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
@@ -669,6 +702,36 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	return nil
 }
 
+// serveTrieRange gathers the entries of the account or storage trie with the
+// given root from origin on, until the fetch or network limits are reached,
+// along with the proof of the range. Unknown tries yield an empty response.
+func (pm *ProtocolManager) serveTrieRange(root common.Hash, origin common.Hash) *trieRangeData {
+	t, err := trie.New(root, pm.blockchain.TrieCache())
+	if err != nil {
+		return &trieRangeData{}
+	}
+	var (
+		data  = new(trieRangeData)
+		bytes int
+	)
+	it := trie.NewIterator(t.NodeIterator(origin[:]))
+	for bytes < softResponseLimit && len(data.Keys) < downloader.MaxRangeFetch && it.Next() {
+		data.Keys = append(data.Keys, common.BytesToHash(it.Key))
+		data.Values = append(data.Values, common.CopyBytes(it.Value))
+		bytes += common.HashLength + len(it.Value)
+	}
+	if it.Err != nil {
+		log.Debug("Failed to iterate state range", "root", root, "err", it.Err)
+		return &trieRangeData{}
+	}
+	if len(data.Keys) == 0 {
+		data.Proof = t.Prove(origin[:])
+	} else {
+		data.Proof = t.ProveRange(origin[:], data.Keys[len(data.Keys)-1][:])
+	}
+	return data
+}
+
 // BroadcastBlock will either propagate a block to a subset of it's peers, or
 // will only announce it's availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
@@ -0,0 +1,94 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// registryNodeFilter is a p2p.NodeFilter allowing the nodes whose key address
+// holds core.PermissionNode in the permission config of the chain, read from
+// the registry at the head block if the chain has one.
+type registryNodeFilter struct {
+	blockchain *core.BlockChain
+	config     *params.PermissionConfig
+
+	root common.Hash // Storage root of the registry at the last head
+	feed event.Feed
+	sub  *event.TypeMuxSubscription
+}
+
+func newRegistryNodeFilter(blockchain *core.BlockChain, config *params.PermissionConfig, mux *event.TypeMux) *registryNodeFilter {
+	f := &registryNodeFilter{blockchain: blockchain, config: config}
+	if config.Registry != nil {
+		f.root = f.registryRoot()
+		f.sub = mux.Subscribe(core.ChainHeadEvent{})
+		go f.loop()
+	}
+	return f
+}
+
+// loop announces the changes of the registry as new heads arrive.
+func (f *registryNodeFilter) loop() {
+	for range f.sub.Chan() {
+		if root := f.registryRoot(); root != f.root {
+			log.Info("Node registry updated", "root", root)
+			f.root = root
+			f.feed.Send(struct{}{})
+		}
+	}
+}
+
+func (f *registryNodeFilter) stop() {
+	if f.sub != nil {
+		f.sub.Unsubscribe()
+	}
+}
+
+// registryRoot returns the storage root of the registry at the head block.
+func (f *registryNodeFilter) registryRoot() common.Hash {
+	statedb, err := f.blockchain.State()
+	if err != nil {
+		return common.Hash{}
+	}
+	if trie := statedb.StorageTrie(*f.config.Registry); trie != nil {
+		return trie.Hash()
+	}
+	return common.Hash{}
+}
+
+// Allowed returns whether the address of the node key holds the node permission.
+func (f *registryNodeFilter) Allowed(id discover.NodeID) bool {
+	statedb, err := f.blockchain.State()
+	if err != nil {
+		log.Warn("Failed to check node permission", "id", id, "err", err)
+		return false
+	}
+	addr := common.BytesToAddress(crypto.Keccak256(id[:])[12:])
+	return core.Permissions(f.config, statedb, addr)&core.PermissionNode != 0
+}
+
+// SubscribeChanges notifies ch whenever the registry changed.
+func (f *registryNodeFilter) SubscribeChanges(ch chan<- struct{}) event.Subscription {
+	return f.feed.Subscribe(ch)
+}
@@ -181,6 +181,12 @@ func (p *peer) SendNodeData(data [][]byte) error {
 	return p2p.Send(p.rw, NodeDataMsg, data)
 }
 
+// SendTrieRange sends a range of trie entries along with its proof, corresponding
+// to the range requested.
+func (p *peer) SendTrieRange(data *trieRangeData) error {
+	return p2p.Send(p.rw, TrieRangeMsg, data)
+}
+
 // SendReceiptsRLP sends a batch of transaction receipts, corresponding to the
 // ones requested from an already RLP encoded format.
 func (p *peer) SendReceiptsRLP(receipts []rlp.RawValue) error {
@@ -222,6 +228,13 @@ func (p *peer) RequestNodeData(hashes []common.Hash) error {
 	return p2p.Send(p.rw, GetNodeDataMsg, hashes)
 }
 
+// RequestTrieRange fetches consecutive entries of an account or storage trie
+// from a node's known state, starting at the given hashed key.
+func (p *peer) RequestTrieRange(root common.Hash, origin common.Hash) error {
+	p.Log().Debug("Fetching range of state entries", "root", root, "origin", origin)
+	return p2p.Send(p.rw, GetTrieRangeMsg, &getTrieRangeData{Root: root, Origin: origin})
+}
+
 // RequestReceipts fetches a batch of transaction receipts from a remote node.
 func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of receipts", "count", len(hashes))
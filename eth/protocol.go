@@ -30,16 +30,21 @@ import (
 const (
 	eth62 = 62
 	eth63 = 63
+
+	// ethRange extends eth/63 with state trie ranges. It is numbered apart from
+	// the official versions so that peers running those never negotiate it and
+	// misread its messages.
+	ethRange = 1063
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "eth"
 
 // Supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{eth63, eth62}
+var ProtocolVersions = []uint{ethRange, eth63, eth62}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{19, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -60,6 +65,10 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+	// Protocol messages belonging to eth/1063
+	GetTrieRangeMsg = 0x11
+	TrieRangeMsg    = 0x12
 )
 
 type errCode int
@@ -175,3 +184,17 @@ type blockBody struct {
 
 // blockBodiesData is the network packet for block content distribution.
 type blockBodiesData []*blockBody
+
+// getTrieRangeData represents a query for consecutive entries of a state trie.
+type getTrieRangeData struct {
+	Root   common.Hash // Root of the account or storage trie to retrieve entries of
+	Origin common.Hash // Hashed key of the first entry to retrieve
+}
+
+// trieRangeData is the network packet for trie range distribution, proving the
+// entries against the trie root. Empty for tries the node doesn't have.
+type trieRangeData struct {
+	Keys   []common.Hash  // Hashed keys of the entries in ascending order
+	Values [][]byte       // Values of the entries
+	Proof  []rlp.RawValue // Proof of the origin and the last key
+}
@@ -17,11 +17,13 @@
 package eth
 
 import (
+	"errors"
 	"math/rand"
 	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/log"
@@ -172,6 +174,13 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 	if pTd.Cmp(td) <= 0 {
 		return
 	}
+	// Empty BFT chains skip the blocks preceding a trusted checkpoint
+	if pm.checkpoint != nil && currentBlock.NumberU64() == 0 {
+		if err := pm.syncCheckpoint(peer); err != nil {
+			log.Warn("Checkpoint synchronisation failed", "peer", peer.id, "err", err)
+			return
+		}
+	}
 	// Otherwise try to sync with the downloader
 	mode := downloader.FullSync
 	if atomic.LoadUint32(&pm.fastSync) == 1 {
@@ -208,3 +217,27 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		}
 	}
 }
+
+// syncCheckpoint starts the empty local chain from the configured checkpoint:
+// the checkpoint block is retrieved from the peer and verified against its
+// certificate, its state is downloaded and the block becomes the head of the
+// chain. Regular synchronisation then continues from it.
+func (pm *ProtocolManager) syncCheckpoint(peer *peer) error {
+	engine, ok := bft.FromEngine(pm.engine)
+	if !ok {
+		return errors.New("checkpoints require a BFT chain")
+	}
+	cp := pm.checkpoint
+	verify := func(header *types.Header) error {
+		return engine.VerifyCheckpoint(pm.chainconfig, cp, header)
+	}
+	ancestors, block, err := pm.downloader.SyncCheckpoint(peer.id, cp.Hash, verify)
+	if err != nil {
+		return err
+	}
+	td := bft.CheckpointTD(pm.blockchain.Genesis(), block.NumberU64())
+	if err := pm.blockchain.InsertCheckpoint(ancestors, block, td); err != nil {
+		return err
+	}
+	return engine.ImportCheckpoint(cp)
+}
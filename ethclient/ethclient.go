@@ -290,6 +290,87 @@ func (ec *Client) NonceAt(ctx context.Context, account common.Address, blockNumb
 	return uint64(result), err
 }
 
+// AccountResult is the Merkle proof of an account and of some of its storage
+// slots. The account proof is keyed by the hash of the address and verifies
+// against the state root, the storage proofs are keyed by the hashes of the
+// storage keys and verify against the storage root of the account.
+type AccountResult struct {
+	Address      common.Address
+	AccountProof [][]byte
+	Balance      *big.Int
+	CodeHash     common.Hash
+	Nonce        uint64
+	StorageHash  common.Hash
+	StorageProof []StorageResult
+}
+
+// StorageResult is the Merkle proof of a single storage slot.
+type StorageResult struct {
+	Key   common.Hash
+	Value *big.Int
+	Proof [][]byte
+}
+
+type rpcAccountResult struct {
+	Address      common.Address     `json:"address"`
+	AccountProof []hexutil.Bytes    `json:"accountProof"`
+	Balance      *hexutil.Big       `json:"balance"`
+	CodeHash     common.Hash        `json:"codeHash"`
+	Nonce        hexutil.Uint64     `json:"nonce"`
+	StorageHash  common.Hash        `json:"storageHash"`
+	StorageProof []rpcStorageResult `json:"storageProof"`
+}
+
+type rpcStorageResult struct {
+	Key   common.Hash     `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// ProofAt returns the Merkle proofs of the given account and of the given keys
+// in its contract storage. The block number can be nil, in which case the
+// proofs are taken from the latest known block.
+func (ec *Client) ProofAt(ctx context.Context, account common.Address, keys []common.Hash, blockNumber *big.Int) (*AccountResult, error) {
+	if keys == nil {
+		keys = []common.Hash{}
+	}
+	var res rpcAccountResult
+	if err := ec.c.CallContext(ctx, &res, "eth_getProof", account, keys, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	if res.Balance == nil {
+		return nil, fmt.Errorf("incomplete proof of account %x", account)
+	}
+	result := &AccountResult{
+		Address:      res.Address,
+		AccountProof: fromHexSlice(res.AccountProof),
+		Balance:      (*big.Int)(res.Balance),
+		CodeHash:     res.CodeHash,
+		Nonce:        uint64(res.Nonce),
+		StorageHash:  res.StorageHash,
+		StorageProof: make([]StorageResult, len(res.StorageProof)),
+	}
+	for i, proof := range res.StorageProof {
+		if proof.Value == nil {
+			return nil, fmt.Errorf("incomplete proof of storage key %x", proof.Key)
+		}
+		result.StorageProof[i] = StorageResult{
+			Key:   proof.Key,
+			Value: (*big.Int)(proof.Value),
+			Proof: fromHexSlice(proof.Proof),
+		}
+	}
+	return result, nil
+}
+
+func fromHexSlice(nodes []hexutil.Bytes) [][]byte {
+	proof := make([][]byte, len(nodes))
+	for i, node := range nodes {
+		proof[i] = node
+	}
+	return proof
+}
+
 // Filters
 
 // FilterLogs executes a filter query.
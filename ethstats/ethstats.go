@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth"
@@ -41,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 	"golang.org/x/net/websocket"
 )
@@ -54,10 +56,11 @@ const historyUpdateRange = 50
 type Service struct {
 	stack *node.Node // Temporary workaround, remove when API finalized
 
-	server *p2p.Server        // Peer-to-peer server to retrieve networking infos
-	eth    *eth.Ethereum      // Full Ethereum service if monitoring a full node
-	les    *les.LightEthereum // Light Ethereum service if monitoring a light node
-	engine consensus.Engine   // Consensus engine to retrieve variadic block fields
+	server *p2p.Server         // Peer-to-peer server to retrieve networking infos
+	eth    *eth.Ethereum       // Full Ethereum service if monitoring a full node
+	les    *les.LightEthereum  // Light Ethereum service if monitoring a light node
+	engine consensus.Engine    // Consensus engine to retrieve variadic block fields
+	config *params.ChainConfig // Chain config to tell the blocks sealed by BFT apart
 
 	node string // Name of the node to display on the monitoring page
 	pass string // Password to authorize access to the monitoring page
@@ -76,16 +79,20 @@ func New(url string, ethServ *eth.Ethereum, lesServ *les.LightEthereum) (*Servic
 		return nil, fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host:port", url)
 	}
 	// Assemble and return the stats service
-	var engine consensus.Engine
+	var (
+		engine consensus.Engine
+		config *params.ChainConfig
+	)
 	if ethServ != nil {
-		engine = ethServ.Engine()
+		engine, config = ethServ.Engine(), ethServ.BlockChain().Config()
 	} else {
-		engine = lesServ.Engine()
+		engine, config = lesServ.Engine(), lesServ.ApiBackend.ChainConfig()
 	}
 	return &Service{
 		eth:    ethServ,
 		les:    lesServ,
 		engine: engine,
+		config: config,
 		node:   parts[1],
 		pass:   parts[3],
 		host:   parts[4],
@@ -241,6 +248,12 @@ func (s *Service) loop() {
 				if err = s.reportPending(conn); err != nil {
 					log.Warn("Post-block transaction stats report failed", "err", err)
 				}
+				// BFT consensus state changes with every block, push it eagerly
+				if _, ok := bft.FromEngine(s.engine); ok {
+					if err = s.reportStats(conn); err != nil {
+						log.Warn("Post-block consensus stats report failed", "err", err)
+					}
+				}
 			case <-txCh:
 				if err = s.reportPending(conn); err != nil {
 					log.Warn("Transaction stats report failed", "err", err)
@@ -460,6 +473,15 @@ type blockStats struct {
 	TxHash     common.Hash    `json:"transactionsRoot"`
 	Root       common.Hash    `json:"stateRoot"`
 	Uncles     uncleStats     `json:"uncles"`
+	Commit     *commitStats   `json:"commit,omitempty"`
+}
+
+// commitStats is the information to report about the commit certificate of a
+// block sealed by BFT consensus, whose difficulty carries no information.
+type commitStats struct {
+	Round    uint64 `json:"round"`
+	Votes    int    `json:"votes"`    // Precommit votes in the certificate
+	Eligible uint64 `json:"eligible"` // Number of validators eligible to vote
 }
 
 // txStats is the information to report about individual transactions.
@@ -529,6 +551,20 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		td = s.les.BlockChain().GetTd(header.Hash(), header.Number.Uint64())
 		txs = []txStats{}
 	}
+	// BFT blocks have a fixed difficulty, report their commit instead
+	diff, totalDiff := header.Difficulty.String(), td.String()
+
+	var commit *commitStats
+	if engine, ok := bft.FromEngine(s.engine); ok && s.config.IsBFT(header.Number) {
+		diff, totalDiff = "0", "0"
+		if pls := engine.Certificate(header.Hash()); pls != nil && len(pls.PrecommitVotes) > 0 {
+			commit = &commitStats{
+				Round:    pls.Round(),
+				Votes:    len(pls.PrecommitVotes),
+				Eligible: pls.EligibleVotesNum,
+			}
+		}
+	}
 	// Assemble and return the block stats
 	author, _ := s.engine.Author(header)
 
@@ -540,12 +576,13 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 		Miner:      author,
 		GasUsed:    new(big.Int).Set(header.GasUsed),
 		GasLimit:   new(big.Int).Set(header.GasLimit),
-		Diff:       header.Difficulty.String(),
-		TotalDiff:  td.String(),
+		Diff:       diff,
+		TotalDiff:  totalDiff,
 		Txs:        txs,
 		TxHash:     header.TxHash,
 		Root:       header.Root,
 		Uncles:     uncles,
+		Commit:     commit,
 	}
 }
 
@@ -641,13 +678,14 @@ func (s *Service) reportPending(conn *websocket.Conn) error {
 
 // nodeStats is the information to report about the local node.
 type nodeStats struct {
-	Active   bool `json:"active"`
-	Syncing  bool `json:"syncing"`
-	Mining   bool `json:"mining"`
-	Hashrate int  `json:"hashrate"`
-	Peers    int  `json:"peers"`
-	GasPrice int  `json:"gasPrice"`
-	Uptime   int  `json:"uptime"`
+	Active   bool        `json:"active"`
+	Syncing  bool        `json:"syncing"`
+	Mining   bool        `json:"mining"`
+	Hashrate int         `json:"hashrate"`
+	Peers    int         `json:"peers"`
+	GasPrice int         `json:"gasPrice"`
+	Uptime   int         `json:"uptime"`
+	BFT      *bft.Status `json:"bft,omitempty"` // Consensus state if running a BFT engine
 }
 
 // reportPending retrieves various stats about the node at the networking and
@@ -659,11 +697,20 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 		hashrate int
 		syncing  bool
 		gasprice int
+		status   *bft.Status
 	)
 	if s.eth != nil {
 		mining = s.eth.Miner().Mining()
 		hashrate = int(s.eth.Miner().HashRate())
 
+		// BFT nodes don't hash, they seal only if they are part of the validator set
+		if engine, ok := bft.FromEngine(s.engine); ok {
+			if status = engine.Status(); status != nil {
+				mining = mining && status.Validator
+				hashrate = 0
+			}
+		}
+
 		sync := s.eth.Downloader().Progress()
 		syncing = s.eth.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
 
@@ -686,6 +733,7 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 			GasPrice: gasprice,
 			Syncing:  syncing,
 			Uptime:   100,
+			BFT:      status,
 		},
 	}
 	report := map[string][]interface{}{
@@ -51,6 +51,8 @@ const (
 	emptyHex        = "0x"
 )
 
+var errNoPrivateManager = errors.New("private transactions not supported")
+
 // PublicEthereumAPI provides an API to access Ethereum related information.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicEthereumAPI struct {
@@ -118,7 +120,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, s.b.ChainConfig().ChainId)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -126,7 +128,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, s.b.ChainConfig().ChainId)
 		}
 		content["queued"][account.Hex()] = dump
 	}
@@ -336,14 +338,15 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return common.Hash{}, err
 	}
-	// Assemble the transaction and sign with the wallet
-	tx := args.toTransaction()
-
-	var chainID *big.Int
-	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
-		chainID = config.ChainId
+	if err := args.setPrivate(s.b); err != nil {
+		return common.Hash{}, err
 	}
-	signed, err := wallet.SignTxWithPassphrase(account, passwd, tx, chainID)
+	// Assemble the transaction and sign with the wallet
+	signed, err := args.signTx(s.b, func(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+		return wallet.SignTxWithPassphrase(account, passwd, tx, chainID)
+	}, func(hash []byte) ([]byte, error) {
+		return wallet.SignHashWithPassphrase(account, passwd, hash)
+	})
 	if err != nil {
 		return common.Hash{}, err
 	}
@@ -550,6 +553,76 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the Merkle proof of an account and of some of its storage
+// slots, as returned by GetProof.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the Merkle proof of a storage slot against the storage root
+// of its account.
+type StorageResult struct {
+	Key   common.Hash     `json:"key"`
+	Value *hexutil.Big    `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// GetProof returns the Merkle proofs of the account at the given address and
+// of the given storage slots, against the state root of the given block. The
+// rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block numbers are also
+// allowed.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	storageHash := types.EmptyRootHash
+	if storageTrie := state.StorageTrie(address); storageTrie != nil {
+		storageHash = storageTrie.Hash()
+	}
+	storageProof := make([]StorageResult, len(storageKeys))
+	for i, key := range storageKeys {
+		hash := common.HexToHash(key)
+		proof, err := state.GetStorageProof(address, hash)
+		if err != nil {
+			return nil, err
+		}
+		storageProof[i] = StorageResult{
+			Key:   hash,
+			Value: (*hexutil.Big)(state.GetState(address, hash).Big()),
+			Proof: toHexSlice(proof),
+		}
+	}
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		CodeHash:     state.GetCodeHash(address),
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+// toHexSlice converts the nodes of a Merkle proof for JSON encoding.
+func toHexSlice(proof []rlp.RawValue) []hexutil.Bytes {
+	nodes := make([]hexutil.Bytes, len(proof))
+	for i, node := range proof {
+		nodes[i] = hexutil.Bytes(node)
+	}
+	return nodes
+}
+
 // callmsg is the message type used for call transitions.
 type callmsg struct {
 	addr          common.Address
@@ -601,7 +674,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if gas.Sign() == 0 {
 		gas = big.NewInt(50000000)
 	}
-	if gasPrice.Sign() == 0 {
+	if gasPrice.Sign() == 0 && !s.b.ChainConfig().IsZeroGasPrice(header.Number) {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
 
@@ -769,7 +842,7 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(b *types.Block, inclTx bool, fullTx
 
 		if fullTx {
 			formatTx = func(tx *types.Transaction) (interface{}, error) {
-				return newRPCTransaction(b, tx.Hash())
+				return newRPCTransaction(b, tx.Hash(), s.b.ChainConfig().ChainId)
 			}
 		}
 
@@ -812,13 +885,23 @@ type RPCTransaction struct {
 	S                *hexutil.Big    `json:"s"`
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
-	var signer types.Signer = types.FrontierSigner{}
-	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
+// rpcSigner returns the signer recovering the sender of the given transaction.
+// Private transactions do not carry the chain id in their signature, so it is
+// taken from the configuration of the chain.
+func rpcSigner(tx *types.Transaction, chainId *big.Int) types.Signer {
+	switch {
+	case tx.IsPrivate():
+		return types.NewPrivateSigner(chainId)
+	case tx.Protected():
+		return types.NewEIP155Signer(tx.ChainId())
+	default:
+		return types.FrontierSigner{}
 	}
-	from, _ := types.Sender(signer, tx)
+}
+
+// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func newRPCPendingTransaction(tx *types.Transaction, chainId *big.Int) *RPCTransaction {
+	from, _ := types.Sender(rpcSigner(tx, chainId), tx)
 	v, r, s := tx.RawSignatureValues()
 	return &RPCTransaction{
 		From:     from,
@@ -836,14 +919,10 @@ func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC representation.
-func newRPCTransactionFromBlockIndex(b *types.Block, txIndex uint) (*RPCTransaction, error) {
+func newRPCTransactionFromBlockIndex(b *types.Block, txIndex uint, chainId *big.Int) (*RPCTransaction, error) {
 	if txIndex < uint(len(b.Transactions())) {
 		tx := b.Transactions()[txIndex]
-		var signer types.Signer = types.FrontierSigner{}
-		if tx.Protected() {
-			signer = types.NewEIP155Signer(tx.ChainId())
-		}
-		from, _ := types.Sender(signer, tx)
+		from, _ := types.Sender(rpcSigner(tx, chainId), tx)
 		v, r, s := tx.RawSignatureValues()
 		return &RPCTransaction{
 			BlockHash:        b.Hash(),
@@ -877,10 +956,10 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, txIndex uint) (hexutil.B
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC representation.
-func newRPCTransaction(b *types.Block, txHash common.Hash) (*RPCTransaction, error) {
+func newRPCTransaction(b *types.Block, txHash common.Hash, chainId *big.Int) (*RPCTransaction, error) {
 	for idx, tx := range b.Transactions() {
 		if tx.Hash() == txHash {
-			return newRPCTransactionFromBlockIndex(b, uint(idx))
+			return newRPCTransactionFromBlockIndex(b, uint(idx), chainId)
 		}
 	}
 
@@ -937,7 +1016,7 @@ func (s *PublicTransactionPoolAPI) GetBlockTransactionCountByHash(ctx context.Co
 // GetTransactionByBlockNumberAndIndex returns the transaction for the given block number and index.
 func (s *PublicTransactionPoolAPI) GetTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (*RPCTransaction, error) {
 	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
-		return newRPCTransactionFromBlockIndex(block, uint(index))
+		return newRPCTransactionFromBlockIndex(block, uint(index), s.b.ChainConfig().ChainId)
 	}
 	return nil, nil
 }
@@ -945,7 +1024,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionByBlockNumberAndIndex(ctx conte
 // GetTransactionByBlockHashAndIndex returns the transaction for the given block hash and index.
 func (s *PublicTransactionPoolAPI) GetTransactionByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) (*RPCTransaction, error) {
 	if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
-		return newRPCTransactionFromBlockIndex(block, uint(index))
+		return newRPCTransactionFromBlockIndex(block, uint(index), s.b.ChainConfig().ChainId)
 	}
 	return nil, nil
 }
@@ -1011,7 +1090,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 		return nil, nil
 	}
 	if isPending {
-		return newRPCPendingTransaction(tx), nil
+		return newRPCPendingTransaction(tx, s.b.ChainConfig().ChainId), nil
 	}
 
 	blockHash, _, _, err := getTransactionBlockData(s.b.ChainDb(), hash)
@@ -1021,7 +1100,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	}
 
 	if block, _ := s.b.GetBlock(ctx, blockHash); block != nil {
-		return newRPCTransaction(block, hash)
+		return newRPCTransaction(block, hash, s.b.ChainConfig().ChainId)
 	}
 	return nil, nil
 }
@@ -1054,6 +1133,12 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 		log.Debug("Failed to retrieve transaction", "hash", hash, "err", err)
 		return nil, nil
 	}
+	// Participants of private transactions get the receipt of the private execution
+	if tx.IsPrivate() {
+		if private := core.GetPrivateReceipt(s.b.ChainDb(), hash); private != nil {
+			receipt = private
+		}
+	}
 
 	txBlock, blockIndex, index, err := getTransactionBlockData(s.b.ChainDb(), hash)
 	if err != nil {
@@ -1061,11 +1146,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 		return nil, nil
 	}
 
-	var signer types.Signer = types.FrontierSigner{}
-	if tx.Protected() {
-		signer = types.NewEIP155Signer(tx.ChainId())
-	}
-	from, _ := types.Sender(signer, tx)
+	from, _ := types.Sender(rpcSigner(tx, s.b.ChainConfig().ChainId), tx)
 
 	fields := map[string]interface{}{
 		"root":              hexutil.Bytes(receipt.PostState),
@@ -1117,6 +1198,11 @@ type SendTxArgs struct {
 	Value    *hexutil.Big    `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
 	Nonce    *hexutil.Uint64 `json:"nonce"`
+
+	// Participants of a private transaction, identified to the transaction
+	// manager. The transaction is public if PrivateFor is empty.
+	PrivateFrom string   `json:"privateFrom"`
+	PrivateFor  []string `json:"privateFor"`
 }
 
 // prepareSendTxArgs is a helper function that fills in default values for unspecified tx fields.
@@ -1144,6 +1230,56 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	return nil
 }
 
+// setPrivate hands the data of a private transaction to the transaction manager,
+// replacing it with the key of the stored payload.
+func (args *SendTxArgs) setPrivate(b Backend) error {
+	if !args.isPrivate() {
+		return nil
+	}
+	if !b.ChainConfig().IsPrivate(new(big.Int).Add(b.CurrentBlock().Number(), big.NewInt(1))) {
+		return core.ErrPrivateDisabled
+	}
+	manager := b.PrivateManager()
+	if manager == nil {
+		return errNoPrivateManager
+	}
+	if args.Value.ToInt().Sign() != 0 {
+		return core.ErrPrivateValue
+	}
+	key, err := manager.Send(args.Data, args.PrivateFrom, args.PrivateFor)
+	if err != nil {
+		return err
+	}
+	args.Data = key
+	return nil
+}
+
+func (args *SendTxArgs) isPrivate() bool {
+	return len(args.PrivateFor) > 0
+}
+
+// signTx signs the transaction assembled from the arguments with the given
+// signing functions. Public transactions are signed as transactions, replay
+// protected once EIP155 is active, while private ones have the hash of the
+// private signer signed, which covers their privacy.
+func (args *SendTxArgs) signTx(b Backend, signTx func(*types.Transaction, *big.Int) (*types.Transaction, error), signHash func([]byte) ([]byte, error)) (*types.Transaction, error) {
+	if args.isPrivate() {
+		signer := types.NewPrivateSigner(b.ChainConfig().ChainId)
+		tx := args.toTransaction()
+		hash := signer.Hash(tx)
+		sig, err := signHash(hash[:])
+		if err != nil {
+			return nil, err
+		}
+		return tx.WithSignature(signer, sig)
+	}
+	var chainID *big.Int
+	if config := b.ChainConfig(); config.IsEIP155(b.CurrentBlock().Number()) {
+		chainID = config.ChainId
+	}
+	return signTx(args.toTransaction(), chainID)
+}
+
 func (args *SendTxArgs) toTransaction() *types.Transaction {
 	if args.To == nil {
 		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), (*big.Int)(args.Gas), (*big.Int)(args.GasPrice), args.Data)
@@ -1190,14 +1326,15 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return common.Hash{}, err
 	}
-	// Assemble the transaction and sign with the wallet
-	tx := args.toTransaction()
-
-	var chainID *big.Int
-	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) {
-		chainID = config.ChainId
+	if err := args.setPrivate(s.b); err != nil {
+		return common.Hash{}, err
 	}
-	signed, err := wallet.SignTx(account, tx, chainID)
+	// Assemble the transaction and sign with the wallet
+	signed, err := args.signTx(s.b, func(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+		return wallet.SignTx(account, tx, chainID)
+	}, func(hash []byte) ([]byte, error) {
+		return wallet.SignHash(account, hash)
+	})
 	if err != nil {
 		return common.Hash{}, err
 	}
@@ -1302,7 +1439,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err == nil {
-			transactions = append(transactions, newRPCPendingTransaction(tx))
+			transactions = append(transactions, newRPCPendingTransaction(tx, s.b.ChainConfig().ChainId))
 		}
 	}
 	return transactions, nil
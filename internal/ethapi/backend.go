@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/private"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -65,6 +66,7 @@ type Backend interface {
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
+	PrivateManager() private.TransactionManager
 }
 
 func GetAPIs(apiBackend Backend) []rpc.API {
@@ -109,6 +111,11 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
 			Public:    false,
+		}, {
+			Namespace: "permission",
+			Version:   "1.0",
+			Service:   NewPrivatePermissionAPI(apiBackend, nonceLock),
+			Public:    false,
 		},
 	}
 }
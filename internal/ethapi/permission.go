@@ -0,0 +1,100 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errNoPermissions = errors.New("chain has no permission config")
+	errNoRegistry    = errors.New("permissions are fixed by the chain config")
+)
+
+// PermissionFlags are the permissions of an account.
+type PermissionFlags struct {
+	Send   bool `json:"send"`   // Send transactions
+	Create bool `json:"create"` // Deploy contracts
+	Admin  bool `json:"admin"`  // Update the permission registry
+	Node   bool `json:"node"`   // Connect to the network, for the address of a node key
+}
+
+// PrivatePermissionAPI provides an API to inspect and manage the accounts
+// permitted to transact on a permissioned chain.
+type PrivatePermissionAPI struct {
+	b   Backend
+	txs *PublicTransactionPoolAPI
+}
+
+// NewPrivatePermissionAPI creates a new permission API.
+func NewPrivatePermissionAPI(b Backend, nonceLock *AddrLocker) *PrivatePermissionAPI {
+	return &PrivatePermissionAPI{b: b, txs: NewPublicTransactionPoolAPI(b, nonceLock)}
+}
+
+// GetPermissions returns the permissions of an account at the given block.
+func (s *PrivatePermissionAPI) GetPermissions(ctx context.Context, account common.Address, blockNr rpc.BlockNumber) (*PermissionFlags, error) {
+	config := s.b.ChainConfig().Permissions
+	if config == nil {
+		return nil, errNoPermissions
+	}
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	flags := core.Permissions(config, state, account)
+	return &PermissionFlags{
+		Send:   flags&core.PermissionSend != 0,
+		Create: flags&core.PermissionCreate != 0,
+		Admin:  flags&core.PermissionAdmin != 0,
+		Node:   flags&core.PermissionNode != 0,
+	}, state.Error()
+}
+
+// SetPermissions sends a transaction from admin to the permission registry,
+// replacing the permissions of account. The admin account must be unlocked.
+func (s *PrivatePermissionAPI) SetPermissions(ctx context.Context, admin common.Address, account common.Address, perms PermissionFlags) (common.Hash, error) {
+	config := s.b.ChainConfig().Permissions
+	if config == nil {
+		return common.Hash{}, errNoPermissions
+	}
+	if config.Registry == nil {
+		return common.Hash{}, errNoRegistry
+	}
+	var flags uint64
+	if perms.Send {
+		flags |= core.PermissionSend
+	}
+	if perms.Create {
+		flags |= core.PermissionCreate
+	}
+	if perms.Admin {
+		flags |= core.PermissionAdmin
+	}
+	if perms.Node {
+		flags |= core.PermissionNode
+	}
+	return s.txs.SendTransaction(ctx, SendTxArgs{
+		From: admin,
+		To:   config.Registry,
+		Data: core.SetPermissionsData(account, flags),
+	})
+}
@@ -25,6 +25,7 @@ var Modules = map[string]string{
 	"eth":        Eth_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
+	"permission": Permission_JS,
 	"personal":   Personal_JS,
 	"rpc":        RPC_JS,
 	"shh":        Shh_JS,
@@ -127,6 +128,21 @@ web3._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'allowNode',
+			call: 'admin_allowNode',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'disallowNode',
+			call: 'admin_disallowNode',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'reloadAllowedNodes',
+			call: 'admin_reloadAllowedNodes',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -174,6 +190,10 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'allowedNodes',
+			getter: 'admin_allowedNodes'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
@@ -360,6 +380,12 @@ web3._extend({
 	property: 'eth',
 	methods:
 	[
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'eth_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'sign',
 			call: 'eth_sign',
@@ -669,6 +695,27 @@ web3._extend({
 });
 `
 
+const Permission_JS = `
+web3._extend({
+	property: 'permission',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'getPermissions',
+			call: 'permission_getPermissions',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'setPermissions',
+			call: 'permission_setPermissions',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, null]
+		})
+	]
+});
+`
+
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
@@ -890,7 +890,7 @@ func (d *downloaderPeerNotify) registerPeer(p *peer) {
 		return nil
 	}
 
-	pm.downloader.RegisterPeer(p.id, ethVersion, p.HeadAndTd, requestHeadersByHash, requestHeadersByNumber, nil, nil, nil)
+	pm.downloader.RegisterPeer(p.id, ethVersion, p.HeadAndTd, requestHeadersByHash, requestHeadersByNumber, nil, nil, nil, nil)
 }
 
 func (d *downloaderPeerNotify) unregisterPeer(p *peer) {
@@ -24,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -131,6 +132,16 @@ func (t *odrTrie) NodeIterator(startkey []byte) trie.NodeIterator {
 	return newNodeIterator(t, startkey)
 }
 
+func (t *odrTrie) TryProve(key []byte) ([]rlp.RawValue, error) {
+	key = crypto.Keccak256(key)
+	var proof []rlp.RawValue
+	err := t.do(key, func() (err error) {
+		proof, err = t.trie.TryProve(key)
+		return err
+	})
+	return proof, err
+}
+
 func (t *odrTrie) GetKey(sha []byte) []byte {
 	return nil
 }
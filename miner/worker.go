@@ -115,8 +115,9 @@ type worker struct {
 	unconfirmed *unconfirmedBlocks // set of locally mined blocks pending canonicalness confirmations
 
 	// atomic status counters
-	mining int32
-	atWork int32
+	mining  int32
+	atWork  int32
+	waiting int32 // Whether a commit is scheduled for when the seal policy changes
 
 	fullValidation bool
 }
@@ -246,7 +247,7 @@ func (self *worker) update() {
 
 				acc, _ := types.Sender(self.current.signer, ev.Tx)
 				txs := map[common.Address]types.Transactions{acc: {ev.Tx}}
-				txset := types.NewTransactionsByPriceAndNonce(txs)
+				txset := types.NewTransactionsByPriceAndNonce(self.current.signer, txs)
 
 				self.current.commitTransactions(self.mux, txset, self.chain, self.coinbase)
 				self.currentMu.Unlock()
@@ -274,8 +275,7 @@ func (self *worker) wait() {
 				}
 				go self.mux.Post(core.NewMinedBlockEvent{Block: block})
 			} else {
-				work.state.CommitTo(self.chainDb, self.config.IsEIP158(block.Number()))
-				stat, err := self.chain.WriteBlock(block)
+				stat, err := self.chain.WriteBlockWithState(block, work.state)
 				if err != nil {
 					log.Error("Failed writing block to chain", "err", err)
 					continue
@@ -444,11 +444,11 @@ func (self *worker) commitNewWork() {
 		return
 	}
 
-	txs := types.NewTransactionsByPriceAndNonce(pending)
+	txs := types.NewTransactionsByPriceAndNonce(work.signer, pending)
 
 	work.commitTransactions(self.mux, txs, self.chain, self.coinbase)
 
-	if atomic.LoadInt32(&self.mining) == 1 && work.tcount == 0 {
+	if _, ok := self.engine.(consensus.SealPolicy); !ok && atomic.LoadInt32(&self.mining) == 1 && work.tcount == 0 {
 		time.Sleep(3 * time.Second)
 		go self.commitNewWork()
 		return
@@ -483,6 +483,19 @@ func (self *worker) commitNewWork() {
 		log.Error("Failed to finalize block for sealing", "err", err)
 		return
 	}
+	// Hold the work back until the consensus engine deems the block worth sealing
+	if policy, ok := self.engine.(consensus.SealPolicy); ok && atomic.LoadInt32(&self.mining) == 1 {
+		if ready, changed := policy.Ready(self.chain, work.Block); !ready {
+			if atomic.CompareAndSwapInt32(&self.waiting, 0, 1) {
+				go func() {
+					<-changed
+					atomic.StoreInt32(&self.waiting, 0)
+					self.commitNewWork()
+				}()
+			}
+			return
+		}
+	}
 	// We only care about logging if we're actually mining.
 	if atomic.LoadInt32(&self.mining) == 1 {
 		log.Info("Commit new mining work", "number", work.Block.Number(), "txs", work.tcount, "uncles", len(uncles), "elapsed", common.PrettyDuration(time.Since(tstart)))
@@ -73,6 +73,79 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// allowlist returns the node allowlist of the running server.
+func (api *PrivateAdminAPI) allowlist() (*p2p.FileNodeFilter, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	filter, ok := server.GetNodeFilter().(*p2p.FileNodeFilter)
+	if !ok {
+		return nil, errNoAllowlist
+	}
+	return filter, nil
+}
+
+// AllowNode adds a node to the allowlist of the nodes permitted to connect.
+func (api *PrivateAdminAPI) AllowNode(url string) (bool, error) {
+	filter, err := api.allowlist()
+	if err != nil {
+		return false, err
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	if err := filter.Allow(node.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DisallowNode removes a node from the allowlist of the nodes permitted to
+// connect, disconnecting it if connected.
+func (api *PrivateAdminAPI) DisallowNode(url string) (bool, error) {
+	filter, err := api.allowlist()
+	if err != nil {
+		return false, err
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	if err := filter.Disallow(node.ID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AllowedNodes returns the enode URLs in the allowlist of the nodes permitted
+// to connect.
+func (api *PrivateAdminAPI) AllowedNodes() ([]string, error) {
+	filter, err := api.allowlist()
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, id := range filter.Nodes() {
+		urls = append(urls, fmt.Sprintf("enode://%x", id[:]))
+	}
+	return urls, nil
+}
+
+// ReloadAllowedNodes reads the allowlist of the nodes permitted to connect from
+// its file again, disconnecting the nodes removed from it.
+func (api *PrivateAdminAPI) ReloadAllowedNodes() (bool, error) {
+	filter, err := api.allowlist()
+	if err != nil {
+		return false, err
+	}
+	if err := filter.Reload(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // StartRPC starts the HTTP RPC API server.
 func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis *string) (bool, error) {
 	api.node.lock.Lock()
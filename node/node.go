@@ -43,6 +43,8 @@ var (
 	ErrNodeRunning    = errors.New("node already running")
 	ErrServiceUnknown = errors.New("unknown service")
 
+	errNoAllowlist = errors.New("connections not restricted by a node allowlist")
+
 	datadirInUseErrnos = map[uint]bool{11: true, 32: true, 35: true}
 )
 
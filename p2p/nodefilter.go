@@ -0,0 +1,185 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+)
+
+// NodeFilter decides which remote nodes are allowed to connect. The server
+// checks it during the handshakes and disconnects the peers that are no longer
+// allowed whenever the filter announces a change.
+type NodeFilter interface {
+	// Allowed returns whether the node of the given ID may connect.
+	Allowed(id discover.NodeID) bool
+
+	// SubscribeChanges notifies ch whenever nodes may have been disallowed.
+	SubscribeChanges(ch chan<- struct{}) event.Subscription
+}
+
+// FileNodeFilter is a NodeFilter allowing the nodes listed in a JSON file of
+// enode URLs, in the format of static-nodes.json. Changes made through Allow
+// and Disallow are written back to the file.
+type FileNodeFilter struct {
+	path  string
+	nodes map[discover.NodeID]bool
+	feed  event.Feed
+	lock  sync.RWMutex
+}
+
+// NewFileNodeFilter creates a node filter backed by the given file. A missing
+// file is created on the first change, allowing no node until then.
+func NewFileNodeFilter(path string) (*FileNodeFilter, error) {
+	f := &FileNodeFilter{path: path}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload reads the list of allowed nodes from the file again, notifying the
+// subscribers if any node was removed.
+func (f *FileNodeFilter) Reload() error {
+	nodes := make(map[discover.NodeID]bool)
+	if _, err := os.Stat(f.path); err == nil {
+		var urls []string
+		if err := common.LoadJSON(f.path, &urls); err != nil {
+			return err
+		}
+		for _, url := range urls {
+			node, err := discover.ParseNode(url)
+			if err != nil {
+				return fmt.Errorf("node URL %s: %v", url, err)
+			}
+			nodes[node.ID] = true
+		}
+	}
+	f.lock.Lock()
+	removed := false
+	for id := range f.nodes {
+		if !nodes[id] {
+			removed = true
+		}
+	}
+	f.nodes = nodes
+	f.lock.Unlock()
+
+	log.Info("Loaded allowed nodes", "path", f.path, "nodes", len(nodes))
+	if removed {
+		f.feed.Send(struct{}{})
+	}
+	return nil
+}
+
+// Allowed returns whether the node is listed in the file.
+func (f *FileNodeFilter) Allowed(id discover.NodeID) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.nodes[id]
+}
+
+// SubscribeChanges notifies ch whenever a node is disallowed.
+func (f *FileNodeFilter) SubscribeChanges(ch chan<- struct{}) event.Subscription {
+	return f.feed.Subscribe(ch)
+}
+
+// Nodes returns the IDs of the allowed nodes.
+func (f *FileNodeFilter) Nodes() []discover.NodeID {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	ids := make([]discover.NodeID, 0, len(f.nodes))
+	for id := range f.nodes {
+		ids = append(ids, id)
+	}
+	sort.Sort(nodeIDs(ids))
+	return ids
+}
+
+// Allow adds a node to the file.
+func (f *FileNodeFilter) Allow(id discover.NodeID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.nodes[id] {
+		return nil
+	}
+	f.nodes[id] = true
+	if err := f.save(); err != nil {
+		delete(f.nodes, id)
+		return err
+	}
+	log.Info("Allowed node", "id", id)
+	return nil
+}
+
+// Disallow removes a node from the file, notifying the subscribers so that
+// the node is disconnected.
+func (f *FileNodeFilter) Disallow(id discover.NodeID) error {
+	f.lock.Lock()
+	if !f.nodes[id] {
+		f.lock.Unlock()
+		return nil
+	}
+	delete(f.nodes, id)
+	if err := f.save(); err != nil {
+		f.nodes[id] = true
+		f.lock.Unlock()
+		return err
+	}
+	f.lock.Unlock()
+
+	log.Info("Disallowed node", "id", id)
+	f.feed.Send(struct{}{})
+	return nil
+}
+
+// save writes the allowed nodes to the file. The caller must hold the lock.
+func (f *FileNodeFilter) save() error {
+	ids := make([]discover.NodeID, 0, len(f.nodes))
+	for id := range f.nodes {
+		ids = append(ids, id)
+	}
+	sort.Sort(nodeIDs(ids))
+
+	urls := make([]string, len(ids))
+	for i, id := range ids {
+		urls[i] = fmt.Sprintf("enode://%x", id[:])
+	}
+	blob, err := json.MarshalIndent(urls, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.path, blob, 0644)
+}
+
+type nodeIDs []discover.NodeID
+
+func (ids nodeIDs) Len() int           { return len(ids) }
+func (ids nodeIDs) Less(i, j int) bool { return string(ids[i][:]) < string(ids[j][:]) }
+func (ids nodeIDs) Swap(i, j int)      { ids[i], ids[j] = ids[j], ids[i] }
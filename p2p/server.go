@@ -27,6 +27,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/ethereum/go-ethereum/p2p/discv5"
@@ -106,6 +107,10 @@ type Config struct {
 	// IP networks contained in the list are considered.
 	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
+	// NodeFilter restricts the nodes allowed to connect by their node ID. If
+	// nil, any node is allowed. It can be replaced with SetNodeFilter.
+	NodeFilter NodeFilter `toml:"-"`
+
 	// NodeDatabase is the path to the database containing the previously seen
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
@@ -149,6 +154,9 @@ type Server struct {
 	lock    sync.Mutex // protects running
 	running bool
 
+	nodeFilter NodeFilter
+	filterLock sync.RWMutex // protects nodeFilter
+
 	ntab         discoverTable
 	listener     net.Listener
 	ourHandshake *protoHandshake
@@ -162,6 +170,7 @@ type Server struct {
 	quit          chan struct{}
 	addstatic     chan *discover.Node
 	removestatic  chan *discover.Node
+	setfilter     chan NodeFilter
 	posthandshake chan *conn
 	addpeer       chan *conn
 	delpeer       chan peerDrop
@@ -291,6 +300,29 @@ func (srv *Server) RemovePeer(node *discover.Node) {
 	}
 }
 
+// SetNodeFilter replaces the filter of the nodes allowed to connect, and
+// disconnects the peers it does not allow. A nil filter allows any node.
+func (srv *Server) SetNodeFilter(filter NodeFilter) {
+	select {
+	case srv.setfilter <- filter:
+	case <-srv.quit:
+	}
+}
+
+// GetNodeFilter returns the filter of the nodes allowed to connect.
+func (srv *Server) GetNodeFilter() NodeFilter {
+	srv.filterLock.RLock()
+	defer srv.filterLock.RUnlock()
+
+	return srv.nodeFilter
+}
+
+// nodeAllowed returns whether the node filter allows the given node.
+func (srv *Server) nodeAllowed(id discover.NodeID) bool {
+	filter := srv.GetNodeFilter()
+	return filter == nil || filter.Allowed(id)
+}
+
 // Self returns the local node's endpoint information.
 func (srv *Server) Self() *discover.Node {
 	srv.lock.Lock()
@@ -366,6 +398,8 @@ func (srv *Server) Start() (err error) {
 	srv.posthandshake = make(chan *conn)
 	srv.addstatic = make(chan *discover.Node)
 	srv.removestatic = make(chan *discover.Node)
+	srv.setfilter = make(chan NodeFilter)
+	srv.nodeFilter = srv.NodeFilter
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
@@ -456,7 +490,22 @@ func (srv *Server) run(dialstate dialer) {
 		taskdone     = make(chan task, maxActiveDialTasks)
 		runningTasks []task
 		queuedTasks  []task // tasks that can't run yet
+		filterCh     = make(chan struct{}, 1)
+		filterSub    event.Subscription
 	)
+	// Track the changes of the node filter to drop the peers no longer allowed
+	subscribeFilter := func(filter NodeFilter) {
+		if filterSub != nil {
+			filterSub.Unsubscribe()
+			filterSub = nil
+		}
+		if filter != nil {
+			filterSub = filter.SubscribeChanges(filterCh)
+		}
+	}
+	subscribeFilter(srv.GetNodeFilter())
+	defer subscribeFilter(nil)
+
 	// Put trusted nodes into a map to speed up checks.
 	// Trusted peers are loaded on startup and cannot be
 	// modified while the server is running.
@@ -517,6 +566,18 @@ running:
 			if p, ok := peers[n.ID]; ok {
 				p.Disconnect(DiscRequested)
 			}
+		case filter := <-srv.setfilter:
+			// This channel is used by SetNodeFilter to replace the node
+			// filter, the peers it does not allow are dropped right away.
+			srv.filterLock.Lock()
+			srv.nodeFilter = filter
+			srv.filterLock.Unlock()
+
+			subscribeFilter(filter)
+			srv.enforceNodeFilter(peers)
+		case <-filterCh:
+			// The node filter changed, drop the peers no longer allowed.
+			srv.enforceNodeFilter(peers)
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
@@ -602,11 +663,23 @@ func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn)
 		return DiscAlreadyConnected
 	case c.id == srv.Self().ID:
 		return DiscSelf
+	case !srv.nodeAllowed(c.id):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
 }
 
+// enforceNodeFilter disconnects the peers the node filter does not allow.
+func (srv *Server) enforceNodeFilter(peers map[discover.NodeID]*Peer) {
+	for id, p := range peers {
+		if !srv.nodeAllowed(id) {
+			p.log.Info("Disconnecting peer no longer allowed")
+			p.Disconnect(DiscUselessPeer)
+		}
+	}
+}
+
 type tempError interface {
 	Temporary() bool
 }
@@ -698,6 +771,12 @@ func (srv *Server) setupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 		clog.Trace("Dialed identity mismatch", "want", c, dialDest.ID)
 		return
 	}
+	// Reject nodes not allowed by the node filter before any further exchange
+	if !srv.nodeAllowed(c.id) {
+		clog.Debug("Rejected node not allowed")
+		c.close(DiscUselessPeer)
+		return
+	}
 	if err := srv.checkpoint(c, srv.posthandshake); err != nil {
 		clog.Trace("Rejected peer before protocol handshake", "err", err)
 		c.close(err)
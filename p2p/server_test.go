@@ -19,8 +19,11 @@ package p2p
 import (
 	"crypto/ecdsa"
 	"errors"
+	"io/ioutil"
 	"math/rand"
 	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -359,6 +362,66 @@ func TestServerAtCap(t *testing.T) {
 
 }
 
+func TestServerNodeFilter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nodefilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filter, err := NewFileNodeFilter(filepath.Join(dir, "allowed-nodes.json"))
+	if err != nil {
+		t.Fatalf("could not create filter: %v", err)
+	}
+	allowedID := randomID()
+	if err := filter.Allow(allowedID); err != nil {
+		t.Fatalf("could not allow node: %v", err)
+	}
+	srv := &Server{
+		Config: Config{
+			PrivateKey: newkey(),
+			MaxPeers:   10,
+			NoDial:     true,
+			NodeFilter: filter,
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	// Nodes not allowed are rejected right after the encryption handshake
+	tt := &setupTransport{id: randomID()}
+	srv.newTransport = func(fd net.Conn) transport { return tt }
+	p1, _ := net.Pipe()
+	srv.setupConn(p1, inboundConn, nil)
+	if tt.closeErr != DiscUselessPeer || tt.calls != "doEncHandshake,close," {
+		t.Errorf("node not allowed: close error %v, calls %q", tt.closeErr, tt.calls)
+	}
+	// Allowed nodes connect, and are dropped once disallowed
+	fd, _ := net.Pipe()
+	c := &conn{fd: fd, transport: newTestTransport(allowedID, fd), flags: inboundConn, id: allowedID, cont: make(chan error)}
+	if err := srv.checkpoint(c, srv.addpeer); err != nil {
+		t.Fatalf("allowed node rejected: %v", err)
+	}
+	if err := filter.Disallow(allowedID); err != nil {
+		t.Fatalf("could not disallow node: %v", err)
+	}
+	for start := time.Now(); srv.PeerCount() > 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatal("disallowed peer not disconnected")
+		}
+	}
+	// The changes persist in the file
+	reloaded, err := NewFileNodeFilter(filepath.Join(dir, "allowed-nodes.json"))
+	if err != nil {
+		t.Fatalf("could not reload filter: %v", err)
+	}
+	if reloaded.Allowed(allowedID) {
+		t.Error("disallowed node still allowed after reload")
+	}
+}
+
 func TestServerSetupConn(t *testing.T) {
 	id := randomID()
 	srvkey := newkey()
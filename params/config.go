@@ -80,8 +80,8 @@ var (
 	// means that all fields must be set at all times. This forces
 	// anyone adding flags to the config to also have to set these
 	// fields.
-	AllProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), new(EthashConfig), nil, false}
-	TestChainConfig    = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, false}
+	AllProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), new(EthashConfig), nil, nil, nil, nil, nil, big.NewInt(0), nil, nil}
+	TestChainConfig    = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, nil, nil, nil, nil, nil, nil}
 	TestRules          = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -109,8 +109,18 @@ type ChainConfig struct {
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
+	BFT    *BFTConfig    `json:"bft,omitempty"`
 
-	BFT bool // BFT config
+	BftBlock *big.Int `json:"bftBlock,omitempty"` // Block from which BFT replaces ethash or clique (nil = no switch)
+
+	Permissions     *PermissionConfig `json:"permissions,omitempty"`
+	PermissionBlock *big.Int          `json:"permissionBlock,omitempty"` // Block from which permissions are enforced (nil = from the genesis)
+
+	PrivateBlock *big.Int `json:"privateBlock,omitempty"` // Block from which private transactions are accepted (nil = never)
+
+	ZeroGasPriceBlock *big.Int `json:"zeroGasPriceBlock,omitempty"` // Block from which gas is metered but must be priced at zero, leaving balances untouched (nil = never)
+
+	Precompiles []*PrecompileConfig `json:"precompiles,omitempty"` // Native system contracts callable in addition to the precompiled ones
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -132,6 +142,81 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// BFTConfig is the consensus engine configs for byzantine fault tolerant sealing.
+type BFTConfig struct {
+	Validators         []common.Address `json:"validators"`                   // Initial set of validators allowed to seal
+	RoundTimeout       uint64           `json:"roundTimeout,omitempty"`       // Seconds to wait for a proposal in the first round
+	RoundTimeoutFactor float64          `json:"roundTimeoutFactor,omitempty"` // Multiplier applied to the timeouts with every new round
+	PrecommitTimeout   uint64           `json:"precommitTimeout,omitempty"`   // Seconds to wait for precommit votes in the first round
+	AllowEmpty         bool             `json:"allowEmpty,omitempty"`         // Whether to seal blocks without transactions
+	EmptyBlockPeriod   uint64           `json:"emptyBlockPeriod,omitempty"`   // Seconds without a block after which an empty one is sealed (0 = never)
+	MinBlockTxs        uint64           `json:"minBlockTxs,omitempty"`        // Minimum number of transactions before proposing a block
+	MinBlockGas        uint64           `json:"minBlockGas,omitempty"`        // Minimum gas used before proposing a block
+	MaxTxWait          uint64           `json:"maxTxWait,omitempty"`          // Seconds a transaction waits for the minimums before a partial block is sealed (0 = forever)
+	DomainBlock        *big.Int         `json:"domainBlock,omitempty"`        // First block whose consensus messages are signed for this chain only (nil = no fork)
+	RewardBlock        *big.Int         `json:"rewardBlock,omitempty"`        // First block paying rewards and distributing fees (nil = no fork)
+	BlockReward        *big.Int         `json:"blockReward,omitempty"`        // Wei credited to the proposer of every block
+	Treasury           *common.Address  `json:"treasury,omitempty"`           // Address receiving a share of the rewards and fees (nil = none)
+	TreasuryShare      uint64           `json:"treasuryShare,omitempty"`      // Percentage of the rewards and fees paid to the treasury
+	BlockPeriod        uint64           `json:"blockPeriod,omitempty"`        // Minimum seconds between the timestamps of consecutive blocks
+	MaxTimeSkew        uint64           `json:"maxTimeSkew,omitempty"`        // Seconds a block timestamp may be ahead of the local clock
+	MedianTimeBlock    *big.Int         `json:"medianTimeBlock,omitempty"`    // First block timestamped with the median time attested by the parent's signers (nil = no fork)
+}
+
+// String implements the stringer interface, returning the consensus engine details.
+func (c *BFTConfig) String() string {
+	return "bft"
+}
+
+// IsMedianTime returns whether num is past the block from which timestamps are
+// the median of the times attested in the commit certificate of the parent.
+func (c *BFTConfig) IsMedianTime(num *big.Int) bool {
+	return isForked(c.MedianTimeBlock, num)
+}
+
+// IsRewarding returns whether num is past the block from which rewards are paid
+// and transaction fees distributed among the validators.
+func (c *BFTConfig) IsRewarding(num *big.Int) bool {
+	return isForked(c.RewardBlock, num)
+}
+
+// sameRewards returns whether the rewards paid under both configs are the same.
+// The reward parameters can't be scheduled to change, so they are frozen once
+// the reward block is imported.
+func (c *BFTConfig) sameRewards(other *BFTConfig) bool {
+	if !configNumEqual(c.BlockReward, other.BlockReward) || c.TreasuryShare != other.TreasuryShare {
+		return false
+	}
+	if c.Treasury == nil || other.Treasury == nil {
+		return c.Treasury == other.Treasury
+	}
+	return *c.Treasury == *other.Treasury
+}
+
+// PermissionConfig restricts the accounts allowed to send transactions and to
+// deploy contracts. Without a registry the lists are fixed; with a registry they
+// seed it in the genesis and are managed on-chain by the admins afterwards.
+type PermissionConfig struct {
+	Senders  []common.Address `json:"senders"`            // Accounts allowed to send transactions
+	Creators []common.Address `json:"creators"`           // Accounts allowed to deploy contracts
+	Admins   []common.Address `json:"admins,omitempty"`   // Accounts allowed to update the registry
+	Nodes    []common.Address `json:"nodes,omitempty"`    // Addresses of the node keys allowed to connect, if nodes are filtered
+	Registry *common.Address  `json:"registry,omitempty"` // Contract holding the permissions (nil = fixed by the lists)
+}
+
+// PrecompileConfig activates one of the native system contracts of the EVM at an
+// address, callable like the precompiled contracts from the given block on.
+type PrecompileConfig struct {
+	Name    string         `json:"name"`            // Name of the native contract
+	Address common.Address `json:"address"`         // Address the contract is callable at
+	Block   *big.Int       `json:"block,omitempty"` // Block from which the contract is callable (nil = from the genesis)
+}
+
+// IsActive returns whether the native contract is callable in block num.
+func (c *PrecompileConfig) IsActive(num *big.Int) bool {
+	return c.Block == nil || isForked(c.Block, num)
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -140,6 +225,8 @@ func (c *ChainConfig) String() string {
 		engine = c.Ethash
 	case c.Clique != nil:
 		engine = c.Clique
+	case c.BFT != nil:
+		engine = c.BFT
 	default:
 		engine = "unknown"
 	}
@@ -182,6 +269,34 @@ func (c *ChainConfig) IsMetropolis(num *big.Int) bool {
 	return isForked(c.MetropolisBlock, num)
 }
 
+// IsBFT returns whether num is sealed by BFT, either because the chain runs BFT
+// from genesis or because num is past the switch from ethash or clique.
+func (c *ChainConfig) IsBFT(num *big.Int) bool {
+	if c.BFT == nil {
+		return false
+	}
+	return c.BftBlock == nil || isForked(c.BftBlock, num)
+}
+
+// IsPermissioned returns whether transactions of num are restricted to the
+// accounts permitted by the permission config.
+func (c *ChainConfig) IsPermissioned(num *big.Int) bool {
+	if c.Permissions == nil {
+		return false
+	}
+	return c.PermissionBlock == nil || isForked(c.PermissionBlock, num)
+}
+
+// IsPrivate returns whether num accepts private transactions.
+func (c *ChainConfig) IsPrivate(num *big.Int) bool {
+	return isForked(c.PrivateBlock, num)
+}
+
+// IsZeroGasPrice returns whether transactions of num must not pay for their gas.
+func (c *ChainConfig) IsZeroGasPrice(num *big.Int) bool {
+	return isForked(c.ZeroGasPriceBlock, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -242,9 +357,75 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.MetropolisBlock, newcfg.MetropolisBlock, head) {
 		return newCompatError("Metropolis fork block", c.MetropolisBlock, newcfg.MetropolisBlock)
 	}
+	if isForkIncompatible(c.BftBlock, newcfg.BftBlock, head) {
+		return newCompatError("BFT switch block", c.BftBlock, newcfg.BftBlock)
+	}
+	if isForkIncompatible(c.PermissionBlock, newcfg.PermissionBlock, head) {
+		return newCompatError("permission block", c.PermissionBlock, newcfg.PermissionBlock)
+	}
+	if isForkIncompatible(c.PrivateBlock, newcfg.PrivateBlock, head) {
+		return newCompatError("private transaction block", c.PrivateBlock, newcfg.PrivateBlock)
+	}
+	if isForkIncompatible(c.ZeroGasPriceBlock, newcfg.ZeroGasPriceBlock, head) {
+		return newCompatError("zero gas price block", c.ZeroGasPriceBlock, newcfg.ZeroGasPriceBlock)
+	}
+	if isForkIncompatible(c.bft().DomainBlock, newcfg.bft().DomainBlock, head) {
+		return newCompatError("BFT domain block", c.bft().DomainBlock, newcfg.bft().DomainBlock)
+	}
+	if isForkIncompatible(c.bft().RewardBlock, newcfg.bft().RewardBlock, head) {
+		return newCompatError("BFT reward block", c.bft().RewardBlock, newcfg.bft().RewardBlock)
+	}
+	if c.bft().IsRewarding(head) && !c.bft().sameRewards(newcfg.bft()) {
+		return newCompatError("BFT reward parameters", c.bft().RewardBlock, newcfg.bft().RewardBlock)
+	}
+	if isForkIncompatible(c.bft().MedianTimeBlock, newcfg.bft().MedianTimeBlock, head) {
+		return newCompatError("BFT median time block", c.bft().MedianTimeBlock, newcfg.bft().MedianTimeBlock)
+	}
+	for _, p := range append(append([]*PrecompileConfig{}, c.Precompiles...), newcfg.Precompiles...) {
+		stored, next := c.precompile(p.Address), newcfg.precompile(p.Address)
+		if isForkIncompatible(precompileBlock(stored), precompileBlock(next), head) {
+			return newCompatError("precompile "+p.Address.Hex()+" block", precompileBlock(stored), precompileBlock(next))
+		}
+		if stored != nil && next != nil && stored.Name != next.Name && stored.IsActive(head) {
+			return newCompatError("precompile "+p.Address.Hex()+" contract", precompileBlock(stored), precompileBlock(next))
+		}
+	}
 	return nil
 }
 
+// bft returns the BFT config of the chain, or an empty one without any forks
+// scheduled if the chain uses another engine.
+func (c *ChainConfig) bft() *BFTConfig {
+	if c.BFT == nil {
+		return new(BFTConfig)
+	}
+	return c.BFT
+}
+
+// precompile returns the native contract activated at the given address, or nil
+// if there is none.
+func (c *ChainConfig) precompile(addr common.Address) *PrecompileConfig {
+	for _, p := range c.Precompiles {
+		if p.Address == addr {
+			return p
+		}
+	}
+	return nil
+}
+
+// precompileBlock returns the block a native contract is activated at, with the
+// genesis standing in for contracts active from the start (nil = never).
+func precompileBlock(p *PrecompileConfig) *big.Int {
+	switch {
+	case p == nil:
+		return nil
+	case p.Block == nil:
+		return new(big.Int)
+	default:
+		return p.Block
+	}
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -70,6 +72,123 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     9,
 			},
 		},
+		{
+			stored: &ChainConfig{ZeroGasPriceBlock: big.NewInt(10)},
+			new:    &ChainConfig{},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "zero gas price block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{BFT: &BFTConfig{DomainBlock: big.NewInt(10)}},
+			new:     &ChainConfig{BFT: &BFTConfig{DomainBlock: big.NewInt(20)}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{BFT: &BFTConfig{DomainBlock: big.NewInt(10)}},
+			new:    &ChainConfig{},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "BFT domain block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{},
+			new:    &ChainConfig{BFT: &BFTConfig{DomainBlock: big.NewInt(10)}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "BFT domain block",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{BFT: &BFTConfig{RewardBlock: big.NewInt(10)}},
+			new:    &ChainConfig{BFT: &BFTConfig{RewardBlock: big.NewInt(20)}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "BFT reward block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{BFT: &BFTConfig{RewardBlock: big.NewInt(10), BlockReward: big.NewInt(1)}},
+			new:     &ChainConfig{BFT: &BFTConfig{RewardBlock: big.NewInt(10), BlockReward: big.NewInt(2)}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{BFT: &BFTConfig{RewardBlock: big.NewInt(10), BlockReward: big.NewInt(1)}},
+			new:    &ChainConfig{BFT: &BFTConfig{RewardBlock: big.NewInt(10), BlockReward: big.NewInt(2)}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "BFT reward parameters",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{BFT: &BFTConfig{RewardBlock: big.NewInt(10), Treasury: &common.Address{1}, TreasuryShare: 10}},
+			new:    &ChainConfig{BFT: &BFTConfig{RewardBlock: big.NewInt(10), Treasury: &common.Address{2}, TreasuryShare: 10}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "BFT reward parameters",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{BFT: &BFTConfig{MedianTimeBlock: big.NewInt(10)}},
+			new:    &ChainConfig{BFT: &BFTConfig{MedianTimeBlock: big.NewInt(20)}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "BFT median time block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "sm3", Address: common.Address{1, 0}, Block: big.NewInt(10)}}},
+			new:     &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "sm3", Address: common.Address{1, 0}, Block: big.NewInt(20)}}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "sm3", Address: common.Address{1, 0}, Block: big.NewInt(10)}}},
+			new:    &ChainConfig{},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "precompile 0x0100000000000000000000000000000000000000 block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "sm3", Address: common.Address{1, 0}}}},
+			new:    &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "sm2verify", Address: common.Address{1, 0}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "precompile 0x0100000000000000000000000000000000000000 contract",
+				StoredConfig: big.NewInt(0),
+				NewConfig:    big.NewInt(0),
+				RewindTo:     0,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -60,6 +60,12 @@ const (
 	MemoryGas        uint64 = 3     // Times the address of the (highest referenced byte in memory + 1). NOTE: referencing happens on read, write and in instructions such as RETURN and CALL.
 	TxDataNonZeroGas uint64 = 68    // Per byte of data attached to a transaction that is not equal to zero. NOTE: Not payable on data of calls between transactions.
 
+	Ed25519VerifyGas     uint64 = 2000 // Once per ed25519 signature verification.
+	Ed25519VerifyWordGas uint64 = 12   // Once per word of the message verified against an ed25519 signature.
+	Sm2VerifyGas         uint64 = 3000 // Once per SM2 signature verification.
+	Sm3Gas               uint64 = 60   // Once per SM3 hash.
+	Sm3WordGas           uint64 = 12   // Once per word of the SM3 hashed data.
+
 	MaxCodeSize = 24576
 )
 
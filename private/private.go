@@ -0,0 +1,116 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package private implements the distribution of private transaction payloads.
+//
+// A private transaction only carries the key of its payload on chain. The
+// payload itself is handed to a transaction manager, which delivers it to the
+// participants of the transaction and to nobody else.
+package private
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var errNoParticipants = errors.New("private transaction without participants")
+
+// TransactionManager distributes the payloads of private transactions among
+// their participants, identified by the public keys of their managers.
+type TransactionManager interface {
+	// Send stores the payload for the sender and the recipients and returns
+	// the key to be included in the transaction in its place.
+	Send(payload []byte, from string, to []string) ([]byte, error)
+
+	// Receive returns the payload stored under the given key, or nil if the
+	// local node is not one of its participants.
+	Receive(key []byte) ([]byte, error)
+}
+
+// FileManager is a TransactionManager storing the payloads in a directory
+// shared by all participants. It stands in for a network of transaction
+// managers in tests and development setups, and does not encrypt anything.
+type FileManager struct {
+	dir  string // Directory holding the payloads
+	self string // Identity of the local node
+}
+
+// fileEntry is the content of a payload file.
+type fileEntry struct {
+	Participants []string `json:"participants"`
+	Payload      []byte   `json:"payload"`
+}
+
+// NewFileManager creates a transaction manager storing payloads in dir on
+// behalf of the participant identified by self.
+func NewFileManager(dir string, self string) (*FileManager, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileManager{dir: dir, self: self}, nil
+}
+
+// Send implements TransactionManager, keying the payload by the hash of its
+// content and participants.
+func (m *FileManager) Send(payload []byte, from string, to []string) ([]byte, error) {
+	if len(to) == 0 {
+		return nil, errNoParticipants
+	}
+	if from == "" {
+		from = m.self
+	}
+	entry := fileEntry{Participants: append([]string{from}, to...), Payload: payload}
+	blob, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	key := crypto.Keccak256(blob)
+	if err := ioutil.WriteFile(m.path(key), blob, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Receive implements TransactionManager.
+func (m *FileManager) Receive(key []byte) ([]byte, error) {
+	blob, err := ioutil.ReadFile(m.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry fileEntry
+	if err := json.Unmarshal(blob, &entry); err != nil {
+		return nil, fmt.Errorf("invalid payload %x: %v", key, err)
+	}
+	for _, participant := range entry.Participants {
+		if participant == m.self {
+			return entry.Payload, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *FileManager) path(key []byte) string {
+	return filepath.Join(m.dir, fmt.Sprintf("%x", key))
+}
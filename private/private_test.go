@@ -0,0 +1,61 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package private
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Tests that payloads are only delivered to the participants.
+func TestFileManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	managers := make(map[string]*FileManager)
+	for _, id := range []string{"alice", "bob", "carol"} {
+		if managers[id], err = NewFileManager(dir, id); err != nil {
+			t.Fatalf("failed to create manager %s: %v", id, err)
+		}
+	}
+	payload := []byte("payload")
+	if _, err := managers["alice"].Send(payload, "", nil); err != errNoParticipants {
+		t.Errorf("error mismatch without participants: have %v, want %v", err, errNoParticipants)
+	}
+	key, err := managers["alice"].Send(payload, "", []string{"bob"})
+	if err != nil {
+		t.Fatalf("failed to send payload: %v", err)
+	}
+	for id, want := range map[string][]byte{"alice": payload, "bob": payload, "carol": nil} {
+		have, err := managers[id].Receive(key)
+		if err != nil {
+			t.Fatalf("%s: failed to receive payload: %v", id, err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Errorf("%s: payload mismatch: have %q, want %q", id, have, want)
+		}
+	}
+	// Unknown keys are not an error, the node just takes no part
+	if have, err := managers["bob"].Receive([]byte("unknown")); have != nil || err != nil {
+		t.Errorf("unknown key: have %q, %v", have, err)
+	}
+}
@@ -65,6 +65,17 @@ func TestStatePreCompiledContracts(t *testing.T) {
 	}
 }
 
+func TestStateNativeContracts(t *testing.T) {
+	chainConfig := &params.ChainConfig{
+		HomesteadBlock: big.NewInt(1150000),
+	}
+
+	fn := filepath.Join(stateTestDir, "stNativeContracts.json")
+	if err := RunStateTest(chainConfig, fn, StateSkipTests); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestStateRecursiveCreate(t *testing.T) {
 	chainConfig := &params.ChainConfig{
 		HomesteadBlock: big.NewInt(1150000),
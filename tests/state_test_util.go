@@ -126,6 +126,11 @@ func runStateTests(chainConfig *params.ChainConfig, tests map[string]VmTest, ski
 }
 
 func runStateTest(chainConfig *params.ChainConfig, test VmTest) error {
+	if len(test.Precompiles) > 0 {
+		config := *chainConfig
+		config.Precompiles = test.Precompiles
+		chainConfig = &config
+	}
 	db, _ := ethdb.NewMemDatabase()
 	statedb := makePreState(db, test.Pre)
 
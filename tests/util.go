@@ -150,6 +150,7 @@ type VmTest struct {
 	Post          map[string]Account
 	Pre           map[string]Account
 	PostStateRoot string
+	Precompiles   []*params.PrecompileConfig // Native contracts to activate on top of the chain config
 }
 
 func NewEVMEnvironment(vmTest bool, chainConfig *params.ChainConfig, statedb *state.StateDB, envValues map[string]string, tx map[string]string) (*vm.EVM, core.Message) {
@@ -112,6 +112,12 @@ func mustDecodeNode(hash, buf []byte, cachegen uint16) node {
 	return n
 }
 
+// IsNode reports whether blob is the RLP encoding of a trie node.
+func IsNode(blob []byte) bool {
+	_, err := decodeNode(nil, blob, 0)
+	return err == nil
+}
+
 // decodeNode parses the RLP encoding of a trie node.
 func decodeNode(hash, buf []byte, cachegen uint16) (node, error) {
 	if len(buf) == 0 {
@@ -0,0 +1,256 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// flushBatchSize is the amount of data accumulated before a batch of flushed
+// nodes is written to disk.
+const flushBatchSize = 100 * 1024
+
+// LeafCallback returns the hashes of the data a trie leaf refers to outside of
+// its own trie, e.g. the storage trie and code of an account. Values which do
+// not refer to anything return nil.
+type LeafCallback func(leaf []byte) []common.Hash
+
+// cachedNode is a trie node held in memory, along with the number of its live
+// references and the in-memory nodes it references itself.
+type cachedNode struct {
+	blob     []byte        // Encoded node (or code) to store under the hash
+	parents  int           // Number of live parents and external references
+	children []common.Hash // In-memory nodes referenced by this one
+}
+
+// NodeCache is an intermediate write layer between the tries and the disk
+// database. Committed trie nodes are kept in memory and reference counted, so
+// the tries of short lived states can be discarded without ever hitting the
+// disk, while the ones worth keeping are flushed.
+//
+// Keys other than hashes (e.g. preimages) are written through to disk.
+type NodeCache struct {
+	diskdb ethdb.Database // Persistent storage for flushed nodes
+	onleaf LeafCallback   // Resolver of the references of trie leaves (nil = none)
+
+	nodes map[common.Hash]*cachedNode // Nodes not yet flushed to disk
+	size  common.StorageSize          // Storage size of the cached nodes
+
+	lock sync.RWMutex
+}
+
+// NewNodeCache creates a node cache on top of diskdb, with leaf references
+// resolved by onleaf.
+func NewNodeCache(diskdb ethdb.Database, onleaf LeafCallback) *NodeCache {
+	return &NodeCache{
+		diskdb: diskdb,
+		onleaf: onleaf,
+		nodes:  make(map[common.Hash]*cachedNode),
+	}
+}
+
+// DiskDB returns the persistent storage beneath the cache.
+func (c *NodeCache) DiskDB() ethdb.Database {
+	return c.diskdb
+}
+
+// Get implements DatabaseReader, retrieving the node from memory if cached
+// and from disk otherwise.
+func (c *NodeCache) Get(key []byte) ([]byte, error) {
+	if len(key) == common.HashLength {
+		c.lock.RLock()
+		node := c.nodes[common.BytesToHash(key)]
+		c.lock.RUnlock()
+
+		if node != nil {
+			return node.blob, nil
+		}
+	}
+	return c.diskdb.Get(key)
+}
+
+// Put implements DatabaseWriter, caching the node in memory without any
+// references. It is collected as soon as a root referencing it is released.
+func (c *NodeCache) Put(key []byte, value []byte) error {
+	if len(key) != common.HashLength {
+		return c.diskdb.Put(key, value)
+	}
+	hash := common.BytesToHash(key)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.nodes[hash]; ok {
+		return nil
+	}
+	entry := &cachedNode{blob: common.CopyBytes(value)}
+
+	// Anything not decoding as a trie node (i.e. code) references nothing. The
+	// children missing from memory were flushed and need no tracking.
+	if n, err := decodeNode(key, entry.blob, 0); err == nil {
+		for _, child := range c.references(n, nil) {
+			if node, ok := c.nodes[child]; ok {
+				node.parents++
+				entry.children = append(entry.children, child)
+			}
+		}
+	}
+	c.nodes[hash] = entry
+	c.size += common.StorageSize(common.HashLength + len(entry.blob))
+
+	return nil
+}
+
+// references appends the hashes of all nodes and external data referenced by
+// n, including those of the nodes embedded into it.
+func (c *NodeCache) references(n node, refs []common.Hash) []common.Hash {
+	switch n := n.(type) {
+	case *shortNode:
+		return c.references(n.Val, refs)
+	case *fullNode:
+		for _, child := range n.Children {
+			refs = c.references(child, refs)
+		}
+	case hashNode:
+		refs = append(refs, common.BytesToHash(n))
+	case valueNode:
+		if c.onleaf != nil {
+			refs = append(refs, c.onleaf(n)...)
+		}
+	}
+	return refs
+}
+
+// Reference adds an external reference to the cached trie rooted at root,
+// keeping it alive until released by Dereference. Roots already flushed to
+// disk need no references and are ignored.
+func (c *NodeCache) Reference(root common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if node, ok := c.nodes[root]; ok {
+		node.parents++
+	}
+}
+
+// Dereference releases an external reference to the trie rooted at root,
+// dropping all of its nodes no longer referenced from memory.
+func (c *NodeCache) Dereference(root common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	nodes, size, start := len(c.nodes), c.size, time.Now()
+	c.dereference(root)
+
+	log.Debug("Dereferenced trie from memory", "nodes", nodes-len(c.nodes), "size", size-c.size, "time", time.Since(start),
+		"livenodes", len(c.nodes), "livesize", c.size)
+}
+
+// dereference drops a reference to the node, collecting it recursively if it
+// was the last one. The caller must hold the lock.
+func (c *NodeCache) dereference(hash common.Hash) {
+	node, ok := c.nodes[hash]
+	if !ok {
+		return
+	}
+	if node.parents > 0 {
+		node.parents--
+	}
+	if node.parents > 0 {
+		return
+	}
+	for _, child := range node.children {
+		c.dereference(child)
+	}
+	delete(c.nodes, hash)
+	c.size -= common.StorageSize(common.HashLength + len(node.blob))
+}
+
+// Commit flushes all cached nodes of the trie rooted at root to disk and drops
+// them from memory. Children are written before their parents, so a partially
+// flushed trie never leaves dangling references on disk.
+func (c *NodeCache) Commit(root common.Hash) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	start := time.Now()
+
+	var order []common.Hash
+	c.gather(root, make(map[common.Hash]struct{}), &order)
+
+	var (
+		batch = c.diskdb.NewBatch()
+		bytes int
+		size  common.StorageSize
+	)
+	for _, hash := range order {
+		blob := c.nodes[hash].blob
+		if err := batch.Put(hash[:], blob); err != nil {
+			return err
+		}
+		if bytes += len(blob); bytes >= flushBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch, bytes = c.diskdb.NewBatch(), 0
+		}
+		size += common.StorageSize(common.HashLength + len(blob))
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// All nodes are safely on disk, release them from memory
+	for _, hash := range order {
+		delete(c.nodes, hash)
+	}
+	c.size -= size
+
+	log.Debug("Flushed trie to disk", "root", root, "nodes", len(order), "size", size, "time", time.Since(start),
+		"livenodes", len(c.nodes), "livesize", c.size)
+	return nil
+}
+
+// gather appends the cached nodes of the trie rooted at hash to order, with
+// children preceding their parents. The caller must hold the lock.
+func (c *NodeCache) gather(hash common.Hash, done map[common.Hash]struct{}, order *[]common.Hash) {
+	node, ok := c.nodes[hash]
+	if !ok {
+		return
+	}
+	if _, ok := done[hash]; ok {
+		return
+	}
+	done[hash] = struct{}{}
+
+	for _, child := range node.children {
+		c.gather(child, done, order)
+	}
+	*order = append(*order, hash)
+}
+
+// Size returns the number and storage size of the nodes cached in memory.
+func (c *NodeCache) Size() (int, common.StorageSize) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return len(c.nodes), c.size
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// makeCachedTrie commits a trie of n entries into the cache, with the first
+// modified entries altered by the given suffix.
+func makeCachedTrie(t *testing.T, cache *NodeCache, n, modified int, suffix string) common.Hash {
+	trie, _ := New(common.Hash{}, cache)
+	for i := 0; i < n; i++ {
+		value := fmt.Sprintf("value-%032d", i)
+		if i < modified {
+			value += suffix
+		}
+		trie.Update([]byte(fmt.Sprintf("key-%d", i)), []byte(value))
+	}
+	root, err := trie.CommitTo(cache)
+	if err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	return root
+}
+
+// Tests that dereferencing a trie only drops the nodes not shared with tries
+// still referenced, and that no node lingers once all tries are released.
+func TestNodeCacheDereference(t *testing.T) {
+	diskdb, _ := ethdb.NewMemDatabase()
+	cache := NewNodeCache(diskdb, nil)
+
+	first := makeCachedTrie(t, cache, 100, 0, "")
+	cache.Reference(first)
+	second := makeCachedTrie(t, cache, 100, 10, "-new")
+	cache.Reference(second)
+
+	if len(diskdb.Keys()) != 0 {
+		t.Fatalf("cached nodes written to disk: %d", len(diskdb.Keys()))
+	}
+	cache.Dereference(first)
+	if _, err := New(second, cache); err != nil {
+		t.Fatalf("referenced trie lost: %v", err)
+	}
+	trie, _ := New(second, cache)
+	for i := 0; i < 100; i++ {
+		if _, err := trie.TryGet([]byte(fmt.Sprintf("key-%d", i))); err != nil {
+			t.Fatalf("entry %d of referenced trie lost: %v", i, err)
+		}
+	}
+	cache.Dereference(second)
+	if nodes, size := cache.Size(); nodes != 0 || size != 0 {
+		t.Errorf("nodes left after releasing all tries: %d (%v)", nodes, size)
+	}
+}
+
+// Tests that committing a trie flushes all of its nodes to disk, and only
+// them, releasing them from memory.
+func TestNodeCacheCommit(t *testing.T) {
+	diskdb, _ := ethdb.NewMemDatabase()
+	cache := NewNodeCache(diskdb, nil)
+
+	first := makeCachedTrie(t, cache, 100, 0, "")
+	cache.Reference(first)
+	second := makeCachedTrie(t, cache, 100, 10, "-new")
+	cache.Reference(second)
+
+	if err := cache.Commit(first); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	trie, err := New(first, diskdb)
+	if err != nil {
+		t.Fatalf("committed trie missing from disk: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if _, err := trie.TryGet([]byte(fmt.Sprintf("key-%d", i))); err != nil {
+			t.Fatalf("entry %d of committed trie missing from disk: %v", i, err)
+		}
+	}
+	if _, err := New(second, diskdb); err == nil {
+		t.Errorf("uncommitted trie written to disk")
+	}
+	// The nodes unique to the second trie are still in memory
+	if nodes, _ := cache.Size(); nodes == 0 {
+		t.Errorf("uncommitted nodes dropped from memory")
+	}
+	cache.Dereference(second)
+	if nodes, _ := cache.Size(); nodes != 0 {
+		t.Errorf("nodes left after releasing all tries: %d", nodes)
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -37,6 +38,16 @@ import (
 // (at least the root node), ending with the node that proves the
 // absence of the key.
 func (t *Trie) Prove(key []byte) []rlp.RawValue {
+	proof, err := t.TryProve(key)
+	if err != nil {
+		log.Error(fmt.Sprintf("Unhandled trie error: %v", err))
+	}
+	return proof
+}
+
+// TryProve constructs a merkle proof for key like Prove does. If a node on the
+// path to key was not found in the database, a MissingNodeError is returned.
+func (t *Trie) TryProve(key []byte) ([]rlp.RawValue, error) {
 	// Collect all nodes on the path to key.
 	key = keybytesToHex(key)
 	nodes := []node{}
@@ -60,8 +71,7 @@ func (t *Trie) Prove(key []byte) []rlp.RawValue {
 			var err error
 			tn, err = t.resolveHash(n, nil)
 			if err != nil {
-				log.Error(fmt.Sprintf("Unhandled trie error: %v", err))
-				return nil
+				return nil, err
 			}
 		default:
 			panic(fmt.Sprintf("%T: invalid node: %v", tn, tn))
@@ -81,7 +91,7 @@ func (t *Trie) Prove(key []byte) []rlp.RawValue {
 			proof = append(proof, enc)
 		}
 	}
-	return proof
+	return proof, nil
 }
 
 // VerifyProof checks merkle proofs. The given proof must contain the
@@ -147,3 +157,267 @@ func get(tn node, key []byte) ([]byte, node) {
 		}
 	}
 }
+
+// ProveRange constructs a merkle proof for the range of entries between the
+// keys first and last, made up of the proofs of both boundary keys. Together
+// with the entries of the range it can be checked by VerifyRangeProof.
+func (t *Trie) ProveRange(first, last []byte) []rlp.RawValue {
+	proof := t.Prove(first)
+
+	seen := make(map[string]struct{}, len(proof))
+	for _, node := range proof {
+		seen[string(node)] = struct{}{}
+	}
+	for _, node := range t.Prove(last) {
+		if _, ok := seen[string(node)]; !ok {
+			proof = append(proof, node)
+		}
+	}
+	return proof
+}
+
+// VerifyRangeProof checks that the given entries, with keys in ascending order,
+// are all entries of the trie with the given root hash from firstKey up to the
+// last of the keys. The proof must be the one of ProveRange(firstKey, lastKey).
+// Without any entries the proof of firstKey alone has to show that the trie has
+// no entries from firstKey on, and without a proof the entries have to make up
+// the entire trie.
+//
+// VerifyRangeProof returns whether the trie has further entries after the range.
+func VerifyRangeProof(rootHash common.Hash, firstKey []byte, keys [][]byte, values [][]byte, proof []rlp.RawValue) (bool, error) {
+	if len(keys) != len(values) {
+		return false, fmt.Errorf("key count %d mismatches value count %d", len(keys), len(values))
+	}
+	for i, key := range keys {
+		if len(values[i]) == 0 {
+			return false, fmt.Errorf("empty value for key %x", key)
+		}
+		if i == 0 && bytes.Compare(firstKey, key) > 0 {
+			return false, errors.New("entries before the start of the range")
+		}
+		if i > 0 && bytes.Compare(keys[i-1], key) >= 0 {
+			return false, errors.New("entries not in ascending order")
+		}
+	}
+	db, _ := ethdb.NewMemDatabase()
+
+	// Without a proof the entries have to rebuild the whole trie
+	if len(proof) == 0 {
+		trie := &Trie{db: db}
+		for i, key := range keys {
+			if err := trie.TryUpdate(key, values[i]); err != nil {
+				return false, err
+			}
+		}
+		if hash := trie.Hash(); hash != rootHash {
+			return false, fmt.Errorf("root hash mismatch: have %x, want %x", hash, rootHash)
+		}
+		return false, nil
+	}
+	// Resolve the paths to both ends of the range from the proof
+	nodes := make(map[string][]byte, len(proof))
+	sha := sha3.NewKeccak256()
+	for _, buf := range proof {
+		sha.Reset()
+		sha.Write(buf)
+		nodes[string(sha.Sum(nil))] = buf
+	}
+	left, right := keybytesToHex(firstKey), []byte(nil)
+	if len(keys) > 0 {
+		right = keybytesToHex(keys[len(keys)-1])
+	}
+	root, err := proofToPath(hashNode(rootHash[:]), left, nodes)
+	if err != nil {
+		return false, err
+	}
+	if right != nil {
+		if root, err = proofToPath(root, right, nodes); err != nil {
+			return false, err
+		}
+	}
+	more := false
+	if right != nil {
+		more = hasRightElement(root, right)
+	}
+	// Drop everything within the range and refill it with the entries. The
+	// result only matches the root if the entries are all that was dropped.
+	if right != nil {
+		right = right[:len(right)-1]
+	}
+	root, err = unsetRange(root, nil, left[:len(left)-1], right)
+	if err != nil {
+		return false, err
+	}
+	trie := &Trie{root: root, db: db}
+	for i, key := range keys {
+		if err := trie.TryUpdate(key, values[i]); err != nil {
+			return false, err
+		}
+	}
+	if hash := trie.Hash(); hash != rootHash {
+		return false, fmt.Errorf("root hash mismatch: have %x, want %x", hash, rootHash)
+	}
+	return more, nil
+}
+
+// proofToPath resolves the nodes on the path to key from the proof nodes,
+// leaving all other references as hash nodes.
+func proofToPath(n node, key []byte, proof map[string][]byte) (node, error) {
+	switch n := n.(type) {
+	case hashNode:
+		buf, ok := proof[string(n)]
+		if !ok {
+			// Missing nodes only matter within the range, which unsetRange checks
+			return n, nil
+		}
+		rn, err := decodeNode(n, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node %x: %v", []byte(n), err)
+		}
+		return proofToPath(rn, key, proof)
+
+	case *shortNode:
+		if len(key) < len(n.Key) || !bytes.Equal(n.Key, key[:len(n.Key)]) {
+			return n, nil
+		}
+		child, err := proofToPath(n.Val, key[len(n.Key):], proof)
+		if err != nil {
+			return nil, err
+		}
+		n.Val = child
+		return n, nil
+
+	case *fullNode:
+		if len(key) == 0 {
+			return n, nil
+		}
+		child, err := proofToPath(n.Children[key[0]], key[1:], proof)
+		if err != nil {
+			return nil, err
+		}
+		n.Children[key[0]] = child
+		return n, nil
+	}
+	return n, nil
+}
+
+// hasRightElement reports whether the trie has any entry after key, following
+// the resolved path to it.
+func hasRightElement(n node, key []byte) bool {
+	for len(key) > 0 {
+		switch rn := n.(type) {
+		case *fullNode:
+			// The value of the node itself precedes all of its children
+			start := int(key[0]) + 1
+			if key[0] == 16 {
+				start = 0
+			}
+			for i := start; i < 16; i++ {
+				if rn.Children[i] != nil {
+					return true
+				}
+			}
+			n, key = rn.Children[key[0]], key[1:]
+
+		case *shortNode:
+			if len(key) < len(rn.Key) || !bytes.Equal(rn.Key, key[:len(rn.Key)]) {
+				// The whole subtrie lies after the key if it diverges upwards
+				common := len(key)
+				if len(rn.Key) < common {
+					common = len(rn.Key)
+				}
+				return bytes.Compare(rn.Key[:common], key[:common]) > 0
+			}
+			n, key = rn.Val, key[len(rn.Key):]
+
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// unsetRange removes all entries with keys between left and right (inclusive,
+// in hex encoding without terminator) from the subtrie n at the given path. A
+// nil right bound leaves the range open ended. Subtries outside the range are
+// kept as they are, while the ones crossing a bound must have been resolved.
+func unsetRange(n node, path, left, right []byte) (node, error) {
+	if n == nil {
+		return nil, nil
+	}
+	switch compareRange(path, left, right) {
+	case -1:
+		return n, nil
+	case 1:
+		return nil, nil
+	}
+	switch n := n.(type) {
+	case *shortNode:
+		key := n.Key
+		if hasTerm(key) {
+			key = key[:len(key)-1]
+		}
+		child, err := unsetRange(n.Val, append(append([]byte{}, path...), key...), left, right)
+		if err != nil || child == nil {
+			return nil, err
+		}
+		return &shortNode{n.Key, child, nodeFlag{dirty: true}}, nil
+
+	case *fullNode:
+		rn := &fullNode{flags: nodeFlag{dirty: true}}
+		for i, child := range n.Children[:16] {
+			var err error
+			if rn.Children[i], err = unsetRange(child, append(append([]byte{}, path...), byte(i)), left, right); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		if rn.Children[16], err = unsetRange(n.Children[16], path, left, right); err != nil {
+			return nil, err
+		}
+		return rn, nil
+
+	case valueNode:
+		// Values sit at their exact key, which is inside or outside the range
+		if bytes.Compare(path, left) >= 0 && (right == nil || bytes.Compare(path, right) <= 0) {
+			return nil, nil
+		}
+		return n, nil
+
+	case hashNode:
+		return nil, errors.New("range proof missing boundary nodes")
+	}
+	return nil, fmt.Errorf("%T: invalid node: %v", n, n)
+}
+
+// compareRange classifies the subtrie at path against the range between left
+// and right: -1 if it lies completely outside, 1 if completely inside and 0 if
+// it crosses any of the bounds.
+func compareRange(path, left, right []byte) int {
+	lower := comparePath(path, left)
+	if lower < 0 {
+		return -1
+	}
+	upper := -1
+	if right != nil {
+		if upper = comparePath(path, right); upper > 0 {
+			return -1
+		}
+	}
+	if lower > 0 && upper < 0 {
+		return 1
+	}
+	return 0
+}
+
+// comparePath compares the keys of the subtrie at path with key, returning -1
+// if all of them are smaller, 1 if all of them are larger and 0 otherwise.
+func comparePath(path, key []byte) int {
+	if len(path) > len(key) {
+		if bytes.Equal(path[:len(key)], key) {
+			return 1
+		}
+		return bytes.Compare(path[:len(key)], key)
+	}
+	return bytes.Compare(path, key[:len(path)])
+}
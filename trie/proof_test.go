@@ -20,6 +20,7 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	mrand "math/rand"
+	"sort"
 	"testing"
 	"time"
 
@@ -153,3 +154,95 @@ func randBytes(n int) []byte {
 	crand.Read(r)
 	return r
 }
+
+// sortedEntries returns the entries of a random trie in ascending key order.
+func sortedEntries(vals map[string]*kv) []*kv {
+	entries := make([]*kv, 0, len(vals))
+	for _, kv := range vals {
+		entries = append(entries, kv)
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].k, entries[j].k) < 0 })
+	return entries
+}
+
+func TestRangeProof(t *testing.T) {
+	trie, vals := randomTrie(500)
+	root, entries := trie.Hash(), sortedEntries(vals)
+
+	for i := 0; i < 500; i++ {
+		start := mrand.Intn(len(entries))
+		end := start + mrand.Intn(len(entries)-start)
+
+		keys, values := make([][]byte, 0, end-start+1), make([][]byte, 0, end-start+1)
+		for _, kv := range entries[start : end+1] {
+			keys, values = append(keys, kv.k), append(values, kv.v)
+		}
+		proof := trie.ProveRange(keys[0], keys[len(keys)-1])
+		more, err := VerifyRangeProof(root, keys[0], keys, values, proof)
+		if err != nil {
+			t.Fatalf("range %d-%d: failed to verify proof: %v", start, end, err)
+		}
+		if more != (end < len(entries)-1) {
+			t.Fatalf("range %d-%d: more entries mismatch: have %v, want %v", start, end, more, end < len(entries)-1)
+		}
+		// Omitting an entry must fail verification
+		if len(keys) > 2 {
+			omit := 1 + mrand.Intn(len(keys)-2)
+			skeys := append(append([][]byte{}, keys[:omit]...), keys[omit+1:]...)
+			svalues := append(append([][]byte{}, values[:omit]...), values[omit+1:]...)
+			if _, err := VerifyRangeProof(root, keys[0], skeys, svalues, proof); err == nil {
+				t.Fatalf("range %d-%d: proof verified with entry %d missing", start, end, omit)
+			}
+		}
+		// Modifying a value must fail verification
+		mvalues := append([][]byte{}, values...)
+		mvalues[mrand.Intn(len(mvalues))] = randBytes(20)
+		if _, err := VerifyRangeProof(root, keys[0], keys, mvalues, proof); err == nil {
+			t.Fatalf("range %d-%d: proof verified with modified value", start, end)
+		}
+	}
+}
+
+// Tests range proofs starting from keys not in the trie, and the ones of the
+// empty range past the last entry.
+func TestRangeProofNonExistentOrigin(t *testing.T) {
+	trie, vals := randomTrie(500)
+	root, entries := trie.Hash(), sortedEntries(vals)
+
+	origin := common.CopyBytes(entries[300].k)
+	origin[len(origin)-1]++ // random keys make a collision extremely unlikely
+	if _, ok := vals[string(origin)]; ok {
+		t.Skip("origin collides with an entry")
+	}
+	keys, values := [][]byte{}, [][]byte{}
+	for _, kv := range entries[301:400] {
+		keys, values = append(keys, kv.k), append(values, kv.v)
+	}
+	proof := trie.ProveRange(origin, keys[len(keys)-1])
+	if _, err := VerifyRangeProof(root, origin, keys, values, proof); err != nil {
+		t.Fatalf("failed to verify proof: %v", err)
+	}
+	// Skipping the first entry after the origin must fail verification
+	if _, err := VerifyRangeProof(root, origin, keys[1:], values[1:], proof); err == nil {
+		t.Fatalf("proof verified with first entry missing")
+	}
+	// Past the last entry the range is empty
+	last := bytes.Repeat([]byte{0xff}, 32)
+	if more, err := VerifyRangeProof(root, last, nil, nil, trie.Prove(last)); err != nil || more {
+		t.Fatalf("failed to verify empty range: more %v, err %v", more, err)
+	}
+	if _, err := VerifyRangeProof(root, origin, nil, nil, trie.Prove(origin)); err == nil {
+		t.Fatalf("empty range verified with entries left")
+	}
+	// Without a proof the entries must make up the whole trie
+	keys, values = keys[:0], values[:0]
+	for _, kv := range entries {
+		keys, values = append(keys, kv.k), append(values, kv.v)
+	}
+	if _, err := VerifyRangeProof(root, nil, keys, values, nil); err != nil {
+		t.Fatalf("failed to verify whole trie: %v", err)
+	}
+	if _, err := VerifyRangeProof(root, nil, keys[1:], values[1:], nil); err == nil {
+		t.Fatalf("partial trie verified without proof")
+	}
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 var secureKeyPrefix = []byte("secure-key-")
@@ -129,6 +130,14 @@ func (t *SecureTrie) TryDelete(key []byte) error {
 	return t.trie.TryDelete(hk)
 }
 
+// TryProve constructs a merkle proof for key, which is hashed like on every
+// other access. The proof is thus to be verified against the hash of the key.
+// If a node on the path was not found in the database, a MissingNodeError is
+// returned.
+func (t *SecureTrie) TryProve(key []byte) ([]rlp.RawValue, error) {
+	return t.trie.TryProve(t.hashKey(key))
+}
+
 // GetKey returns the sha3 preimage of a hashed key that was
 // previously used to store a value.
 func (t *SecureTrie) GetKey(shaKey []byte) []byte {
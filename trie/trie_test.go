@@ -138,6 +138,18 @@ func TestMissingNode(t *testing.T) {
 		t.Errorf("Wrong error: %v", err)
 	}
 
+	trie, _ = New(root, db)
+	_, err = trie.TryProve([]byte("120000"))
+	if _, ok := err.(*MissingNodeError); !ok {
+		t.Errorf("Wrong error: %v", err)
+	}
+
+	trie, _ = New(root, db)
+	_, err = trie.TryProve([]byte("123456"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
 	trie, _ = New(root, db)
 	err = trie.TryDelete([]byte("123456"))
 	if _, ok := err.(*MissingNodeError); !ok {